@@ -63,8 +63,11 @@ contract ZKNOX_SimpleHybrid7702ZK is Simple7702Account {
 
     error AlreadyInitialized();
     error InvalidCaller();
+    error InvalidRotation();
 
     bytes32 constant SIMPLEHYBRID7702_STORAGE_POSITION = keccak256("zknox.hybrid.7702.zk");
+    //order of secp256k1, the message of the ECDSA proof is reduced modulo it
+    uint256 constant SECP256K1_N = 0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141;
 
     struct Storage {
         //address of the ZK Proof verifier
@@ -78,6 +81,10 @@ contract ZKNOX_SimpleHybrid7702ZK is Simple7702Account {
 
         address CoreAddress; //address of the core verifier (FALCON, DILITHIUM, etc.), shall be the address of a ISigVerifier
         uint256 algoID;
+        //nullifiers of the proofs already used
+        mapping(uint256 => bool) usedNullifiers;
+        //address of the verifier of the commitment rotation proofs
+        address rotationVerifier;
     }
 
     function getStorage() internal pure returns (Storage storage ds) {
@@ -116,7 +123,13 @@ contract ZKNOX_SimpleHybrid7702ZK is Simple7702Account {
             return SIG_VALIDATION_FAILED;
         }
         (bytes memory proof, uint256[] memory public_inputs, bytes memory sm) = abi.decode(userOp.signature, (bytes, uint256[], bytes));
-        return isValid(userOpHash, proof, public_inputs, sm) ? SIG_VALIDATION_SUCCESS : SIG_VALIDATION_FAILED;
+        (bool valid, uint256 nullifier) = _checkSignature(userOpHash, proof, public_inputs, sm);
+        if (!valid) {
+            return SIG_VALIDATION_FAILED;
+        }
+        // the nullifier is spent only by the entry point, once both signatures are valid
+        getStorage().usedNullifiers[nullifier] = true;
+        return SIG_VALIDATION_SUCCESS;
     }    
 
 
@@ -126,7 +139,19 @@ contract ZKNOX_SimpleHybrid7702ZK is Simple7702Account {
         bytes memory proof,
         uint256[] memory public_inputs, 
         bytes memory sm // the signature in the NIST KAT format, as output by test_falcon.js
-        ) public returns (bool)
+        ) public view returns (bool)
+        {
+            (bool valid, ) = _checkSignature(digest, proof, public_inputs, sm);
+            return valid;
+        }
+
+    //checks both signatures of digest without spending the nullifier, returns the nullifier of the proof
+    function _checkSignature(
+        bytes32 digest,
+        bytes memory proof,
+        uint256[] memory public_inputs,
+        bytes memory sm
+        ) internal view returns (bool, uint256)
         {
             uint256 slen = (uint256(uint8(sm[0])) << 8) + uint256(uint8(sm[1]));
             uint256 mlen = sm.length - slen - 42;
@@ -143,7 +168,7 @@ contract ZKNOX_SimpleHybrid7702ZK is Simple7702Account {
         }
 
          if (sm[2 + 40 + mlen] != 0x29) {
-             return false;
+             return (false, 0);
          }
 
         uint256[] memory s2 =_ZKNOX_NTT_Compact((_decompress_sig(sm, 2 + 40 + mlen + 1)));
@@ -152,35 +177,87 @@ contract ZKNOX_SimpleHybrid7702ZK is Simple7702Account {
 
          uint256[] memory nttpk;
          
+         // 4 message limbs, chainId, account, nullifier and commitment at least
+         if (public_inputs.length < 8) {
+            return (false, 0);
+         }
          // verify commitment
          if (getStorage().public_key_commitment == uint256(0)) {
-            return false;
+            return (false, 0);
          }
          if (public_inputs[public_inputs.length - 1] != getStorage().public_key_commitment) {
-            return false;
+            return (false, 0);
+         }
+         // public inputs start with the four 64-bit limbs of the message, little endian, reduced modulo the secp256k1 order
+         uint256 message = uint256(digest) % SECP256K1_N;
+         for (uint256 i = 0; i < 4; i++) {
+            if (public_inputs[i] != (message >> (64 * i)) & 0xffffffffffffffff) {
+               return (false, 0);
+            }
+         }
+         // public inputs end with chainId, account, nullifier, commitment
+         if (public_inputs[public_inputs.length - 4] != block.chainid) {
+            return (false, 0);
+         }
+         if (public_inputs[public_inputs.length - 3] != uint256(uint160(address(this)))) {
+            return (false, 0);
+         }
+         uint256 nullifier = public_inputs[public_inputs.length - 2];
+         if (getStorage().usedNullifiers[nullifier]) {
+            return (false, 0);
          }
          // verify ZK proof
          IZKVerifier zkVerifier = IZKVerifier(getStorage().zkVerifier);
          if (!zkVerifier.Verify(proof, public_inputs)) {
-            return false;
+            return (false, 0);
          }
 
          if (getStorage().authorized_PQPublicKey == address(0)) {
-            return false;
+            return (false, 0);
          }
          
          nttpk = Core.GetPublicKey(getStorage().authorized_PQPublicKey);
 
          if (!Core.verify(abi.encodePacked(digest), salt, s2, nttpk)) {
-            return false;
+            return (false, 0);
          }
-    
-         return true;
+
+         return (true, nullifier);
 
         }
     
 
 
+    //sets the verifier of the commitment rotation proofs
+    function setRotationVerifier(address iRotationVerifier) external {
+        if (msg.sender != address(this) && msg.sender != address(entryPoint())) {
+            revert InvalidCaller();
+        }
+        getStorage().rotationVerifier = iRotationVerifier;
+    }
+
+    //replaces the public key commitment without sudo, the proof shows that the committed key signed the rotation
+    //public inputs are chainId, account, old commitment, new commitment
+    function rotateCommitment(bytes memory proof, uint256[] memory public_inputs) external {
+        if (getStorage().rotationVerifier == address(0) || public_inputs.length != 4) {
+            revert InvalidRotation();
+        }
+        if (public_inputs[0] != block.chainid) {
+            revert InvalidRotation();
+        }
+        if (public_inputs[1] != uint256(uint160(address(this)))) {
+            revert InvalidRotation();
+        }
+        if (getStorage().public_key_commitment == uint256(0) || public_inputs[2] != getStorage().public_key_commitment) {
+            revert InvalidRotation();
+        }
+        IZKVerifier rotationVerifier = IZKVerifier(getStorage().rotationVerifier);
+        if (!rotationVerifier.Verify(proof, public_inputs)) {
+            revert InvalidRotation();
+        }
+        getStorage().public_key_commitment = public_inputs[3];
+    }
+
     function GetPublicKey() public view returns (uint256[] memory res) {
         ISigVerifier Core = ISigVerifier(getStorage().CoreAddress);
         res = Core.GetPublicKey(getStorage().authorized_PQPublicKey);
@@ -15,52 +15,48 @@ import (
 
 	// Added for performance timing
 	"github.com/consensys/gnark-crypto/ecc"
+	cryptosha3 "golang.org/x/crypto/sha3"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 
-	"github.com/consensys/gnark/backend/plonk"
-	plonk_bn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
-	"github.com/consensys/gnark/std/hash/mimc"
 	"github.com/consensys/gnark/std/math/emulated"
 	"github.com/consensys/gnark/std/signature/ecdsa"
-)
-
-// Circuit defines the circuit structure as provided by you.
-type Circuit[T, S emulated.FieldParams] struct {
-	Sig     ecdsa.Signature[S]    `gnark:",secret"` // signature
-	Msg     emulated.Element[S]   `gnark:",public"` // message
-	Pub     ecdsa.PublicKey[T, S] `gnark:",secret"` // now secret
-	Address frontend.Variable     `gnark:",secret"` // secret address
-	Nonce   frontend.Variable     `gnark:",secret"` // secret nonce
-	Com     frontend.Variable     `gnark:",public"` // public commitment
-}
-
-func (c *Circuit[T, S]) Define(api frontend.API) error {
-	curveParams := sw_emulated.GetCurveParams[T]()
-	c.Pub.Verify(api, curveParams, &c.Msg, &c.Sig)
-
-	mimc, _ := mimc.NewMiMC(api)
 
-	// specify constraints
-	// mimc(preImage) == hash
-	mimc.Write(c.Address)
-	mimc.Write(c.Nonce)
-	api.AssertIsEqual(c.Com, mimc.Sum())
-	return nil
-}
+	"circuits"
+	"zkbackend"
+)
 
 // ProveInputEcdsa struct for JSON serialization of witness inputs.
 type ProveInputEcdsa struct {
 	MsgHash string `json:"msgHash"` // Hex string of the message hash
 	R       string `json:"r"`       // Hex string of signature R
 	S       string `json:"s"`       // Hex string of signature S
+	V       string `json:"v"`       // Hex string of the recovery id (0, 1, 27 or 28), the public key is then recovered
 	PubX    string `json:"pubX"`    // Hex string of public key X
 	PubY    string `json:"pubY"`    // Hex string of public key Y
 	Address string `json:"address"` // Hex string of address
 	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
 	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1 (default) or p256
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+	Scheme  string `json:"scheme"`  // Signature scheme of the key, ecdsa (default), bip340 or ed25519
+
+	ValidUntil string `json:"validUntil,omitempty"` // Hex string of the expiry of the proof, a block number or a timestamp signed with msgHash
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash    string `json:"commitHash"`    // Hash of the public key commitment, mimc or poseidon2
+	Curve         string `json:"curve"`         // Curve of the signer, secp256k1 or p256
+	LowS          bool   `json:"lowS"`          // The circuit rejects high-s signatures
+	CommitVersion int    `json:"commitVersion"` // Layout of the commitment, 0 or 1
+	Expiry        bool   `json:"expiry"`        // The proof carries a validUntil public input, signed with the message
 }
 
 //export verify
@@ -76,96 +72,105 @@ func performVerification() string {
 		}
 	}()
 
+	// the curve of the signer selects the setup artifacts
+	var loadedProveInput ProveInputEcdsa
+	err := readFromFile("witness_input.json", &loadedProveInput)
+	if err != nil {
+		return fmt.Sprintf("Error reading witness input: %v", err)
+	}
+	fmt.Println("Read witness_input.json")
+	if loadedProveInput.Curve == "" {
+		loadedProveInput.Curve = "secp256k1"
+	}
+	// BIP-340 and Ed25519 keys are proven by their own circuits, with their own setups
+	switch loadedProveInput.Scheme {
+	case "", "ecdsa":
+	case "bip340":
+		return "Error: bip340 keys are proven with prove_schnorr_k1.go"
+	case "ed25519":
+		return "Error: ed25519 keys are proven with prove_ed25519.go"
+	default:
+		return fmt.Sprintf("Error: unknown signature scheme %q", loadedProveInput.Scheme)
+	}
+	curve := loadedProveInput.Curve
+
+	// with the recovery id, the public key is recovered from the signature
+	switch curve {
+	case "secp256k1":
+		err = recoverPublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr](&loadedProveInput)
+	case "p256":
+		err = recoverPublicKey[emulated.P256Fp, emulated.P256Fr](&loadedProveInput)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error recovering the public key: %v", err)
+	}
+
+	// the mobile library proves with the PLONK setup
+	b := zkbackend.Plonk{}
+
 	// 1. Read back the compiled circuit
-	loadedR1CS := plonk.NewCS(ecc.BN254)
-	err := readFromFile("r1cs.bin", loadedR1CS)
+	loadedR1CS := b.NewCS()
+	err = readFromFile(artifactName(curve, "r1cs.bin"), loadedR1CS)
 	if err != nil {
 		return fmt.Sprintf("Error reading R1CS: %v", err)
 	}
 	fmt.Printf("Read R1CS.BIN (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
 
 	// 2. Read back the proving key
-	loadedPK := plonk.NewProvingKey(ecc.BN254)
-	err = readFromFile("proving_key.bin", loadedPK)
+	loadedPK := b.NewProvingKey()
+	err = readFromFile(artifactName(curve, "proving_key.bin"), loadedPK)
 	if err != nil {
 		return fmt.Sprintf("Error reading proving key: %v", err)
 	}
 	fmt.Println("Read PROVING_KEY.BIN")
 
 	// 3. Read back the verifying key
-	loadedVK := plonk.NewVerifyingKey(ecc.BN254)
-	err = readFromFile("verifying_key.bin", loadedVK)
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile(artifactName(curve, "verifying_key.bin"), loadedVK)
 	if err != nil {
 		return fmt.Sprintf("Error reading verifying key: %v", err)
 	}
 	fmt.Println("Read VERIFYING_KEY.BIN")
 
-	// 4. Read back the prove input JSON
-	var loadedProveInput ProveInputEcdsa
-	err = readFromFile("witness_input.json", &loadedProveInput)
-	if err != nil {
-		return fmt.Sprintf("Error reading witness input: %v", err)
-	}
-	fmt.Println("Read witness_input.json")
-
-	// Decode hex strings back to big.Int and byte slices for witness construction
-	rBytes, err := hex.DecodeString(loadedProveInput.R)
-	if err != nil {
-		return fmt.Sprintf("Error decoding R hex: %v", err)
-	}
-	sBytes, err := hex.DecodeString(loadedProveInput.S)
-	if err != nil {
-		return fmt.Sprintf("Error decoding S hex: %v", err)
+	// 4. the commitment must have been computed with the hash of the setup
+	config := SetupConfig{CommitHash: "mimc", Curve: curve}
+	configName := artifactName(curve, "setup_config.json")
+	if _, statErr := os.Stat(configName); statErr == nil {
+		err = readFromFile(configName, &config)
+		if err != nil {
+			return fmt.Sprintf("Error reading setup config: %v", err)
+		}
 	}
-	msgHashBytes, err := hex.DecodeString(loadedProveInput.MsgHash)
-	if err != nil {
-		return fmt.Sprintf("Error decoding MsgHash hex: %v", err)
+	if loadedProveInput.Hash == "" {
+		loadedProveInput.Hash = "mimc"
 	}
-	pubXBytes, err := hex.DecodeString(loadedProveInput.PubX)
-	if err != nil {
-		return fmt.Sprintf("Error decoding PubX hex: %v", err)
+	if loadedProveInput.Hash != config.CommitHash {
+		return fmt.Sprintf("Error: commitment computed with %s, setup uses %s", loadedProveInput.Hash, config.CommitHash)
 	}
-	pubYBytes, err := hex.DecodeString(loadedProveInput.PubY)
-	if err != nil {
-		return fmt.Sprintf("Error decoding PubY hex: %v", err)
+	if config.Curve != curve {
+		return fmt.Sprintf("Error: signer on %s, setup uses %s", curve, config.Curve)
 	}
-	addressBytes, err := hex.DecodeString(loadedProveInput.Address)
-	if err != nil {
-		return fmt.Sprintf("Error decoding Address hex: %v", err)
+	if loadedProveInput.Version != config.CommitVersion {
+		return fmt.Sprintf("Error: commitment version %d, setup uses version %d", loadedProveInput.Version, config.CommitVersion)
 	}
-	nonceBytes, err := hex.DecodeString(loadedProveInput.Nonce)
-	if err != nil {
-		return fmt.Sprintf("Error decoding Nonce hex: %v", err)
+	if config.Expiry != (loadedProveInput.ValidUntil != "") {
+		return fmt.Sprintf("Error: expiry of the setup %v, validUntil of witness_input.json %q", config.Expiry, loadedProveInput.ValidUntil)
 	}
-	comBytes, err := hex.DecodeString(loadedProveInput.Com)
+	err = checkCommitment(loadedProveInput, config.CommitHash)
 	if err != nil {
-		return fmt.Sprintf("Error decoding Com hex: %v", err)
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	rLoaded := new(big.Int).SetBytes(rBytes)
-	sLoaded := new(big.Int).SetBytes(sBytes)
-	pubXLoaded := new(big.Int).SetBytes(pubXBytes)
-	pubYLoaded := new(big.Int).SetBytes(pubYBytes)
-	addressLoaded := new(big.Int).SetBytes(addressBytes)
-	nonceLoaded := new(big.Int).SetBytes(nonceBytes)
-	comLoaded := new(big.Int).SetBytes(comBytes)
-
 	// 5. Create a new witness using the loaded input data
-	witnessCircuitLoaded := Circuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
-		Sig: ecdsa.Signature[emulated.Secp256k1Fr]{
-			R: emulated.ValueOf[emulated.Secp256k1Fr](rLoaded),
-			S: emulated.ValueOf[emulated.Secp256k1Fr](sLoaded),
-		},
-		Msg: emulated.ValueOf[emulated.Secp256k1Fr](msgHashBytes),
-		Pub: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
-			X: emulated.ValueOf[emulated.Secp256k1Fp](pubXLoaded),
-			Y: emulated.ValueOf[emulated.Secp256k1Fp](pubYLoaded),
-		},
-		Address: addressLoaded,
-		Nonce:   nonceLoaded,
-		Com:     comLoaded,
+	var witnessFullLoaded witness.Witness
+	switch curve {
+	case "secp256k1":
+		witnessFullLoaded, err = newEcdsaWitness[emulated.Secp256k1Fp, emulated.Secp256k1Fr](loadedProveInput, config.CommitHash)
+	case "p256":
+		witnessFullLoaded, err = newEcdsaWitness[emulated.P256Fp, emulated.P256Fr](loadedProveInput, config.CommitHash)
+	default:
+		err = fmt.Errorf("unknown curve %q", curve)
 	}
-	witnessFullLoaded, err := frontend.NewWitness(&witnessCircuitLoaded, ecc.BN254.ScalarField())
 	if err != nil {
 		return fmt.Sprintf("Error creating witness: %v", err)
 	}
@@ -179,7 +184,7 @@ func performVerification() string {
 
 	// Prove
 	startProveLoaded := time.Now()
-	proofLoaded, err := plonk.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
+	proofLoaded, err := b.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
 	if err != nil {
 		return fmt.Sprintf("Error generating proof: %v", err)
 	}
@@ -187,12 +192,16 @@ func performVerification() string {
 
 	// Verify
 	startVerifyLoaded := time.Now()
-	err = plonk.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
+	err = b.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
 	if err != nil {
 		return fmt.Sprintf("Verification FAILED: %v", err)
 	}
 	fmt.Printf("Verification from loaded files: Verification SUCCEEDED (%.1fms)!\n", float64(time.Since(startVerifyLoaded).Milliseconds()))
 	fmt.Println("ReadFromFile test PASSED. Loaded artifacts are valid and functional.")
+	proofBytes, err := b.MarshalProof(proofLoaded)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
 
 	// 9. Export the Solidity verifier test
 	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
@@ -200,53 +209,387 @@ func performVerification() string {
 	// Create solidity directory if it doesn't exist
 	os.MkdirAll("solidity/test", 0755)
 	
-	verifierTestFile, err := os.Create("solidity/test/Verifier.t.sol")
+	verifierTestFile, err := os.Create("solidity/test/" + verifierName(curve) + ".t.sol")
 	if err != nil {
 		return fmt.Sprintf("Error creating solidity test file: %v", err)
 	}
 	defer verifierTestFile.Close()
 
+	// with an expiry, validUntil follows the message in the public inputs, and
+	// the test account compares it to the timestamp
+	nbInputs := 8
+	inputsLayout := "msg (4 limbs), chainId, account, nullifier, commitment"
+	inputIndexes := "    uint256 constant NULLIFIER_INDEX = 6;\n"
+	expiryCheck, warp := "", ""
+	if config.Expiry {
+		nbInputs = 9
+		inputsLayout = "msg (4 limbs), validUntil, chainId, account, nullifier, commitment"
+		inputIndexes = "    uint256 constant VALID_UNTIL_INDEX = 4;\n    uint256 constant NULLIFIER_INDEX = 7;\n"
+		expiryCheck = "        // validUntil is a timestamp here, a block number for an account counting blocks\n        if (block.timestamp > inputs[VALID_UNTIL_INDEX]) return false;\n"
+		warp = "        vm.warp(inputs[VALID_UNTIL_INDEX]);\n"
+	}
+
 	// header
 	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
 pragma solidity ^0.8.25;
 
 import {Test, console} from "forge-std/Test.sol";
-import {PlonkVerifier} from "../src/Verifier.sol";
+import {PlonkVerifier} from "../src/` + verifierName(curve) + `.sol";
 
-contract VerifierTest is Test {
+contract ` + verifierName(curve) + `Test is Test {
     PlonkVerifier ZkK1;
 
+    // public inputs: ` + inputsLayout + `
+` + inputIndexes + `    mapping(uint256 => bool) usedNullifiers;
+
     function setUp() public {
         ZkK1 = new PlonkVerifier();
     }
 
-    function test_k1Plonk() public view {
+    // useProof accepts a proof only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+` + expiryCheck + `        if (!ZkK1.Verify(proof, inputs)) return false;
+        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
+    }
+
+    function test_` + curve + `Plonk() public {
 `))
 
-	Proof := proofLoaded.(*plonk_bn254.Proof)
-	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(Proof.MarshalSolidity())[2:] + `";`))
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";`))
 	verifierTestFile.Write([]byte("\n"))
 
 	PI := fmt.Sprintf("%v", publicWitnessLoaded.Vector())
 
-	verifierTestFile.Write([]byte("uint256[5] memory public_inputs = " + PI + ";\n"))
+	inputsDecl := fmt.Sprintf(`uint256[%d] memory public_inputs = %s;
 
-	// footer
-	verifierTestFile.Write([]byte(`
-        uint256[] memory inputs = new uint256[](5);
-        for (uint i = 0; i < 5; i++) inputs[i] = uint256(public_inputs[i]);
+        uint256[] memory inputs = new uint256[](%d);
+        for (uint i = 0; i < %d; i++) inputs[i] = uint256(public_inputs[i]);
+`, nbInputs, PI, nbInputs, nbInputs)
+	verifierTestFile.Write([]byte(inputsDecl))
 
-        bool res = ZkK1.Verify(proof, inputs);
+	// footer
+	verifierTestFile.Write([]byte(warp + `
+        bool res = useProof(proof, inputs);
         assertTrue(res);
         console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
     }
-}
 `))
-	fmt.Println("Successfully exported solidity/test/Verifier.t.sol")
+	if config.Expiry {
+		verifierTestFile.Write([]byte(`
+    function test_` + curve + `PlonkExpired() public {
+        bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";
+        ` + inputsDecl + `
+        // used after its expiry, the proof is rejected
+        vm.warp(inputs[VALID_UNTIL_INDEX] + 1);
+        assertFalse(useProof(proof, inputs));
+    }
+`))
+	}
+	verifierTestFile.Write([]byte("}\n"))
+	fmt.Printf("Successfully exported solidity/test/%s.t.sol\n", verifierName(curve))
 
 	return "SUCCESS: All operations completed successfully"
 }
 
+// newEcdsaWitness builds the full witness of the circuit instantiated on the
+// curve of the signer.
+func newEcdsaWitness[T, S emulated.FieldParams](in ProveInputEcdsa, commitHash string) (witness.Witness, error) {
+	// Decode hex strings back to big.Int for witness construction
+	fields := []string{in.R, in.S, in.MsgHash, in.PubX, in.PubY, in.Address, in.Nonce, in.ChainID, in.Account, in.Com}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	r, s, msgHash, pubX, pubY := values[0], values[1], values[2], values[3], values[4]
+	normalizeS[S](s)
+	address, nonce, chainID, account, com := values[5], values[6], values[7], values[8], values[9]
+
+	// with an expiry, the signed digest is keccak256(msgHash || validUntil)
+	var validUntil []frontend.Variable
+	if in.ValidUntil != "" {
+		if _, err := signedDigest[S](in); err != nil {
+			return nil, err
+		}
+		b, err := hex.DecodeString(in.ValidUntil)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", in.ValidUntil, err)
+		}
+		validUntil = []frontend.Variable{new(big.Int).SetBytes(b)}
+	}
+
+	// the nullifier binds the proof to this message, chain and account
+	nullifier, err := circuits.ComputeNullifier(commitHash, nonce, circuits.ScalarLimbs[S](msgHash), chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := circuits.Circuit[T, S]{
+		Sig: ecdsa.Signature[S]{
+			R: emulated.ValueOf[S](r),
+			S: emulated.ValueOf[S](s),
+		},
+		Msg:        emulated.ValueOf[S](msgHash),
+		ValidUntil: validUntil,
+		Pub: ecdsa.PublicKey[T, S]{
+			X: emulated.ValueOf[T](pubX),
+			Y: emulated.ValueOf[T](pubY),
+		},
+		Address:   address,
+		Nonce:     nonce,
+		ChainID:   chainID,
+		Account:   account,
+		Nullifier: nullifier,
+		Com:       com,
+
+		CommitHash: commitHash,
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// signedDigest returns the digest signed by the key: the message hash, or
+// keccak256(msgHash || validUntil) for a proof that expires.
+func signedDigest[S emulated.FieldParams](in ProveInputEcdsa) (*big.Int, error) {
+	msg, err := hex.DecodeString(in.MsgHash)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex %q: %w", in.MsgHash, err)
+	}
+	msgHash := new(big.Int).SetBytes(msg)
+	if in.ValidUntil == "" {
+		return msgHash, nil
+	}
+	b, err := hex.DecodeString(in.ValidUntil)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex %q: %w", in.ValidUntil, err)
+	}
+	validUntil := new(big.Int).SetBytes(b)
+	if validUntil.BitLen() > 64 {
+		return nil, fmt.Errorf("validUntil %s does not fit in 64 bits", in.ValidUntil)
+	}
+	// the circuit hashes the message read from the limbs of the reduced scalar
+	var fr S
+	if msgHash.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("the digest %x is not reduced modulo the group order, it cannot expire", msgHash)
+	}
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(msgHash.FillBytes(make([]byte, 32)))
+	keccak.Write(validUntil.FillBytes(make([]byte, 32)))
+	return new(big.Int).SetBytes(keccak.Sum(nil)), nil
+}
+
+// normalizeS replaces s by n - s when s > (n-1)/2. (r, n - s) is also a valid
+// signature, so the witness is the same for both forms of the signature.
+func normalizeS[S emulated.FieldParams](s *big.Int) {
+	var fr S
+	if s.Cmp(new(big.Int).Rsh(fr.Modulus(), 1)) > 0 {
+		s.Sub(fr.Modulus(), s)
+	}
+}
+
+// recoverPublicKey sets the public key of the input to the one recovered from
+// the signature and the message hash, as ecrecover does, and checks that it is
+// the key of the committed address. The input is unchanged when v is empty.
+func recoverPublicKey[T, S emulated.FieldParams](in *ProveInputEcdsa) error {
+	if in.V == "" {
+		return nil
+	}
+	fields := []string{in.R, in.S, in.V, in.Address}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	r, s, v, address := values[0], values[1], values[2], values[3]
+	msgHash, err := signedDigest[S](*in)
+	if err != nil {
+		return err
+	}
+
+	// Ethereum signatures carry v = 27 + recovery id
+	if v.Cmp(big.NewInt(27)) >= 0 {
+		v.Sub(v, big.NewInt(27))
+	}
+	if v.Cmp(big.NewInt(3)) > 0 {
+		return fmt.Errorf("invalid recovery id %s", in.V)
+	}
+	x, y, err := ecRecover[T, S](msgHash, r, s, uint(v.Uint64()))
+	if err != nil {
+		return err
+	}
+
+	pubX := x.FillBytes(make([]byte, 32))
+	pubY := y.FillBytes(make([]byte, 32))
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(pubX)
+	keccak.Write(pubY)
+	recovered := keccak.Sum(nil)[12:]
+	if new(big.Int).SetBytes(recovered).Cmp(address) != 0 {
+		return fmt.Errorf("the signature is from address %x, the commitment is to address %s", recovered, in.Address)
+	}
+	in.PubX = hex.EncodeToString(pubX)
+	in.PubY = hex.EncodeToString(pubY)
+	return nil
+}
+
+// ecRecover returns the public key Q = r^-1 (s R - z G) of an ECDSA signature,
+// R being the point of x-coordinate r + (v>>1) n and of parity v&1, and z the
+// message hash reduced modulo n, as in the circuit.
+func ecRecover[T, S emulated.FieldParams](msgHash, r, s *big.Int, v uint) (*big.Int, *big.Int, error) {
+	var fp T
+	var fr S
+	p, n := fp.Modulus(), fr.Modulus()
+	params := sw_emulated.GetCurveParams[T]()
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf("r and s must be in [1, n-1]")
+	}
+
+	rx := new(big.Int).Set(r)
+	if v>>1 == 1 {
+		rx.Add(rx, n)
+	}
+	if rx.Cmp(p) >= 0 {
+		return nil, nil, fmt.Errorf("r + n is not a coordinate of the curve")
+	}
+	// y^2 = x^3 + ax + b
+	y2 := new(big.Int).Exp(rx, big.NewInt(3), p)
+	y2.Add(y2, new(big.Int).Mul(params.A, rx))
+	y2.Add(y2, params.B).Mod(y2, p)
+	ry := new(big.Int).ModSqrt(y2, p)
+	if ry == nil {
+		return nil, nil, fmt.Errorf("r is not the x-coordinate of a point of the curve")
+	}
+	if ry.Bit(0) != v&1 {
+		ry.Sub(p, ry)
+	}
+
+	rInv := new(big.Int).ModInverse(r, n)
+	u1 := new(big.Int).Mod(msgHash, n)
+	u1.Mul(u1, rInv).Neg(u1).Mod(u1, n)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, n)
+	x1, y1 := scalarMul(params.A, p, params.Gx, params.Gy, u1)
+	x2, y2 := scalarMul(params.A, p, rx, ry, u2)
+	qx, qy := pointAdd(params.A, p, x1, y1, x2, y2)
+	if qx == nil {
+		return nil, nil, fmt.Errorf("the recovered key is the point at infinity")
+	}
+	return qx, qy, nil
+}
+
+// pointAdd adds two affine points of y^2 = x^3 + ax + b over F_p, the point at
+// infinity being nil.
+func pointAdd(a, p, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	lambda := new(big.Int)
+	if x1.Cmp(x2) == 0 {
+		sum := new(big.Int).Add(y1, y2)
+		if sum.Mod(sum, p).Sign() == 0 {
+			return nil, nil
+		}
+		// (3x^2 + a) / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3)).Add(num, a)
+		den := new(big.Int).Lsh(y1, 1)
+		lambda.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	} else {
+		// (y2 - y1) / (x2 - x1)
+		den := new(big.Int).Sub(x2, x1)
+		lambda.Mul(new(big.Int).Sub(y2, y1), den.ModInverse(den.Mod(den, p), p))
+	}
+	lambda.Mod(lambda, p)
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, x1).Sub(x, x2).Mod(x, p)
+	y := new(big.Int).Sub(x1, x)
+	y.Mul(y, lambda).Sub(y, y1).Mod(y, p)
+	return x, y
+}
+
+// scalarMul computes k(x, y) by double-and-add.
+func scalarMul(a, p, x, y, k *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		rx, ry = pointAdd(a, p, rx, ry, rx, ry)
+		if k.Bit(i) == 1 {
+			rx, ry = pointAdd(a, p, rx, ry, x, y)
+		}
+	}
+	return rx, ry
+}
+
+// artifactName returns the file name of a setup artifact for the signer curve.
+// The secp256k1 artifacts keep their historical names.
+func artifactName(curve, name string) string {
+	if curve == "" || curve == "secp256k1" {
+		return name
+	}
+	return curve + "_" + name
+}
+
+// verifierName returns the name of the Solidity verifier for the signer curve.
+func verifierName(curve string) string {
+	if curve == "p256" {
+		return "P256Verifier"
+	}
+	return "Verifier"
+}
+
+// checkCommitment recomputes natively the commitment of the input, so that a
+// commitment made for another chain, account or layout is reported before proving.
+func checkCommitment(in ProveInputEcdsa, commitHash string) error {
+	h, err := circuits.NewNativeCommitmentHasher(commitHash)
+	if err != nil {
+		return err
+	}
+	if in.Version == 1 {
+		// h(tag, chainId, account, address, nonce)
+		prefix := []*big.Int{new(big.Int).SetBytes([]byte(circuits.CommitTagV1))}
+		for _, field := range []string{in.ChainID, in.Account} {
+			b, err := hex.DecodeString(field)
+			if err != nil {
+				return fmt.Errorf("decoding hex %q: %w", field, err)
+			}
+			prefix = append(prefix, new(big.Int).SetBytes(b))
+		}
+		for _, v := range prefix {
+			h.Write(v.FillBytes(make([]byte, 32)))
+		}
+	}
+	for _, field := range []string{in.Address, in.Nonce} {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		h.Write(b)
+	}
+	com, err := hex.DecodeString(in.Com)
+	if err != nil {
+		return fmt.Errorf("decoding hex %q: %w", in.Com, err)
+	}
+	if new(big.Int).SetBytes(h.Sum(nil)).Cmp(new(big.Int).SetBytes(com)) != 0 {
+		if in.Version == 1 {
+			return fmt.Errorf("the commitment does not open to the address, the nonce, the chain id %s and the account %s", in.ChainID, in.Account)
+		}
+		return fmt.Errorf("the commitment does not open to the address and the nonce")
+	}
+	return nil
+}
+
 // writeToFile is a helper to serialize and write gnark objects or byte readers to files.
 func writeToFile(filename string, data interface{}) {
 	file, err := os.Create(filename)
@@ -292,6 +635,12 @@ func readFromFile(filename string, data interface{}) error {
 		if err != nil {
 			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
 		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
 	default:
 		return fmt.Errorf("unsupported type for reading from file: %T", data)
 	}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+	recursion_plonk "github.com/consensys/gnark/std/recursion/plonk"
+
+	"circuits"
+	"zkbackend"
+)
+
+// AggregateConfig struct for JSON serialization of the aggregation options chosen at setup time.
+type AggregateConfig struct {
+	NbProofs int    `json:"nbProofs"` // Number of aggregated proofs
+	Curve    string `json:"curve"`    // Curve of the signers of the inner proofs
+	Backend  string `json:"backend"`  // Proof system of the aggregation proof, plonk
+	Unsafe   bool   `json:"unsafe"`   // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println("Usage: go run aggregate_proofs.go <proofs directory>")
+		os.Exit(1)
+	}
+	dir := os.Args[1]
+
+	// 1. Read the aggregation options and the inner verifying key
+	var config AggregateConfig
+	err := readFromFile("aggregate_setup_config.json", &config)
+	if err != nil {
+		fmt.Printf("Error reading aggregate_setup_config.json: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: aggregate_setup_config.json is a test setup, its proofs must not be trusted on-chain\n")
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	// the circuit verifies PLONK proofs of the single signer circuit
+	inner := zkbackend.Plonk{}
+	innerVK := inner.NewVerifyingKey()
+	err = readFromFile(artifactName(config.Curve, "verifying_key.bin"), innerVK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(config.Curve, "verifying_key.bin"), err)
+		os.Exit(1)
+	}
+
+	// 2. Read back the aggregation circuit and its keys
+	loadedR1CS := b.NewCS()
+	err = readFromFile("aggregate_r1cs.bin", loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading aggregate_r1cs.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read aggregate_r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+	loadedPK := b.NewProvingKey()
+	err = readFromFile("aggregate_proving_key.bin", loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading aggregate_proving_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile("aggregate_verifying_key.bin", loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading aggregate_verifying_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 3. Read the inner proofs, written by prove_blinded_k1.go -aggregate
+	proofFiles, err := filepath.Glob(filepath.Join(dir, "*.proof"))
+	if err != nil {
+		fmt.Printf("Error listing %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+	sort.Strings(proofFiles)
+	if len(proofFiles) != config.NbProofs {
+		fmt.Printf("Error: %d proofs in %s, the setup aggregates %d\n", len(proofFiles), dir, config.NbProofs)
+		os.Exit(1)
+	}
+	innerProofs := make([]zkbackend.Proof, len(proofFiles))
+	innerWitnesses := make([]witness.Witness, len(proofFiles))
+	for i, proofFile := range proofFiles {
+		innerProofs[i] = inner.NewProof()
+		err = readFromFile(proofFile, innerProofs[i])
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", proofFile, err)
+			os.Exit(1)
+		}
+		innerWitnesses[i], err = witness.New(ecc.BN254.ScalarField())
+		if err != nil {
+			fmt.Printf("Error creating witness: %v\n", err)
+			os.Exit(1)
+		}
+		witnessFile := strings.TrimSuffix(proofFile, ".proof") + ".pub"
+		err = readFromFile(witnessFile, innerWitnesses[i])
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", witnessFile, err)
+			os.Exit(1)
+		}
+		field := ecc.BN254.ScalarField()
+		err = inner.Verify(innerProofs[i], innerVK, innerWitnesses[i], recursion_plonk.GetNativeVerifierOptions(field, field))
+		if err != nil {
+			fmt.Printf("Error: %s does not verify, was it created with -aggregate? %v\n", proofFile, err)
+			os.Exit(1)
+		}
+		fmt.Println("Read", proofFile)
+	}
+
+	// 4. Create the witness of the aggregation circuit
+	assignment, innerInputs, err := newAggregateAssignment(innerProofs, innerWitnesses)
+	if err != nil {
+		fmt.Printf("Error creating aggregation witness: %v\n", err)
+		os.Exit(1)
+	}
+	witnessFull, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		fmt.Printf("Error creating full witness: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitness, err := witnessFull.Public()
+	if err != nil {
+		fmt.Printf("Error getting public witness: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Prove and verify
+	fmt.Println("\n--- Proving aggregation ---")
+	startProve := time.Now()
+	proof, err := b.Prove(loadedR1CS, loadedPK, witnessFull)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
+	err = b.Verify(proof, loadedVK, publicWitness)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proof)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	verifierTestFile, err := os.Create("solidity/test/AggregateVerifier.t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/AggregateVerifier.t.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	nbInputs := len(innerInputs) / len(innerProofs)
+	// header
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/AggregateVerifier.sol";
+
+contract AggregateVerifierTest is Test {
+    PlonkVerifier ZkAgg;
+
+    uint256 constant R_MOD = ` + fr.Modulus().String() + `;
+
+    // public inputs of each inner proof: msg (4 limbs), chainId, account, nullifier, commitment
+    uint256 constant NB_INPUTS = ` + fmt.Sprint(nbInputs) + `;
+    uint256 constant NULLIFIER_INDEX = NB_INPUTS - 2;
+    mapping(uint256 => bool) usedNullifiers;
+
+    function setUp() public {
+        ZkAgg = new PlonkVerifier();
+    }
+
+    // useAggregate accepts the inner public inputs of an aggregated proof if
+    // none of their nullifiers was used, as the account does
+    function useAggregate(bytes memory proof, uint256[] memory inner) internal returns (bool) {
+        for (uint i = 0; i < inner.length; i += NB_INPUTS) {
+            if (usedNullifiers[inner[i + NULLIFIER_INDEX]]) return false;
+            for (uint j = 0; j < i; j += NB_INPUTS) {
+                if (inner[j + NULLIFIER_INDEX] == inner[i + NULLIFIER_INDEX]) return false;
+            }
+        }
+        uint256[] memory inputs = new uint256[](1);
+        inputs[0] = uint256(keccak256(abi.encodePacked(inner))) % R_MOD;
+        if (!ZkAgg.Verify(proof, inputs)) return false;
+        for (uint i = 0; i < inner.length; i += NB_INPUTS) {
+            usedNullifiers[inner[i + NULLIFIER_INDEX]] = true;
+        }
+        return true;
+    }
+
+    function test_aggregatePlonk() public {
+`))
+
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";`))
+	verifierTestFile.Write([]byte("\n"))
+
+	PI := make([]string, len(innerInputs))
+	for i := range innerInputs {
+		PI[i] = innerInputs[i].String()
+	}
+	PI[0] = "uint256(" + PI[0] + ")"
+	verifierTestFile.Write([]byte(fmt.Sprintf("uint256[%d] memory inner_inputs = [%s];\n", len(PI), strings.Join(PI, ", "))))
+
+	// footer
+	verifierTestFile.Write([]byte(fmt.Sprintf(`
+        uint256[] memory inner = new uint256[](%d);
+        for (uint i = 0; i < %d; i++) inner[i] = inner_inputs[i];
+
+        bool res = useAggregate(proof, inner);
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifiers are spent, the same proofs cannot be replayed
+        assertFalse(useAggregate(proof, inner));
+    }
+}
+`, len(PI), len(PI))))
+	fmt.Println("Successfully exported solidity/test/AggregateVerifier.t.sol")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitness.Vector(), "\"\n")
+}
+
+// newAggregateAssignment returns the witness of the aggregation circuit for
+// the inner proofs, and the concatenation of their public inputs.
+func newAggregateAssignment(proofs []zkbackend.Proof, witnesses []witness.Witness) (*circuits.AggregateCircuit, fr.Vector, error) {
+	assignment := &circuits.AggregateCircuit{
+		Proofs:    make([]recursion_plonk.Proof[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine], len(proofs)),
+		Witnesses: make([]recursion_plonk.Witness[sw_bn254.ScalarField], len(proofs)),
+	}
+	var innerInputs fr.Vector
+	for i := range proofs {
+		proof, ok := proofs[i].(plonk.Proof)
+		if !ok {
+			return nil, nil, fmt.Errorf("proof %d is not a PLONK proof", i)
+		}
+		var err error
+		assignment.Proofs[i], err = recursion_plonk.ValueOfProof[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine](proof)
+		if err != nil {
+			return nil, nil, err
+		}
+		assignment.Witnesses[i], err = recursion_plonk.ValueOfWitness[sw_bn254.ScalarField](witnesses[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		innerInputs = append(innerInputs, witnesses[i].Vector().(fr.Vector)...)
+	}
+
+	// keccak256(abi.encodePacked(inputs)) mod r, as in the circuit
+	packed := make([]byte, 0, 32*len(innerInputs))
+	for i := range innerInputs {
+		b := innerInputs[i].Bytes()
+		packed = append(packed, b[:]...)
+	}
+	inputsHash := new(big.Int).SetBytes(crypto.Keccak256(packed))
+	assignment.InputsHash = inputsHash.Mod(inputsHash, fr.Modulus())
+	return assignment, innerInputs, nil
+}
+
+// artifactName returns the file name of a setup artifact for the signer curve.
+// The secp256k1 artifacts keep their historical names.
+func artifactName(curve, name string) string {
+	if curve == "" || curve == "secp256k1" {
+		return name
+	}
+	return curve + "_" + name
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *AggregateConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
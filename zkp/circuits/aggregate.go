@@ -0,0 +1,88 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	recursion_plonk "github.com/consensys/gnark/std/recursion/plonk"
+)
+
+// AggregateCircuit verifies proofs of the single signer circuit and exposes
+// the hash of all their public inputs as its only public input.
+type AggregateCircuit struct {
+	Proofs       []recursion_plonk.Proof[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine]      `gnark:",secret"`
+	Witnesses    []recursion_plonk.Witness[sw_bn254.ScalarField]                                          `gnark:",secret"`
+	VerifyingKey recursion_plonk.VerifyingKey[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine] `gnark:"-"`       // inner verifying key, constant
+	InputsHash   frontend.Variable                                                                        `gnark:",public"` // keccak256 of the inner public inputs, reduced mod r
+}
+
+// NewAggregateCircuit returns the circuit verifying n proofs of the inner
+// circuit ccs, with verifying key vk.
+func NewAggregateCircuit(n int, ccs constraint.ConstraintSystem, vk plonk.VerifyingKey) (*AggregateCircuit, error) {
+	circuitVK, err := recursion_plonk.ValueOfVerifyingKey[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine](vk)
+	if err != nil {
+		return nil, err
+	}
+	circuit := &AggregateCircuit{
+		Proofs:       make([]recursion_plonk.Proof[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine], n),
+		Witnesses:    make([]recursion_plonk.Witness[sw_bn254.ScalarField], n),
+		VerifyingKey: circuitVK,
+	}
+	for i := 0; i < n; i++ {
+		circuit.Proofs[i] = recursion_plonk.PlaceholderProof[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine](ccs)
+		circuit.Witnesses[i] = recursion_plonk.PlaceholderWitness[sw_bn254.ScalarField](ccs)
+	}
+	return circuit, nil
+}
+
+func (c *AggregateCircuit) Define(api frontend.API) error {
+	verifier, err := recursion_plonk.NewVerifier[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine, sw_bn254.GTEl](api)
+	if err != nil {
+		return err
+	}
+	err = verifier.AssertSameProofs(c.VerifyingKey, c.Proofs, c.Witnesses)
+	if err != nil {
+		return err
+	}
+
+	// the inner public inputs, as canonical 32-byte big-endian integers, are
+	// hashed as the contract does with keccak256(abi.encodePacked(inputs))
+	scalarApi, err := emulated.NewField[sw_bn254.ScalarField](api)
+	if err != nil {
+		return err
+	}
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return err
+	}
+	keccak, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return err
+	}
+	for _, w := range c.Witnesses {
+		for i := range w.Public {
+			inputBits := scalarApi.ToBitsCanonical(&w.Public[i])
+			for len(inputBits) < 256 {
+				inputBits = append(inputBits, 0)
+			}
+			inputBytes := make([]uints.U8, 32)
+			for j := range inputBytes {
+				inputBytes[31-j] = uapi.ByteValueOf(api.FromBinary(inputBits[8*j : 8*j+8]...))
+			}
+			keccak.Write(inputBytes)
+		}
+	}
+	digest := keccak.Sum()
+
+	// the native arithmetic reduces the digest mod r
+	var inputsHash frontend.Variable = 0
+	for _, b := range digest {
+		inputsHash = api.Add(api.Mul(inputsHash, 256), b.Val)
+	}
+	api.AssertIsEqual(c.InputsHash, inputsHash)
+	return nil
+}
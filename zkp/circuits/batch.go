@@ -0,0 +1,73 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// BatchSize is the number of digests proven at once. A batch of fewer digests
+// is padded by repeating the first signature.
+const BatchSize = 4
+
+// BatchCircuit proves that the key committed in Com signed the first Count
+// digests of Msgs, each with its own nullifier. The padding slots hold a valid
+// signature of the same key and a zero nullifier.
+type BatchCircuit[T, S emulated.FieldParams] struct {
+	Sigs       [BatchSize]ecdsa.Signature[S]  `gnark:",secret"` // signatures
+	Msgs       [BatchSize]emulated.Element[S] `gnark:",public"` // messages
+	Count      frontend.Variable              `gnark:",public"` // number of used slots, in [1, BatchSize]
+	Pub        ecdsa.PublicKey[T, S]          `gnark:",secret"` // now secret
+	Address    frontend.Variable              `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce      frontend.Variable              `gnark:",secret"` // secret nonce
+	ChainID    frontend.Variable              `gnark:",public"` // chain id of the account
+	Account    frontend.Variable              `gnark:",public"` // address of the account using the proof
+	Nullifiers [BatchSize]frontend.Variable   `gnark:",public"` // h(nonce, msg, chainId, account), 0 for padding
+	Com        frontend.Variable              `gnark:",public"` // public commitment, last public input
+
+	CommitHash string `gnark:"-"` // commitment hash, mimc or poseidon2
+	LowS       bool   `gnark:"-"` // enforce the canonical low-s signatures
+}
+
+func (c *BatchCircuit[T, S]) Define(api frontend.API) error {
+	// 1 <= Count <= BatchSize
+	api.AssertIsLessOrEqual(api.Sub(c.Count, 1), BatchSize-1)
+
+	curveParams := sw_emulated.GetCurveParams[T]()
+	var used frontend.Variable = 1
+	for i := range c.Msgs {
+		c.Pub.Verify(api, curveParams, &c.Msgs[i], &c.Sigs[i])
+		if c.LowS {
+			if err := AssertCanonicalSignature(api, &c.Sigs[i]); err != nil {
+				return err
+			}
+		}
+
+		// the slot is used while i < Count
+		if i > 0 {
+			used = api.Mul(used, api.Sub(1, api.IsZero(api.Sub(c.Count, i))))
+		}
+		nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msgs[i].Limbs, c.ChainID, c.Account)
+		if err != nil {
+			return err
+		}
+		api.AssertIsEqual(c.Nullifiers[i], api.Select(used, nullifier, 0))
+	}
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package circuits holds the circuits of the setup tools and the provers, and
+// the gadgets they share: the Ethereum address of a key, the hash of the public
+// key commitment and the nullifier. The setup and the prover of a circuit import
+// it from here, so that they always compile the same constraint system.
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/permutation/poseidon2"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// CommitTagV1 is the domain separation tag of the version 1 commitment, absorbed
+// as a field element.
+const CommitTagV1 = "ZKeeper com v1"
+
+// NewCommitmentHasher returns the in-circuit hash of the public key commitment.
+func NewCommitmentHasher(api frontend.API, name string) (hash.FieldHasher, error) {
+	switch name {
+	case "", "mimc":
+		return mimc.New(api)
+	case "poseidon2":
+		// same parameters as the native gnark-crypto BN254 Poseidon2 hasher
+		perm, err := poseidon2.NewPoseidon2FromParameters(api, 2, 6, 50)
+		if err != nil {
+			return nil, err
+		}
+		return hash.NewMerkleDamgardHasher(api, perm, 0), nil
+	default:
+		return nil, fmt.Errorf("unknown commitment hash %q", name)
+	}
+}
+
+// Nullifier computes in-circuit the nullifier h(nonce, msg, chainId, account)
+// with the commitment hash, the message being absorbed as its limbs. It is
+// unique per message, chain and account, and the secret nonce keeps it
+// unlinkable to the commitment.
+func Nullifier(api frontend.API, name string, nonce frontend.Variable, msg []frontend.Variable, chainID, account frontend.Variable) (frontend.Variable, error) {
+	h, err := NewCommitmentHasher(api, name)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(nonce)
+	h.Write(msg...)
+	h.Write(chainID, account)
+	return h.Sum(), nil
+}
+
+// EthAddress computes in-circuit the Ethereum address keccak256(X||Y)[12:] of
+// the public key, X and Y being encoded as 32-byte big-endian integers.
+func EthAddress[T, S emulated.FieldParams](api frontend.API, pub *ecdsa.PublicKey[T, S]) (frontend.Variable, error) {
+	baseApi, err := emulated.NewField[T](api)
+	if err != nil {
+		return nil, err
+	}
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return nil, err
+	}
+	keccak, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return nil, err
+	}
+	for _, coord := range []*emulated.Element[T]{&pub.X, &pub.Y} {
+		// little-endian bits of the reduced coordinate
+		coordBits := baseApi.ToBitsCanonical(coord)
+		for len(coordBits) < 256 {
+			coordBits = append(coordBits, 0)
+		}
+		coordBytes := make([]uints.U8, 32)
+		for i := range coordBytes {
+			coordBytes[31-i] = uapi.ByteValueOf(bits.FromBinary(api, coordBits[8*i:8*i+8]))
+		}
+		keccak.Write(coordBytes)
+	}
+	digest := keccak.Sum()
+
+	// the 20 last bytes, read big-endian, fit in a native field element
+	var address frontend.Variable = 0
+	for _, b := range digest[12:] {
+		address = api.Add(api.Mul(address, 256), b.Val)
+	}
+	return address, nil
+}
+
+// AssertCanonicalSignature checks that r and s are non-zero and reduced modulo
+// the group order n, and that s <= (n-1)/2, the low-s form required by Ethereum.
+func AssertCanonicalSignature[S emulated.FieldParams](api frontend.API, sig *ecdsa.Signature[S]) error {
+	scalarApi, err := emulated.NewField[S](api)
+	if err != nil {
+		return err
+	}
+	var fr S
+	halfOrder := new(big.Int).Rsh(fr.Modulus(), 1)
+	scalarApi.AssertIsInRange(&sig.R)
+	scalarApi.AssertIsLessOrEqual(&sig.S, scalarApi.NewElement(halfOrder))
+	api.AssertIsEqual(scalarApi.IsZero(&sig.R), 0)
+	api.AssertIsEqual(scalarApi.IsZero(&sig.S), 0)
+	return nil
+}
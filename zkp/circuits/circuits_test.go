@@ -0,0 +1,98 @@
+package circuits
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+	"github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// addressCircuit checks the in-circuit address of the key
+type addressCircuit struct {
+	Pub     ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]
+	Address frontend.Variable `gnark:",public"`
+}
+
+func (c *addressCircuit) Define(api frontend.API) error {
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+	return nil
+}
+
+// nullifierCircuit checks the in-circuit nullifier of the message
+type nullifierCircuit struct {
+	Nonce     frontend.Variable
+	Msg       emulated.Element[emulated.Secp256k1Fr]
+	ChainID   frontend.Variable
+	Account   frontend.Variable
+	Nullifier frontend.Variable `gnark:",public"`
+
+	CommitHash string `gnark:"-"`
+}
+
+func (c *nullifierCircuit) Define(api frontend.API) error {
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
+
+func TestEthAddress(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	assignment := &addressCircuit{
+		Pub: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](key.PublicKey.X),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](key.PublicKey.Y),
+		},
+		Address: new(big.Int).SetBytes(address[:]),
+	}
+	if err := test.IsSolved(&addressCircuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+		t.Fatal(err)
+	}
+	assignment.Address = new(big.Int).Add(new(big.Int).SetBytes(address[:]), big.NewInt(1))
+	if test.IsSolved(&addressCircuit{}, assignment, ecc.BN254.ScalarField()) == nil {
+		t.Fatal("the address is not the one of the key")
+	}
+}
+
+func TestNullifier(t *testing.T) {
+	// a digest above the group order, reduced by the emulated scalar
+	msg := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	nonce, chainID, account := big.NewInt(42), big.NewInt(1), big.NewInt(0x1111)
+	for _, name := range []string{"mimc", "poseidon2"} {
+		t.Run(name, func(t *testing.T) {
+			nullifier, err := ComputeNullifier(name, nonce, ScalarLimbs[emulated.Secp256k1Fr](msg), chainID, account)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assignment := &nullifierCircuit{
+				Nonce:     nonce,
+				Msg:       emulated.ValueOf[emulated.Secp256k1Fr](msg),
+				ChainID:   chainID,
+				Account:   account,
+				Nullifier: nullifier,
+			}
+			if err := test.IsSolved(&nullifierCircuit{CommitHash: name}, assignment, ecc.BN254.ScalarField()); err != nil {
+				t.Fatal(err)
+			}
+			assignment.Account = big.NewInt(0x2222)
+			if test.IsSolved(&nullifierCircuit{CommitHash: name}, assignment, ecc.BN254.ScalarField()) == nil {
+				t.Fatal("the nullifier does not depend on the account")
+			}
+		})
+	}
+}
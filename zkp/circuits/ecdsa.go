@@ -0,0 +1,123 @@
+package circuits
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// Circuit defines the circuit structure as provided by you.
+type Circuit[T, S emulated.FieldParams] struct {
+	Sig        ecdsa.Signature[S]    `gnark:",secret"` // signature
+	Msg        emulated.Element[S]   `gnark:",public"` // message
+	ValidUntil []frontend.Variable   `gnark:",public"` // expiry of the proof, signed with msg, empty without expiry
+	Pub        ecdsa.PublicKey[T, S] `gnark:",secret"` // now secret
+	Address    frontend.Variable     `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce      frontend.Variable     `gnark:",secret"` // secret nonce
+	ChainID    frontend.Variable     `gnark:",public"` // chain id of the account
+	Account    frontend.Variable     `gnark:",public"` // address of the account using the proof
+	Nullifier  frontend.Variable     `gnark:",public"` // h(nonce, msg, chainId, account)
+	Com        frontend.Variable     `gnark:",public"` // public commitment, last public input
+
+	CommitHash    string `gnark:"-"` // commitment hash, mimc or poseidon2
+	LowS          bool   `gnark:"-"` // enforce the canonical low-s signature
+	CommitVersion int    `gnark:"-"` // commitment layout, 0: h(address, nonce), 1: h(tag, chainId, account, address, nonce)
+}
+
+func (c *Circuit[T, S]) Define(api frontend.API) error {
+	curveParams := sw_emulated.GetCurveParams[T]()
+	// with an expiry, the signed digest is keccak256(msg || validUntil)
+	digest := &c.Msg
+	if len(c.ValidUntil) == 1 {
+		var err error
+		digest, err = expiringDigest(api, &c.Msg, c.ValidUntil[0])
+		if err != nil {
+			return err
+		}
+	}
+	c.Pub.Verify(api, curveParams, digest, &c.Sig)
+	if c.LowS {
+		if err := AssertCanonicalSignature(api, &c.Sig); err != nil {
+			return err
+		}
+	}
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+
+	// specify constraints
+	// h(preImage) == hash, the version 1 layout binding the commitment to
+	// the chain and the account
+	if c.CommitVersion == 1 {
+		h.Write(new(big.Int).SetBytes([]byte(CommitTagV1)), c.ChainID, c.Account)
+	}
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// the nullifier is unique per message, chain and account, and the secret
+	// nonce keeps it unlinkable to the commitment
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
+
+// expiringDigest computes in-circuit the digest keccak256(msg || validUntil)
+// signed by a proof that expires, msg and validUntil being encoded as 32-byte
+// big-endian integers, as abi.encode(msgHash, validUntil) does. validUntil, a
+// block number or a timestamp, must fit in 64 bits.
+func expiringDigest[S emulated.FieldParams](api frontend.API, msg *emulated.Element[S], validUntil frontend.Variable) (*emulated.Element[S], error) {
+	scalarApi, err := emulated.NewField[S](api)
+	if err != nil {
+		return nil, err
+	}
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return nil, err
+	}
+	keccak, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return nil, err
+	}
+	var fr S
+	msgBytes := make([]uints.U8, 0, 32)
+	for i := len(msg.Limbs) - 1; i >= 0; i-- {
+		limbBits := bits.ToBinary(api, msg.Limbs[i], bits.WithNbDigits(int(fr.BitsPerLimb())))
+		for j := len(limbBits)/8 - 1; j >= 0; j-- {
+			msgBytes = append(msgBytes, uapi.ByteValueOf(bits.FromBinary(api, limbBits[8*j:8*j+8])))
+		}
+	}
+	keccak.Write(msgBytes)
+	expiryBytes := uints.NewU8Array(make([]byte, 24))
+	expiryBits := bits.ToBinary(api, validUntil, bits.WithNbDigits(64))
+	for j := 7; j >= 0; j-- {
+		expiryBytes = append(expiryBytes, uapi.ByteValueOf(bits.FromBinary(api, expiryBits[8*j:8*j+8])))
+	}
+	keccak.Write(expiryBytes)
+
+	// the digest, read big-endian, is reduced by the signature verification
+	digest := keccak.Sum()
+	digestBits := make([]frontend.Variable, 0, 256)
+	for i := len(digest) - 1; i >= 0; i-- {
+		digestBits = append(digestBits, bits.ToBinary(api, digest[i].Val, bits.WithNbDigits(8))...)
+	}
+	return scalarApi.FromBits(digestBits...), nil
+}
@@ -0,0 +1,320 @@
+package circuits
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+)
+
+// Ed25519Fp is the emulated base field of Ed25519, 2^255 - 19.
+type Ed25519Fp struct{}
+
+func (Ed25519Fp) NbLimbs() uint     { return 4 }
+func (Ed25519Fp) BitsPerLimb() uint { return 64 }
+func (Ed25519Fp) IsPrime() bool     { return true }
+func (Ed25519Fp) Modulus() *big.Int {
+	p, _ := new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+	return p
+}
+
+// Ed25519Fr is the emulated scalar field of Ed25519, the order L of the base point.
+type Ed25519Fr struct{}
+
+func (Ed25519Fr) NbLimbs() uint     { return 4 }
+func (Ed25519Fr) BitsPerLimb() uint { return 64 }
+func (Ed25519Fr) IsPrime() bool     { return true }
+func (Ed25519Fr) Modulus() *big.Int {
+	l, _ := new(big.Int).SetString("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed", 16)
+	return l
+}
+
+// Ed25519 curve -x^2 + y^2 = 1 + d x^2 y^2 and its base point B
+const (
+	Ed25519D  = "37095705934669439343138083508754565189542113879843219016388785533085940283555"
+	Ed25519Bx = "15112221349535400772501151409588531511454012693041857206046113283949847762202"
+	Ed25519By = "46316835694926478169428394003475163141307993866256225615783033603165251855960"
+)
+
+// Ed25519Point is an affine point of Ed25519.
+type Ed25519Point struct {
+	X emulated.Element[Ed25519Fp]
+	Y emulated.Element[Ed25519Fp]
+}
+
+// Ed25519Signature is an Ed25519 signature, R being the decoded nonce point.
+type Ed25519Signature struct {
+	R Ed25519Point
+	S emulated.Element[Ed25519Fr]
+}
+
+// Ed25519Circuit proves that the Ed25519 key committed in Com signed Msg. The
+// commitment, the nullifier and the public inputs are those of Circuit, the
+// message being the 32-byte message hash.
+type Ed25519Circuit struct {
+	Sig       Ed25519Signature                       `gnark:",secret"` // signature
+	Msg       emulated.Element[emulated.Secp256k1Fr] `gnark:",public"` // message, as the 4 limbs of the public message of Circuit
+	Pub       Ed25519Point                           `gnark:",secret"` // public key
+	Address   frontend.Variable                      `gnark:",secret"` // secret address, keccak256(encoding of the key)[12:]
+	Nonce     frontend.Variable                      `gnark:",secret"` // secret nonce
+	ChainID   frontend.Variable                      `gnark:",public"` // chain id of the account
+	Account   frontend.Variable                      `gnark:",public"` // address of the account using the proof
+	Nullifier frontend.Variable                      `gnark:",public"` // h(nonce, msg, chainId, account)
+	Com       frontend.Variable                      `gnark:",public"` // public commitment, last public input
+
+	CommitHash    string `gnark:"-"` // commitment hash, mimc or poseidon2
+	CommitVersion int    `gnark:"-"` // commitment layout, 0: h(address, nonce), 1: h(tag, chainId, account, address, nonce)
+}
+
+func (c *Ed25519Circuit) Define(api frontend.API) error {
+	ed, err := newEdwards(api)
+	if err != nil {
+		return err
+	}
+	pubBytes := ed.encode(&c.Pub)
+	if err := ed.verify(pubBytes, &c.Pub, &c.Msg, &c.Sig); err != nil {
+		return err
+	}
+
+	// the committed address is the one of the encoded key
+	keccak, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return err
+	}
+	keccak.Write(pubBytes)
+	var address frontend.Variable = 0
+	for _, b := range keccak.Sum()[12:] {
+		address = api.Add(api.Mul(address, 256), b.Val)
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	// h(preImage) == hash, the version 1 layout binding the commitment to
+	// the chain and the account
+	if c.CommitVersion == 1 {
+		h.Write(new(big.Int).SetBytes([]byte(CommitTagV1)), c.ChainID, c.Account)
+	}
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// the nullifier is unique per message, chain and account, and the secret
+	// nonce keeps it unlinkable to the commitment
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
+
+// edwards is the in-circuit arithmetic of Ed25519 over the emulated base field.
+type edwards struct {
+	api      frontend.API
+	fp       *emulated.Field[Ed25519Fp]
+	fr       *emulated.Field[Ed25519Fr]
+	uapi     *uints.BinaryField[uints.U64]
+	d, one   *emulated.Element[Ed25519Fp]
+	base     Ed25519Point
+	identity Ed25519Point
+}
+
+func newEdwards(api frontend.API) (*edwards, error) {
+	fp, err := emulated.NewField[Ed25519Fp](api)
+	if err != nil {
+		return nil, err
+	}
+	fr, err := emulated.NewField[Ed25519Fr](api)
+	if err != nil {
+		return nil, err
+	}
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return nil, err
+	}
+	d, _ := new(big.Int).SetString(Ed25519D, 10)
+	bx, _ := new(big.Int).SetString(Ed25519Bx, 10)
+	by, _ := new(big.Int).SetString(Ed25519By, 10)
+	return &edwards{
+		api:      api,
+		fp:       fp,
+		fr:       fr,
+		uapi:     uapi,
+		d:        fp.NewElement(d),
+		one:      fp.One(),
+		base:     Ed25519Point{X: emulated.ValueOf[Ed25519Fp](bx), Y: emulated.ValueOf[Ed25519Fp](by)},
+		identity: Ed25519Point{X: emulated.ValueOf[Ed25519Fp](0), Y: emulated.ValueOf[Ed25519Fp](1)},
+	}, nil
+}
+
+// assertIsOnCurve checks that -x^2 + y^2 = 1 + d x^2 y^2.
+func (e *edwards) assertIsOnCurve(p *Ed25519Point) {
+	xx := e.fp.Mul(&p.X, &p.X)
+	yy := e.fp.Mul(&p.Y, &p.Y)
+	rhs := e.fp.Add(e.one, e.fp.Mul(e.d, e.fp.Mul(xx, yy)))
+	e.fp.AssertIsEqual(e.fp.Sub(yy, xx), rhs)
+}
+
+// add is the complete addition law of the curve:
+// x3 = (x1y2 + y1x2) / (1 + d x1x2y1y2), y3 = (y1y2 + x1x2) / (1 - d x1x2y1y2).
+func (e *edwards) add(p, q *Ed25519Point) *Ed25519Point {
+	x1y2 := e.fp.Mul(&p.X, &q.Y)
+	y1x2 := e.fp.Mul(&p.Y, &q.X)
+	x1x2 := e.fp.Mul(&p.X, &q.X)
+	y1y2 := e.fp.Mul(&p.Y, &q.Y)
+	t := e.fp.Mul(e.d, e.fp.Mul(x1x2, y1y2))
+	return &Ed25519Point{
+		X: *e.fp.Div(e.fp.Add(x1y2, y1x2), e.fp.Add(e.one, t)),
+		Y: *e.fp.Div(e.fp.Add(y1y2, x1x2), e.fp.Sub(e.one, t)),
+	}
+}
+
+// double uses the curve equation to drop the d term of add:
+// x3 = 2xy / (y^2 - x^2), y3 = (y^2 + x^2) / (2 - y^2 + x^2).
+func (e *edwards) double(p *Ed25519Point) *Ed25519Point {
+	xx := e.fp.Mul(&p.X, &p.X)
+	yy := e.fp.Mul(&p.Y, &p.Y)
+	xy := e.fp.Mul(&p.X, &p.Y)
+	yyMinusXx := e.fp.Sub(yy, xx)
+	return &Ed25519Point{
+		X: *e.fp.Div(e.fp.Add(xy, xy), yyMinusXx),
+		Y: *e.fp.Div(e.fp.Add(yy, xx), e.fp.Sub(e.fp.Add(e.one, e.one), yyMinusXx)),
+	}
+}
+
+// encode returns the 32-byte encoding of the point: y little-endian, the top
+// bit holding the parity of x.
+func (e *edwards) encode(p *Ed25519Point) []uints.U8 {
+	yBits := e.fp.ToBitsCanonical(&p.Y)
+	encBits := append(yBits[:255:255], e.fp.ToBitsCanonical(&p.X)[0])
+	enc := make([]uints.U8, 32)
+	for i := range enc {
+		enc[i] = e.uapi.ByteValueOf(bits.FromBinary(e.api, encBits[8*i:8*i+8]))
+	}
+	return enc
+}
+
+// verify checks the Ed25519 signature (R, S) of the 32-byte message m by the
+// key A of encoding pubBytes: with k = sha512(R || A || m) mod L, [S]B = R + [k]A.
+func (e *edwards) verify(pubBytes []uints.U8, pub *Ed25519Point, msg *emulated.Element[emulated.Secp256k1Fr], sig *Ed25519Signature) error {
+	e.assertIsOnCurve(pub)
+	e.fr.AssertIsInRange(&sig.S)
+
+	// the message hash as 32 big-endian bytes
+	msgBytes := make([]uints.U8, 0, 32)
+	for i := len(msg.Limbs) - 1; i >= 0; i-- {
+		limbBits := bits.ToBinary(e.api, msg.Limbs[i], bits.WithNbDigits(64))
+		for j := 7; j >= 0; j-- {
+			msgBytes = append(msgBytes, e.uapi.ByteValueOf(bits.FromBinary(e.api, limbBits[8*j:8*j+8])))
+		}
+	}
+	data := append(e.encode(&sig.R), pubBytes...)
+	digest := sha512Sum(e.uapi, append(data, msgBytes...))
+
+	// the 512-bit digest is read little-endian, k = lo + 2^256 hi mod L
+	kBits := make([]frontend.Variable, 0, 512)
+	for _, b := range digest {
+		kBits = append(kBits, bits.ToBinary(e.api, b.Val, bits.WithNbDigits(8))...)
+	}
+	lo := e.fr.FromBits(kBits[:256]...)
+	hi := e.fr.FromBits(kBits[256:]...)
+	k := e.fr.Add(lo, e.fr.Mul(hi, e.fr.NewElement(new(big.Int).Lsh(big.NewInt(1), 256))))
+
+	// [S]B - [k]A by a joint double-and-add, the addition law being complete
+	sBits := e.fr.ToBitsCanonical(&sig.S)
+	kBits = e.fr.ToBitsCanonical(k)
+	negPub := Ed25519Point{X: *e.fp.Neg(&pub.X), Y: pub.Y}
+	table := [4]*Ed25519Point{&e.identity, &e.base, &negPub, e.add(&e.base, &negPub)}
+	acc := &e.identity
+	for i := len(sBits) - 1; i >= 0; i-- {
+		acc = e.double(acc)
+		acc = e.add(acc, &Ed25519Point{
+			X: *e.fp.Lookup2(sBits[i], kBits[i], &table[0].X, &table[1].X, &table[2].X, &table[3].X),
+			Y: *e.fp.Lookup2(sBits[i], kBits[i], &table[0].Y, &table[1].Y, &table[2].Y, &table[3].Y),
+		})
+	}
+	e.fp.AssertIsEqual(&acc.X, &sig.R.X)
+	e.fp.AssertIsEqual(&acc.Y, &sig.R.Y)
+	return nil
+}
+
+// sha512K are the round constants of SHA-512.
+var sha512K = []uint64{
+	0x428a2f98d728ae22, 0x7137449123ef65cd, 0xb5c0fbcfec4d3b2f, 0xe9b5dba58189dbbc, 0x3956c25bf348b538, 0x59f111f1b605d019, 0x923f82a4af194f9b, 0xab1c5ed5da6d8118,
+	0xd807aa98a3030242, 0x12835b0145706fbe, 0x243185be4ee4b28c, 0x550c7dc3d5ffb4e2, 0x72be5d74f27b896f, 0x80deb1fe3b1696b1, 0x9bdc06a725c71235, 0xc19bf174cf692694,
+	0xe49b69c19ef14ad2, 0xefbe4786384f25e3, 0x0fc19dc68b8cd5b5, 0x240ca1cc77ac9c65, 0x2de92c6f592b0275, 0x4a7484aa6ea6e483, 0x5cb0a9dcbd41fbd4, 0x76f988da831153b5,
+	0x983e5152ee66dfab, 0xa831c66d2db43210, 0xb00327c898fb213f, 0xbf597fc7beef0ee4, 0xc6e00bf33da88fc2, 0xd5a79147930aa725, 0x06ca6351e003826f, 0x142929670a0e6e70,
+	0x27b70a8546d22ffc, 0x2e1b21385c26c926, 0x4d2c6dfc5ac42aed, 0x53380d139d95b3df, 0x650a73548baf63de, 0x766a0abb3c77b2a8, 0x81c2c92e47edaee6, 0x92722c851482353b,
+	0xa2bfe8a14cf10364, 0xa81a664bbc423001, 0xc24b8b70d0f89791, 0xc76c51a30654be30, 0xd192e819d6ef5218, 0xd69906245565a910, 0xf40e35855771202a, 0x106aa07032bbd1b8,
+	0x19a4c116b8d2d0c8, 0x1e376c085141ab53, 0x2748774cdf8eeb99, 0x34b0bcb5e19b48a8, 0x391c0cb3c5c95a63, 0x4ed8aa4ae3418acb, 0x5b9cca4f7763e373, 0x682e6ff3d6b2b8a3,
+	0x748f82ee5defb2fc, 0x78a5636f43172f60, 0x84c87814a1f0ab72, 0x8cc702081a6439ec, 0x90befffa23631e28, 0xa4506cebde82bde9, 0xbef9a3f7b2c67915, 0xc67178f2e372532b,
+	0xca273eceea26619c, 0xd186b8c721c0c207, 0xeada7dd6cde0eb1e, 0xf57d4f7fee6ed178, 0x06f067aa72176fba, 0x0a637dc5a2c898a6, 0x113f9804bef90dae, 0x1b710b35131c471b,
+	0x28db77f523047d84, 0x32caab7b40c72493, 0x3c9ebe0a15c9bebc, 0x431d67c49c100d4c, 0x4cc5d4becb3e42b6, 0x597f299cfc657e2a, 0x5fcb6fab3ad6faec, 0x6c44198c4a475817,
+}
+
+// sha512IV is the initial hash value of SHA-512.
+var sha512IV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+// sha512Sum computes in-circuit the SHA-512 digest of data, of length fixed at
+// compile time.
+func sha512Sum(uapi *uints.BinaryField[uints.U64], data []uints.U8) []uints.U8 {
+	// padding: 0x80, zeros, and the 128-bit big-endian bit length
+	padded := append([]uints.U8{}, data...)
+	padded = append(padded, uints.NewU8(0x80))
+	for len(padded)%128 != 112 {
+		padded = append(padded, uints.NewU8(0))
+	}
+	var length [16]byte
+	new(big.Int).SetUint64(uint64(len(data)) * 8).FillBytes(length[:])
+	padded = append(padded, uints.NewU8Array(length[:])...)
+
+	var state [8]uints.U64
+	for i := range state {
+		state[i] = uints.NewU64(sha512IV[i])
+	}
+	for block := 0; block < len(padded); block += 128 {
+		var w [80]uints.U64
+		for i := 0; i < 16; i++ {
+			w[i] = uapi.PackMSB(padded[block+8*i : block+8*i+8]...)
+		}
+		for i := 16; i < 80; i++ {
+			s0 := uapi.Xor(uapi.Lrot(w[i-15], -1), uapi.Lrot(w[i-15], -8), uapi.Rshift(w[i-15], 7))
+			s1 := uapi.Xor(uapi.Lrot(w[i-2], -19), uapi.Lrot(w[i-2], -61), uapi.Rshift(w[i-2], 6))
+			w[i] = uapi.Add(w[i-16], s0, w[i-7], s1)
+		}
+
+		a, b, c, d, e, f, g, h := state[0], state[1], state[2], state[3], state[4], state[5], state[6], state[7]
+		for i := 0; i < 80; i++ {
+			t1 := uapi.Add(
+				h,
+				uapi.Xor(uapi.Lrot(e, -14), uapi.Lrot(e, -18), uapi.Lrot(e, -41)),
+				uapi.Xor(uapi.And(e, f), uapi.And(uapi.Not(e), g)),
+				uints.NewU64(sha512K[i]),
+				w[i],
+			)
+			t2 := uapi.Add(
+				uapi.Xor(uapi.Lrot(a, -28), uapi.Lrot(a, -34), uapi.Lrot(a, -39)),
+				uapi.Xor(uapi.And(a, b), uapi.And(a, c), uapi.And(b, c)),
+			)
+			h, g, f, e, d, c, b, a = g, f, e, uapi.Add(d, t1), c, b, a, uapi.Add(t1, t2)
+		}
+		for i, v := range []uints.U64{a, b, c, d, e, f, g, h} {
+			state[i] = uapi.Add(state[i], v)
+		}
+	}
+
+	digest := make([]uints.U8, 0, 64)
+	for _, v := range state {
+		digest = append(digest, uapi.UnpackMSB(v)...)
+	}
+	return digest
+}
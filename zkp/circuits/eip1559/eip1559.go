@@ -0,0 +1,232 @@
+// Package eip1559 decodes in-circuit the unsigned EIP-1559 transaction signed
+// by a key, for the transaction circuit and the spending policy circuit.
+package eip1559
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/lookup/logderivlookup"
+	"github.com/consensys/gnark/std/math/uints"
+)
+
+// MaxTxLen is the maximum length of the unsigned transaction 0x02 || rlp(fields).
+const MaxTxLen = 512
+
+// MaxUintLen is the maximum length of the integer fields of the transaction,
+// so that they fit in a native field element.
+const MaxUintLen = 31
+
+// Transaction holds the fields of a decoded transaction.
+type Transaction struct {
+	ChainID  frontend.Variable // chain id of the transaction
+	To       frontend.Variable // destination of the transaction
+	Value    frontend.Variable // value of the transaction, in wei
+	Selector frontend.Variable // first 4 bytes of the calldata, 0 without calldata
+	Hash     []uints.U8        // keccak256 of the unsigned transaction, the signed digest
+
+	p                  *rlpParser        // reader of the transaction bytes
+	dataStart, dataLen frontend.Variable // offset and length of the calldata
+}
+
+// TransferSelector and ApproveSelector are the selectors of the ERC-20
+// transfer(address,uint256) and approve(address,uint256), whose amount is
+// capped by AssertPolicy.
+const (
+	TransferSelector = 0xa9059cbb
+	ApproveSelector  = 0x095ea7b3
+)
+
+// Decode constrains the txLen first bytes of tx, MaxTxLen bytes zero padded,
+// to be an unsigned type 2 transaction 0x02 || rlp([chainId, nonce,
+// maxPriorityFeePerGas, maxFeePerGas, gasLimit, to, value, data, accessList]),
+// and returns its fields and its hash. Contract creations and access lists are
+// not supported, and the calldata is either empty or starts with a selector.
+func Decode(api frontend.API, tx []uints.U8, txLen frontend.Variable) (*Transaction, error) {
+	if len(tx) != MaxTxLen {
+		return nil, fmt.Errorf("the transaction is %d bytes long, %d expected", len(tx), MaxTxLen)
+	}
+	// the transaction bytes are witnesses, constrain them to be bytes
+	uapi, err := uints.New[uints.U32](api)
+	if err != nil {
+		return nil, err
+	}
+	txBytes := make([]uints.U8, len(tx))
+	for i := range txBytes {
+		txBytes[i] = uapi.ByteValueOf(tx[i].Val)
+	}
+	api.AssertIsLessOrEqual(txLen, MaxTxLen)
+
+	// the fields are read at variable offsets, the table is padded so that
+	// reading past the end of a field does not overflow
+	table := logderivlookup.New(api)
+	for i := range txBytes {
+		table.Insert(txBytes[i].Val)
+	}
+	for i := 0; i < MaxUintLen+4; i++ {
+		table.Insert(0)
+	}
+	p := rlpParser{api: api, table: table}
+
+	var t Transaction
+	api.AssertIsEqual(txBytes[0].Val, 2)
+	listLen, pos := p.listHeader(1)
+	listStart := pos
+
+	t.ChainID, pos = p.uint(pos)
+	_, pos = p.uint(pos) // nonce
+	_, pos = p.uint(pos) // maxPriorityFeePerGas
+	_, pos = p.uint(pos) // maxFeePerGas
+	_, pos = p.uint(pos) // gasLimit
+
+	// contract creations are not supported, the destination is 20 bytes long
+	api.AssertIsEqual(p.byteAt(pos), 0x80+20)
+	t.To = 0
+	for i := 1; i <= 20; i++ {
+		t.To = api.Add(api.Mul(t.To, 256), p.byteAt(api.Add(pos, i)))
+	}
+	pos = api.Add(pos, 21)
+
+	t.Value, pos = p.uint(pos)
+
+	dataStart, dataLen, _ := p.bytesHeader(pos)
+	// the calldata is either empty or starts with a selector
+	noData := api.IsZero(dataLen)
+	api.AssertIsLessOrEqual(api.Sub(api.Select(noData, 4, dataLen), 4), MaxTxLen)
+	var selector frontend.Variable = 0
+	for i := 0; i < 4; i++ {
+		selector = api.Add(api.Mul(selector, 256), p.byteAt(api.Add(dataStart, i)))
+	}
+	t.Selector = api.Select(noData, 0, selector)
+	t.p, t.dataStart, t.dataLen = &p, dataStart, dataLen
+	pos = api.Add(dataStart, dataLen)
+
+	// access lists are not supported
+	api.AssertIsEqual(p.byteAt(pos), 0xc0)
+	pos = api.Add(pos, 1)
+
+	api.AssertIsEqual(pos, api.Add(listStart, listLen))
+	api.AssertIsEqual(pos, txLen)
+
+	// the signed message is keccak256 of the unsigned transaction
+	keccak, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return nil, err
+	}
+	keccak.Write(txBytes)
+	t.Hash = keccak.FixedLengthSum(txLen)
+	return &t, nil
+}
+
+// AssertPolicy checks that the transaction sends at most maxValue wei to the
+// destination to, calling the function selector, 0 for a transfer without
+// calldata. The only functions allowed are the ERC-20 transfer and approve,
+// whose amount, the second argument, is also at most maxValue, in units of the
+// token to. Their recipient is not checked.
+func (t *Transaction) AssertPolicy(api frontend.API, maxValue, to, selector frontend.Variable) {
+	api.AssertIsLessOrEqual(t.Value, maxValue)
+	api.AssertIsEqual(t.To, to)
+	api.AssertIsEqual(t.Selector, selector)
+
+	// the arguments of other functions would not be checked
+	isTransfer := api.IsZero(api.Sub(t.Selector, TransferSelector))
+	isApprove := api.IsZero(api.Sub(t.Selector, ApproveSelector))
+	isToken := api.Add(isTransfer, isApprove)
+	api.AssertIsEqual(api.Mul(t.Selector, api.Sub(1, isToken)), 0)
+
+	// the calldata is selector || address || amount, the first byte is read
+	// in place of the amount of other calls
+	api.AssertIsLessOrEqual(api.Mul(isToken, 4+32+32), t.dataLen)
+	amountAt := func(i int) frontend.Variable {
+		return t.p.byteAt(api.Mul(isToken, api.Add(t.dataStart, 4+32+i)))
+	}
+	// the amount is below 2^248, so that it fits in a native field element
+	api.AssertIsEqual(api.Mul(isToken, amountAt(0)), 0)
+	var amount frontend.Variable = 0
+	for i := 1; i < 32; i++ {
+		amount = api.Add(api.Mul(amount, 256), amountAt(i))
+	}
+	api.AssertIsLessOrEqual(api.Mul(isToken, amount), maxValue)
+}
+
+// rlpParser reads the RLP items of the transaction at variable offsets.
+type rlpParser struct {
+	api   frontend.API
+	table logderivlookup.Table
+}
+
+// byteAt returns the byte of the transaction at position pos.
+func (p *rlpParser) byteAt(pos frontend.Variable) frontend.Variable {
+	return p.table.Lookup(pos)[0]
+}
+
+// listHeader decodes the list header at pos and returns the length of the
+// list payload and its offset.
+func (p *rlpParser) listHeader(pos frontend.Variable) (length, next frontend.Variable) {
+	api := p.api
+	b := p.byteAt(pos)
+	isLong1 := api.IsZero(api.Sub(b, 0xf8))
+	isLong2 := api.IsZero(api.Sub(b, 0xf9))
+	isShort := api.Sub(1, isLong1, isLong2)
+	// a short list header is in [0xc0, 0xf7]
+	api.AssertIsLessOrEqual(api.Select(isShort, api.Sub(b, 0xc0), 0), 55)
+
+	b1 := p.byteAt(api.Add(pos, 1))
+	b2 := p.byteAt(api.Add(pos, 2))
+	length = api.Add(
+		api.Mul(isShort, api.Sub(b, 0xc0)),
+		api.Mul(isLong1, b1),
+		api.Mul(isLong2, api.Add(api.Mul(b1, 256), b2)),
+	)
+	next = api.Add(pos, 1, isLong1, api.Mul(isLong2, 2))
+	return length, next
+}
+
+// bytesHeader decodes the header of the byte string at pos, of at most
+// 65535 bytes, and returns the offset and the length of its payload and the
+// offset of the next item.
+func (p *rlpParser) bytesHeader(pos frontend.Variable) (start, length, next frontend.Variable) {
+	api := p.api
+	b := p.byteAt(pos)
+	isLong1 := api.IsZero(api.Sub(b, 0xb8))
+	isLong2 := api.IsZero(api.Sub(b, 0xb9))
+	isShort := api.Sub(1, isLong1, isLong2)
+	// a short string header is in [0x80, 0xb7], single bytes are not
+	// accepted as the calldata is either empty or at least 4 bytes long
+	api.AssertIsLessOrEqual(api.Select(isShort, api.Sub(b, 0x80), 0), 55)
+
+	b1 := p.byteAt(api.Add(pos, 1))
+	b2 := p.byteAt(api.Add(pos, 2))
+	length = api.Add(
+		api.Mul(isShort, api.Sub(b, 0x80)),
+		api.Mul(isLong1, b1),
+		api.Mul(isLong2, api.Add(api.Mul(b1, 256), b2)),
+	)
+	api.AssertIsLessOrEqual(length, MaxTxLen)
+	start = api.Add(pos, 1, isLong1, api.Mul(isLong2, 2))
+	next = api.Add(start, length)
+	return start, length, next
+}
+
+// uint decodes the integer at pos, of at most MaxUintLen bytes, and returns
+// its value and the offset of the next item.
+func (p *rlpParser) uint(pos frontend.Variable) (value, next frontend.Variable) {
+	api := p.api
+	b := p.byteAt(pos)
+	// a byte below 0x80 is its own encoding
+	isSingle := api.Sub(1, api.ToBinary(b, 8)[7])
+	length := api.Select(isSingle, 0, api.Sub(b, 0x80))
+	api.AssertIsLessOrEqual(length, MaxUintLen)
+
+	value = 0
+	var done frontend.Variable = 0
+	for i := 0; i < MaxUintLen; i++ {
+		done = api.Or(done, api.IsZero(api.Sub(length, i)))
+		shifted := api.Add(api.Mul(value, 256), p.byteAt(api.Add(pos, 1+i)))
+		value = api.Select(done, value, shifted)
+	}
+	value = api.Select(isSingle, b, value)
+	next = api.Add(pos, 1, length)
+	return value, next
+}
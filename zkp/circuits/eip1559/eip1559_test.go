@@ -0,0 +1,188 @@
+package eip1559
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// transactionCircuit exposes the decoded fields and hash of the transaction
+type transactionCircuit struct {
+	Tx       [MaxTxLen]uints.U8
+	TxLen    frontend.Variable
+	ChainID  frontend.Variable `gnark:",public"`
+	To       frontend.Variable `gnark:",public"`
+	Value    frontend.Variable `gnark:",public"`
+	Selector frontend.Variable `gnark:",public"`
+	Hash     [32]uints.U8      `gnark:",public"`
+}
+
+func (c *transactionCircuit) Define(api frontend.API) error {
+	tx, err := Decode(api, c.Tx[:], c.TxLen)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.ChainID, tx.ChainID)
+	api.AssertIsEqual(c.To, tx.To)
+	api.AssertIsEqual(c.Value, tx.Value)
+	api.AssertIsEqual(c.Selector, tx.Selector)
+	for i := range c.Hash {
+		api.AssertIsEqual(c.Hash[i].Val, tx.Hash[i].Val)
+	}
+	return nil
+}
+
+// policyCircuit checks the policy of the spending policy circuit
+type policyCircuit struct {
+	Tx       [MaxTxLen]uints.U8
+	TxLen    frontend.Variable
+	Cap      frontend.Variable `gnark:",public"`
+	To       frontend.Variable `gnark:",public"`
+	Selector frontend.Variable `gnark:",public"`
+}
+
+func (c *policyCircuit) Define(api frontend.API) error {
+	tx, err := Decode(api, c.Tx[:], c.TxLen)
+	if err != nil {
+		return err
+	}
+	tx.AssertPolicy(api, c.Cap, c.To, c.Selector)
+	return nil
+}
+
+var (
+	recipient        = big.NewInt(0x1111)
+	token            = big.NewInt(0x2222)
+	transferSelector = big.NewInt(TransferSelector)
+	approveSelector  = big.NewInt(ApproveSelector)
+	// transferFrom(address,address,uint256)
+	transferFromSelector = big.NewInt(0x23b872dd)
+)
+
+// unsignedTx returns 0x02 || rlp(fields) of a transaction on chain 1.
+func unsignedTx(t *testing.T, to, value *big.Int, data []byte) []byte {
+	t.Helper()
+	payload, err := rlp.EncodeToBytes([]interface{}{
+		big.NewInt(1), uint64(7), big.NewInt(1_000_000_000), big.NewInt(30_000_000_000), uint64(60000),
+		to.FillBytes(make([]byte, 20)), value, data, []interface{}{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte{2}, payload...)
+}
+
+func txBytes(tx []byte) [MaxTxLen]uints.U8 {
+	var padded [MaxTxLen]uints.U8
+	for i := range padded {
+		padded[i] = uints.NewU8(0)
+		if i < len(tx) {
+			padded[i] = uints.NewU8(tx[i])
+		}
+	}
+	return padded
+}
+
+// tokenCalldata returns the calldata of a call to selector(recipient, amount).
+func tokenCalldata(selector, amount *big.Int) []byte {
+	data := selector.FillBytes(make([]byte, 4))
+	data = append(data, recipient.FillBytes(make([]byte, 32))...)
+	return append(data, amount.FillBytes(make([]byte, 32))...)
+}
+
+func transferCalldata() []byte {
+	return tokenCalldata(transferSelector, big.NewInt(1000))
+}
+
+func TestDecode(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		to       *big.Int
+		data     []byte
+		selector *big.Int
+	}{
+		{"transfer", recipient, nil, big.NewInt(0)},
+		{"call", token, transferCalldata(), transferSelector},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			value := big.NewInt(1_000_000_000_000_000)
+			tx := unsignedTx(t, tc.to, value, tc.data)
+			var hash [32]uints.U8
+			for i, b := range crypto.Keccak256(tx) {
+				hash[i] = uints.NewU8(b)
+			}
+			assignment := &transactionCircuit{
+				Tx:       txBytes(tx),
+				TxLen:    len(tx),
+				ChainID:  1,
+				To:       tc.to,
+				Value:    value,
+				Selector: tc.selector,
+				Hash:     hash,
+			}
+			if err := test.IsSolved(&transactionCircuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+				t.Fatal(err)
+			}
+			assignment.To = token
+			if tc.to == token {
+				assignment.To = recipient
+			}
+			if test.IsSolved(&transactionCircuit{}, assignment, ecc.BN254.ScalarField()) == nil {
+				t.Fatal("the destination is not the one of the transaction")
+			}
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	limit := big.NewInt(1_000_000_000_000_000_000)
+	overLimit := new(big.Int).Add(limit, big.NewInt(1))
+	for _, tc := range []struct {
+		name     string
+		to       *big.Int
+		value    *big.Int
+		data     []byte
+		policyTo *big.Int
+		selector *big.Int
+		allowed  bool
+	}{
+		{"transfer under the cap", recipient, big.NewInt(1000), nil, recipient, big.NewInt(0), true},
+		{"transfer of the cap", recipient, limit, nil, recipient, big.NewInt(0), true},
+		{"token call under the cap", token, big.NewInt(0), transferCalldata(), token, transferSelector, true},
+		{"transfer over the cap", recipient, overLimit, nil, recipient, big.NewInt(0), false},
+		{"transfer to another destination", token, big.NewInt(1000), nil, recipient, big.NewInt(0), false},
+		{"calldata on a transfer policy", recipient, big.NewInt(1000), transferCalldata(), recipient, big.NewInt(0), false},
+		{"other selector", token, big.NewInt(0), transferCalldata(), token, approveSelector, false},
+		{"approve under the cap", token, big.NewInt(0), tokenCalldata(approveSelector, limit), token, approveSelector, true},
+		{"token transfer over the cap", token, big.NewInt(0), tokenCalldata(transferSelector, overLimit), token, transferSelector, false},
+		{"approve over the cap", token, big.NewInt(0), tokenCalldata(approveSelector, overLimit), token, approveSelector, false},
+		{"token amount above 2^248", token, big.NewInt(0), tokenCalldata(transferSelector, new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 248), big.NewInt(1000))), token, transferSelector, false},
+		// the bytes after the calldata would read as an amount under the cap
+		{"truncated token transfer", token, big.NewInt(0), tokenCalldata(transferSelector, big.NewInt(0))[:4+32+25], token, transferSelector, false},
+		{"other function", token, big.NewInt(0), tokenCalldata(transferFromSelector, big.NewInt(1000)), token, transferFromSelector, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := unsignedTx(t, tc.to, tc.value, tc.data)
+			assignment := &policyCircuit{
+				Tx:       txBytes(tx),
+				TxLen:    len(tx),
+				Cap:      limit,
+				To:       tc.policyTo,
+				Selector: tc.selector,
+			}
+			err := test.IsSolved(&policyCircuit{}, assignment, ecc.BN254.ScalarField())
+			if tc.allowed && err != nil {
+				t.Fatal(err)
+			}
+			if !tc.allowed && err == nil {
+				t.Fatal("the policy accepts the transaction")
+			}
+		})
+	}
+}
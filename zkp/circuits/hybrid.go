@@ -0,0 +1,369 @@
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+	mathbits "math/bits"
+
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/lookup/logderivlookup"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/permutation/keccakf"
+	"github.com/consensys/gnark/std/rangecheck"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// Falcon-512 parameters
+const (
+	FalconN     = 512
+	FalconQ     = 12289
+	FalconBound = 34034726 // bound on the squared norm of (s1, s2)
+	FalconSalt  = 40       // length of the salt, in bytes
+	FalconPsi   = 10302    // primitive 1024-th root of unity modulo q, 11^12
+	// FalconSamples is the number of 16-bit samples squeezed by the hash to
+	// point, 9 blocks of SHAKE256. A sample is kept when below 5q, and 612
+	// samples hold 512 kept ones except with probability about 2^-60.
+	FalconSamples = 612
+	// FalconPacking is the number of 14-bit coefficients of the Falcon public
+	// key packed in a field element of the commitment.
+	FalconPacking = 18
+	shake256Rate  = 136
+)
+
+// falconZetas[k] is psi^brv(k), brv reversing the 9 bits of k, the twiddle
+// factor of the k-th butterfly group of the NTT. falconZetasInv are their
+// inverses modulo q.
+var falconZetas, falconZetasInv = falconTwiddles()
+
+func init() {
+	solver.RegisterHint(falconDivHint, falconGreaterHint, falconIndexHint)
+}
+
+// HybridCircuit proves that the key committed in Com signed Msg with ECDSA, and
+// that the Falcon-512 public key committed with it signed the same digest, as
+// the two halves of a hybrid signature.
+type HybridCircuit[T, S emulated.FieldParams] struct {
+	Sig       ecdsa.Signature[S]            `gnark:",secret"` // signature
+	Msg       emulated.Element[S]           `gnark:",public"` // message
+	Pub       ecdsa.PublicKey[T, S]         `gnark:",secret"` // now secret
+	Address   frontend.Variable             `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce     frontend.Variable             `gnark:",secret"` // secret nonce
+	FalconPub [FalconN]frontend.Variable    `gnark:",secret"` // Falcon public key h, coefficients modulo q
+	Salt      [FalconSalt]frontend.Variable `gnark:",secret"` // bytes of the salt of the Falcon signature
+	S2        [FalconN]frontend.Variable    `gnark:",secret"` // Falcon signature s2, coefficients modulo q
+	ChainID   frontend.Variable             `gnark:",public"` // chain id of the account
+	Account   frontend.Variable             `gnark:",public"` // address of the account using the proof
+	Nullifier frontend.Variable             `gnark:",public"` // h(nonce, msg, chainId, account)
+	Com       frontend.Variable             `gnark:",public"` // h(address, nonce, h(falcon key)), last public input
+
+	CommitHash string `gnark:"-"` // commitment hash, mimc or poseidon2
+	LowS       bool   `gnark:"-"` // enforce the canonical low-s signature
+}
+
+func (c *HybridCircuit[T, S]) Define(api frontend.API) error {
+	curveParams := sw_emulated.GetCurveParams[T]()
+	c.Pub.Verify(api, curveParams, &c.Msg, &c.Sig)
+	if c.LowS {
+		if err := AssertCanonicalSignature(api, &c.Sig); err != nil {
+			return err
+		}
+	}
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	// the Falcon half signs the same 32-byte digest
+	falcon, err := newFalconVerifier(api)
+	if err != nil {
+		return err
+	}
+	msg := make([]uints.U8, 0, 32)
+	for i := len(c.Msg.Limbs) - 1; i >= 0; i-- {
+		msg = append(msg, falcon.uapi.UnpackMSB(falcon.uapi.ValueOf(c.Msg.Limbs[i]))...)
+	}
+	salt := make([]uints.U8, FalconSalt)
+	for i := range salt {
+		salt[i] = falcon.uapi.ByteValueOf(c.Salt[i])
+	}
+	if err = falcon.Verify(c.FalconPub[:], c.S2[:], salt, msg); err != nil {
+		return err
+	}
+
+	// h(address, nonce, h(falcon key))
+	kh, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	kh.Write(falcon.packKey(c.FalconPub[:])...)
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	h.Write(kh.Sum())
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// the nullifier is unique per message, chain and account, and the secret
+	// nonce keeps it unlinkable to the commitment
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
+
+// falconVerifier verifies Falcon-512 signatures in-circuit. q fits in the
+// native field, so the arithmetic modulo q is done on native variables, each
+// reduction being a hinted division with range checked quotient and remainder.
+type falconVerifier struct {
+	api  frontend.API
+	uapi *uints.BinaryField[uints.U64]
+	rc   frontend.Rangechecker
+}
+
+func newFalconVerifier(api frontend.API) (*falconVerifier, error) {
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return nil, err
+	}
+	return &falconVerifier{api: api, uapi: uapi, rc: rangecheck.New(api)}, nil
+}
+
+// Verify checks the signature (salt, s2) of msg for the public key h: with
+// c = HashToPoint(salt || msg) and s1 = c - s2 * h mod (q, x^512 + 1), the
+// squared norm of (s1, s2) must be at most FalconBound.
+func (f *falconVerifier) Verify(h, s2 []frontend.Variable, salt, msg []uints.U8) error {
+	for i := range h {
+		f.assertReduced(h[i])
+		f.assertReduced(s2[i])
+	}
+	c, err := f.hashToPoint(salt, msg)
+	if err != nil {
+		return err
+	}
+
+	// s2 * h in the NTT domain
+	nttS2 := f.ntt(s2)
+	nttH := f.ntt(h)
+	for i := range nttS2 {
+		nttS2[i] = f.reduce(f.api.Mul(nttS2[i], nttH[i]), 14)
+	}
+	s2h := f.intt(nttS2)
+
+	var norm frontend.Variable = 0
+	for i := range c {
+		s1 := f.reduce(f.api.Add(f.api.Sub(c[i], s2h[i]), FalconQ), 1)
+		norm = f.api.Add(norm, f.centeredSquare(s1), f.centeredSquare(s2[i]))
+	}
+	// a norm above the bound wraps around the field and fails the 26-bit check
+	f.rc.Check(f.api.Sub(FalconBound, norm), 26)
+	return nil
+}
+
+// hashToPoint computes the NIST HashToPoint of Falcon: SHAKE256(salt || msg)
+// is read as 16-bit big-endian samples, the samples below 5q are kept and
+// reduced modulo q until 512 coefficients are found.
+func (f *falconVerifier) hashToPoint(salt, msg []uints.U8) ([]frontend.Variable, error) {
+	api := f.api
+	// salt || msg fits in a single block, padded with the SHAKE domain byte
+	block := make([]uints.U8, shake256Rate)
+	n := copy(block, append(append([]uints.U8{}, salt...), msg...))
+	for i := n; i < shake256Rate; i++ {
+		block[i] = uints.NewU8(0)
+	}
+	block[n] = uints.NewU8(0x1f)
+	block[shake256Rate-1] = uints.NewU8(0x80)
+	var state [25]uints.U64
+	for i := range state {
+		state[i] = uints.NewU64(0)
+		if i < shake256Rate/8 {
+			state[i] = f.uapi.PackLSB(block[8*i : 8*i+8]...)
+		}
+	}
+	var stream []uints.U8
+	for len(stream) < 2*FalconSamples {
+		state = keccakf.Permute(f.uapi, state)
+		for i := 0; i < shake256Rate/8; i++ {
+			stream = append(stream, f.uapi.UnpackLSB(state[i])...)
+		}
+	}
+
+	// the table holds (2 kept + accepted) << 14 | sample mod q, kept being the
+	// number of samples accepted before, so the i-th accepted sample is the
+	// only entry whose high part is 2i + 1
+	table := logderivlookup.New(api)
+	samples := make([]frontend.Variable, FalconSamples)
+	var kept frontend.Variable = 0
+	for j := range samples {
+		t := api.Add(api.Mul(stream[2*j].Val, 256), stream[2*j+1].Val)
+		samples[j] = t
+		res, err := api.Compiler().NewHint(falconGreaterHint, 1, t, 5*FalconQ-1)
+		if err != nil {
+			return nil, err
+		}
+		rejected := res[0]
+		api.AssertIsBoolean(rejected)
+		f.rc.Check(api.Select(rejected, api.Sub(t, 5*FalconQ), api.Sub(5*FalconQ-1, t)), 16)
+		accepted := api.Sub(1, rejected)
+		table.Insert(api.Add(api.Mul(api.Add(api.Mul(kept, 2), accepted), 1<<14), f.reduce(t, 3)))
+		kept = api.Add(kept, accepted)
+	}
+	indices, err := api.Compiler().NewHint(falconIndexHint, FalconN, samples...)
+	if err != nil {
+		return nil, err
+	}
+	entries := table.Lookup(indices...)
+	c := make([]frontend.Variable, FalconN)
+	for i := range c {
+		c[i] = api.Sub(entries[i], (2*i+1)<<14)
+		f.rc.Check(c[i], 14)
+	}
+	return c, nil
+}
+
+// ntt returns the negacyclic NTT of a, in bit-reversed order.
+func (f *falconVerifier) ntt(a []frontend.Variable) []frontend.Variable {
+	a = append([]frontend.Variable{}, a...)
+	for length := FalconN / 2; length >= 1; length >>= 1 {
+		for start := 0; start < FalconN; start += 2 * length {
+			zeta := falconZetas[FalconN/(2*length)+start/(2*length)]
+			for j := start; j < start+length; j++ {
+				t := f.reduce(f.api.Mul(a[j+length], zeta), 14)
+				a[j+length] = f.reduce(f.api.Add(f.api.Sub(a[j], t), FalconQ), 1)
+				a[j] = f.reduce(f.api.Add(a[j], t), 1)
+			}
+		}
+	}
+	return a
+}
+
+// intt inverts ntt, the division by n being folded in the last layer.
+func (f *falconVerifier) intt(a []frontend.Variable) []frontend.Variable {
+	a = append([]frontend.Variable{}, a...)
+	nInv := falconPow(FalconN, FalconQ-2)
+	for length := 1; length < FalconN; length <<= 1 {
+		scale, quoBits := uint64(1), 1
+		if length == FalconN/2 {
+			scale, quoBits = nInv, 15
+		}
+		for start := 0; start < FalconN; start += 2 * length {
+			zetaInv := falconZetasInv[FalconN/(2*length)+start/(2*length)] * scale % FalconQ
+			for j := start; j < start+length; j++ {
+				t, u := a[j], a[j+length]
+				a[j] = f.reduce(f.api.Mul(f.api.Add(t, u), scale), quoBits)
+				a[j+length] = f.reduce(f.api.Mul(f.api.Add(f.api.Sub(t, u), FalconQ), zetaInv), 15)
+			}
+		}
+	}
+	return a
+}
+
+// centeredSquare returns the square of the representative of x in
+// [-(q-1)/2, (q-1)/2].
+func (f *falconVerifier) centeredSquare(x frontend.Variable) frontend.Variable {
+	api := f.api
+	res, err := api.Compiler().NewHint(falconGreaterHint, 1, x, FalconQ/2)
+	if err != nil {
+		panic(err)
+	}
+	negative := res[0]
+	api.AssertIsBoolean(negative)
+	f.rc.Check(api.Select(negative, api.Sub(x, FalconQ/2+1), api.Sub(FalconQ/2, x)), 14)
+	v := api.Sub(x, api.Mul(negative, FalconQ))
+	return api.Mul(v, v)
+}
+
+// reduce returns x mod q, x being less than q << quoBits.
+func (f *falconVerifier) reduce(x frontend.Variable, quoBits int) frontend.Variable {
+	res, err := f.api.Compiler().NewHint(falconDivHint, 2, x)
+	if err != nil {
+		panic(err)
+	}
+	quo, rem := res[0], res[1]
+	f.rc.Check(quo, quoBits)
+	f.assertReduced(rem)
+	f.api.AssertIsEqual(x, f.api.Add(f.api.Mul(quo, FalconQ), rem))
+	return rem
+}
+
+// assertReduced checks that 0 <= x < q.
+func (f *falconVerifier) assertReduced(x frontend.Variable) {
+	f.rc.Check(x, 14)
+	f.rc.Check(f.api.Add(x, 1<<14-FalconQ), 14)
+}
+
+// packKey packs the 14-bit coefficients of the Falcon public key by
+// FalconPacking in field elements, little-endian.
+func (f *falconVerifier) packKey(h []frontend.Variable) []frontend.Variable {
+	packed := make([]frontend.Variable, 0, (len(h)+FalconPacking-1)/FalconPacking)
+	for i := 0; i < len(h); i += FalconPacking {
+		var v frontend.Variable = 0
+		for j := min(i+FalconPacking, len(h)) - 1; j >= i; j-- {
+			v = f.api.Add(f.api.Mul(v, 1<<14), h[j])
+		}
+		packed = append(packed, v)
+	}
+	return packed
+}
+
+// falconDivHint returns the quotient and the remainder of x by q.
+func falconDivHint(_ *big.Int, inputs, outputs []*big.Int) error {
+	outputs[0].DivMod(inputs[0], big.NewInt(FalconQ), outputs[1])
+	return nil
+}
+
+// falconGreaterHint returns 1 when x > bound, 0 otherwise.
+func falconGreaterHint(_ *big.Int, inputs, outputs []*big.Int) error {
+	outputs[0].SetUint64(0)
+	if inputs[0].Cmp(inputs[1]) > 0 {
+		outputs[0].SetUint64(1)
+	}
+	return nil
+}
+
+// falconIndexHint returns the indices of the first samples below 5q.
+func falconIndexHint(_ *big.Int, inputs, outputs []*big.Int) error {
+	k := 0
+	for j := 0; j < len(inputs) && k < len(outputs); j++ {
+		if inputs[j].Cmp(big.NewInt(5*FalconQ)) < 0 {
+			outputs[k].SetInt64(int64(j))
+			k++
+		}
+	}
+	if k < len(outputs) {
+		return fmt.Errorf("only %d of the %d samples are below 5q", k, len(inputs))
+	}
+	return nil
+}
+
+// falconTwiddles returns the twiddle factors of the NTT and their inverses.
+func falconTwiddles() (zetas, zetasInv [FalconN]uint64) {
+	for k := range zetas {
+		brv := uint64(mathbits.Reverse16(uint16(k)) >> 7)
+		zetas[k] = falconPow(FalconPsi, brv)
+		zetasInv[k] = falconPow(zetas[k], FalconQ-2)
+	}
+	return zetas, zetasInv
+}
+
+// falconPow returns a^e mod q.
+func falconPow(a, e uint64) uint64 {
+	r := uint64(1)
+	a %= FalconQ
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = r * a % FalconQ
+		}
+		a = a * a % FalconQ
+	}
+	return r
+}
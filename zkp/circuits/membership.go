@@ -0,0 +1,71 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/accumulator/merkle"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// MembershipDepth is the depth of the Merkle tree of commitments, up to 2^MembershipDepth members.
+const MembershipDepth = 4
+
+// MembershipCircuit proves that the signer's commitment h(address, nonce) is a
+// leaf of the public Merkle root, without revealing which one.
+type MembershipCircuit[T, S emulated.FieldParams] struct {
+	Sig       ecdsa.Signature[S]    `gnark:",secret"` // signature
+	Msg       emulated.Element[S]   `gnark:",public"` // message
+	Pub       ecdsa.PublicKey[T, S] `gnark:",secret"` // signer key
+	Address   frontend.Variable     `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce     frontend.Variable     `gnark:",secret"` // secret nonce
+	Path      []frontend.Variable   `gnark:",secret"` // commitment of the signer, then the siblings up to the root
+	Index     frontend.Variable     `gnark:",secret"` // position of the signer in the tree
+	ChainID   frontend.Variable     `gnark:",public"` // chain id of the account
+	Account   frontend.Variable     `gnark:",public"` // address of the account using the proof
+	Nullifier frontend.Variable     `gnark:",public"` // h(nonce, msg, chainId, account)
+	Root      frontend.Variable     `gnark:",public"` // Merkle root of the commitments, last public input
+
+	CommitHash string `gnark:"-"` // commitment and tree hash, mimc or poseidon2
+}
+
+// NewMembershipCircuit allocates a membership circuit for a tree of the given depth.
+func NewMembershipCircuit[T, S emulated.FieldParams](depth int) MembershipCircuit[T, S] {
+	return MembershipCircuit[T, S]{
+		Path: make([]frontend.Variable, depth+1),
+	}
+}
+
+func (c *MembershipCircuit[T, S]) Define(api frontend.API) error {
+	curveParams := sw_emulated.GetCurveParams[T]()
+	c.Pub.Verify(api, curveParams, &c.Msg, &c.Sig)
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+
+	// specify constraints
+	// h(preImage) == leaf, and the leaf is in the tree
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Path[0], h.Sum())
+
+	proof := merkle.MerkleProof{RootHash: c.Root, Path: c.Path}
+	proof.VerifyProof(api, h, c.Index)
+
+	// the nullifier does not depend on the leaf, members stay indistinguishable
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
@@ -0,0 +1,66 @@
+package circuits
+
+import (
+	"fmt"
+	"hash"
+	"math/big"
+
+	cryptomimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	cryptoposeidon2 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/std/math/emulated"
+)
+
+// NewNativeCommitmentHasher returns the native hash of the public key
+// commitment, matching the in-circuit one.
+func NewNativeCommitmentHasher(name string) (hash.Hash, error) {
+	switch name {
+	case "", "mimc":
+		return cryptomimc.NewMiMC(), nil
+	case "poseidon2":
+		return cryptoposeidon2.NewMerkleDamgardHasher(), nil
+	default:
+		return nil, fmt.Errorf("unknown commitment hash %q", name)
+	}
+}
+
+// ComputeNullifier computes natively the nullifier h(nonce, msg limbs, chainId,
+// account) of Nullifier.
+func ComputeNullifier(name string, nonce *big.Int, msgLimbs []*big.Int, chainID, account *big.Int) (*big.Int, error) {
+	h, err := NewNativeCommitmentHasher(name)
+	if err != nil {
+		return nil, err
+	}
+	values := append(append([]*big.Int{nonce}, msgLimbs...), chainID, account)
+	for _, v := range values {
+		if v.BitLen() > 253 {
+			return nil, fmt.Errorf("nullifier input %x does not fit in a field element", v)
+		}
+		buf := make([]byte, 32)
+		h.Write(v.FillBytes(buf))
+	}
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+// ScalarLimbs returns the limbs of msg reduced modulo the order of S, as those
+// of the emulated scalar of the circuits.
+func ScalarLimbs[S emulated.FieldParams](msg *big.Int) []*big.Int {
+	var fr S
+	reduced := new(big.Int).Mod(msg, fr.Modulus())
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), fr.BitsPerLimb()), big.NewInt(1))
+	limbs := make([]*big.Int, fr.NbLimbs())
+	for i := range limbs {
+		limb := new(big.Int).Rsh(reduced, uint(i)*fr.BitsPerLimb())
+		limbs[i] = limb.And(limb, mask)
+	}
+	return limbs
+}
+
+// DigestLimbs splits a 32-byte big-endian digest into 4 little-endian 64-bit
+// limbs, without reducing it.
+func DigestLimbs(digest []byte) []*big.Int {
+	limbs := make([]*big.Int, 4)
+	for k := range limbs {
+		limbs[k] = new(big.Int).SetBytes(digest[24-8*k : 32-8*k])
+	}
+	return limbs
+}
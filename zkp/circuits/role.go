@@ -0,0 +1,88 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/accumulator/merkle"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// RoleDepth is the depth of the Merkle tree of the commitments of a role, up to
+// 2^RoleDepth keys per role.
+const RoleDepth = 4
+
+// Roles of the keys, the bit selecting the subtree of the role under the root.
+const (
+	RoleUser  = 0
+	RoleAdmin = 1
+)
+
+// RoleCircuit proves that the signer's commitment h(address, nonce) is a leaf
+// of the tree of the public role, without revealing which one. The root hashes
+// the root of the user keys and the root of the admin keys, so a leaf of the
+// tree of the role is at the index position + role*2^RoleDepth.
+type RoleCircuit[T, S emulated.FieldParams] struct {
+	Sig       ecdsa.Signature[S]    `gnark:",secret"` // signature
+	Msg       emulated.Element[S]   `gnark:",public"` // message
+	Role      frontend.Variable     `gnark:",public"` // role of the signer, 0: user, 1: admin
+	Pub       ecdsa.PublicKey[T, S] `gnark:",secret"` // signer key
+	Address   frontend.Variable     `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce     frontend.Variable     `gnark:",secret"` // secret nonce
+	Path      []frontend.Variable   `gnark:",secret"` // commitment of the signer, then the siblings up to the root
+	Position  frontend.Variable     `gnark:",secret"` // position of the signer in the tree of its role
+	ChainID   frontend.Variable     `gnark:",public"` // chain id of the account
+	Account   frontend.Variable     `gnark:",public"` // address of the account using the proof
+	Nullifier frontend.Variable     `gnark:",public"` // h(nonce, msg, chainId, account)
+	Root      frontend.Variable     `gnark:",public"` // h(user root, admin root), last public input
+
+	CommitHash string `gnark:"-"` // commitment and tree hash, mimc or poseidon2
+}
+
+// NewRoleCircuit allocates a role circuit for trees of the given depth.
+func NewRoleCircuit[T, S emulated.FieldParams](depth int) RoleCircuit[T, S] {
+	return RoleCircuit[T, S]{
+		Path: make([]frontend.Variable, depth+2),
+	}
+}
+
+func (c *RoleCircuit[T, S]) Define(api frontend.API) error {
+	curveParams := sw_emulated.GetCurveParams[T]()
+	c.Pub.Verify(api, curveParams, &c.Msg, &c.Sig)
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+
+	// specify constraints
+	// h(preImage) == leaf, and the leaf is in the tree of the role: the
+	// position stays in the subtree, the role selects the subtree
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Path[0], h.Sum())
+
+	depth := len(c.Path) - 2
+	api.AssertIsBoolean(c.Role)
+	bits.ToBinary(api, c.Position, bits.WithNbDigits(depth))
+	index := api.Add(c.Position, api.Mul(c.Role, 1<<depth))
+
+	proof := merkle.MerkleProof{RootHash: c.Root, Path: c.Path}
+	proof.VerifyProof(api, h, index)
+
+	// the nullifier does not depend on the leaf, keys of a role stay indistinguishable
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
@@ -0,0 +1,92 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// RotationTag prefixes the rotation message signed by the key.
+const RotationTag = "ZKeeper rotation v1"
+
+// RotationCircuit proves that the key committed in OldCom signed the rotation
+// to NewCom, a commitment to the same address with a new nonce.
+type RotationCircuit[T, S emulated.FieldParams] struct {
+	Sig      ecdsa.Signature[S]    `gnark:",secret"` // signature of the rotation message
+	Pub      ecdsa.PublicKey[T, S] `gnark:",secret"` // now secret
+	Address  frontend.Variable     `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce    frontend.Variable     `gnark:",secret"` // secret nonce of the old commitment
+	NewNonce frontend.Variable     `gnark:",secret"` // secret nonce of the new commitment
+	ChainID  frontend.Variable     `gnark:",public"` // chain id of the account
+	Account  frontend.Variable     `gnark:",public"` // address of the account
+	OldCom   frontend.Variable     `gnark:",public"` // commitment registered in the account
+	NewCom   frontend.Variable     `gnark:",public"` // new commitment, last public input
+
+	CommitHash string `gnark:"-"` // commitment hash, mimc or poseidon2
+}
+
+func (c *RotationCircuit[T, S]) Define(api frontend.API) error {
+	// the signed message is keccak256(tag || chainId || account || oldCom || newCom),
+	// the integers being encoded as 32-byte big-endian
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return err
+	}
+	keccak, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return err
+	}
+	keccak.Write(uints.NewU8Array([]byte(RotationTag)))
+	for _, v := range []frontend.Variable{c.ChainID, c.Account, c.OldCom, c.NewCom} {
+		vBits := bits.ToBinary(api, v, bits.WithNbDigits(256))
+		vBytes := make([]uints.U8, 32)
+		for i := range vBytes {
+			vBytes[31-i] = uapi.ByteValueOf(bits.FromBinary(api, vBits[8*i:8*i+8]))
+		}
+		keccak.Write(vBytes)
+	}
+	msgBytes := keccak.Sum()
+
+	scalarApi, err := emulated.NewField[S](api)
+	if err != nil {
+		return err
+	}
+	msgBits := make([]frontend.Variable, 0, 8*len(msgBytes))
+	for i := len(msgBytes) - 1; i >= 0; i-- {
+		msgBits = append(msgBits, api.ToBinary(msgBytes[i].Val, 8)...)
+	}
+	msg := scalarApi.FromBits(msgBits...)
+
+	curveParams := sw_emulated.GetCurveParams[T]()
+	c.Pub.Verify(api, curveParams, msg, &c.Sig)
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	// opening of the old commitment
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.OldCom, h.Sum())
+
+	// the new commitment is to the same address
+	nh, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	nh.Write(c.Address)
+	nh.Write(c.NewNonce)
+	api.AssertIsEqual(c.NewCom, nh.Sum())
+	return nil
+}
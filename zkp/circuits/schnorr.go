@@ -0,0 +1,145 @@
+package circuits
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// ChallengeTag is the BIP-340 tag of the challenge hash.
+const ChallengeTag = "BIP0340/challenge"
+
+// SchnorrSignature is a BIP-340 signature, R being the x coordinate of the
+// nonce point.
+type SchnorrSignature struct {
+	R emulated.Element[emulated.Secp256k1Fp]
+	S emulated.Element[emulated.Secp256k1Fr]
+}
+
+// SchnorrCircuit proves that the key committed in Com signed Msg with BIP-340
+// Schnorr. The commitment and the nullifier are those of Circuit.
+type SchnorrCircuit struct {
+	Sig       SchnorrSignature                                            `gnark:",secret"` // signature
+	Msg       emulated.Element[emulated.Secp256k1Fr]                      `gnark:",public"` // message
+	Pub       ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr] `gnark:",secret"` // x-only key, lifted to its even y
+	Address   frontend.Variable                                           `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce     frontend.Variable                                           `gnark:",secret"` // secret nonce
+	ChainID   frontend.Variable                                           `gnark:",public"` // chain id of the account
+	Account   frontend.Variable                                           `gnark:",public"` // address of the account using the proof
+	Nullifier frontend.Variable                                           `gnark:",public"` // h(nonce, msg, chainId, account)
+	Com       frontend.Variable                                           `gnark:",public"` // public commitment, last public input
+
+	CommitHash    string `gnark:"-"` // commitment hash, mimc or poseidon2
+	CommitVersion int    `gnark:"-"` // commitment layout, 0: h(address, nonce), 1: h(tag, chainId, account, address, nonce)
+}
+
+func (c *SchnorrCircuit) Define(api frontend.API) error {
+	if err := verifySchnorr(api, &c.Pub, &c.Msg, &c.Sig); err != nil {
+		return err
+	}
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	// h(preImage) == hash, the version 1 layout binding the commitment to
+	// the chain and the account
+	if c.CommitVersion == 1 {
+		h.Write(new(big.Int).SetBytes([]byte(CommitTagV1)), c.ChainID, c.Account)
+	}
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// the nullifier is unique per message, chain and account, and the secret
+	// nonce keeps it unlinkable to the commitment
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
+
+// verifySchnorr checks the BIP-340 signature (r, s) of the 32-byte message m:
+// with e = sha256(tag || tag || r || x(P) || m) mod n, tag = sha256(ChallengeTag),
+// the point R = [s]G - [e]P must have an even y and x(R) = r.
+func verifySchnorr(api frontend.API, pub *ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr], msg *emulated.Element[emulated.Secp256k1Fr], sig *SchnorrSignature) error {
+	cr, err := sw_emulated.New[emulated.Secp256k1Fp, emulated.Secp256k1Fr](api, sw_emulated.GetSecp256k1Params())
+	if err != nil {
+		return err
+	}
+	baseApi, err := emulated.NewField[emulated.Secp256k1Fp](api)
+	if err != nil {
+		return err
+	}
+	scalarApi, err := emulated.NewField[emulated.Secp256k1Fr](api)
+	if err != nil {
+		return err
+	}
+	uapi, err := uints.New[uints.U32](api)
+	if err != nil {
+		return err
+	}
+
+	// the x-only key is the point of even y
+	pk := sw_emulated.AffinePoint[emulated.Secp256k1Fp](*pub)
+	cr.AssertIsOnCurve(&pk)
+	api.AssertIsEqual(baseApi.ToBitsCanonical(&pk.Y)[0], 0)
+
+	// r < p and s < n, both non-zero
+	scalarApi.AssertIsInRange(&sig.S)
+	api.AssertIsEqual(baseApi.IsZero(&sig.R), 0)
+	api.AssertIsEqual(scalarApi.IsZero(&sig.S), 0)
+
+	// tagged hash of r || x(P) || m, each as 32 big-endian bytes
+	tag := sha256.Sum256([]byte(ChallengeTag))
+	challenge, err := sha2.New(api)
+	if err != nil {
+		return err
+	}
+	challenge.Write(uints.NewU8Array(tag[:]))
+	challenge.Write(uints.NewU8Array(tag[:]))
+	for _, coord := range []*emulated.Element[emulated.Secp256k1Fp]{&sig.R, &pk.X} {
+		coordBits := baseApi.ToBitsCanonical(coord)
+		coordBytes := make([]uints.U8, 32)
+		for i := range coordBytes {
+			coordBytes[31-i] = uapi.ByteValueOf(bits.FromBinary(api, coordBits[8*i:8*i+8]))
+		}
+		challenge.Write(coordBytes)
+	}
+	msgBytes := make([]uints.U8, 0, 32)
+	for i := len(msg.Limbs) - 1; i >= 0; i-- {
+		limbBits := bits.ToBinary(api, msg.Limbs[i], bits.WithNbDigits(64))
+		for j := 7; j >= 0; j-- {
+			msgBytes = append(msgBytes, uapi.ByteValueOf(bits.FromBinary(api, limbBits[8*j:8*j+8])))
+		}
+	}
+	challenge.Write(msgBytes)
+	digest := challenge.Sum()
+	eBits := make([]frontend.Variable, 0, 256)
+	for i := len(digest) - 1; i >= 0; i-- {
+		eBits = append(eBits, bits.ToBinary(api, digest[i].Val, bits.WithNbDigits(8))...)
+	}
+	e := scalarApi.FromBits(eBits...)
+
+	// R = [s]G + [-e]P
+	R := cr.JointScalarMulBase(&pk, scalarApi.Neg(e), &sig.S)
+	baseApi.AssertIsEqual(&R.X, &sig.R)
+	api.AssertIsEqual(baseApi.ToBitsCanonical(&R.Y)[0], 0)
+	return nil
+}
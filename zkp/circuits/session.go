@@ -0,0 +1,134 @@
+package circuits
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/hash/sha3"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	cryptosha3 "golang.org/x/crypto/sha3"
+)
+
+// DelegationTag is hashed into the first word of the delegation message, so
+// that a delegation is not the digest of an operation.
+const DelegationTag = "ZKeeper session v1"
+
+// SessionCircuit proves that the key committed in Com delegated to a session
+// key, until ValidUntil and for Scope, and that the session key signed Msg.
+// The hardware key signs the delegation digest
+// keccak256(abi.encode(keccak256(DelegationTag), chainId, account, session, validUntil, scope)),
+// the session key being designated by its address.
+type SessionCircuit struct {
+	Delegation ecdsa.Signature[emulated.Secp256k1Fr]                       `gnark:",secret"` // signature of the delegation by the committed key
+	Sig        ecdsa.Signature[emulated.Secp256k1Fr]                       `gnark:",secret"` // signature of msg by the session key
+	Msg        emulated.Element[emulated.Secp256k1Fr]                      `gnark:",public"` // message
+	ValidUntil frontend.Variable                                           `gnark:",public"` // expiry of the session key, a block number or a timestamp
+	Scope      frontend.Variable                                           `gnark:",public"` // scope of the session key, checked by the account
+	Pub        ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr] `gnark:",secret"` // committed hardware key
+	SessionPub ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr] `gnark:",secret"` // session key
+	Address    frontend.Variable                                           `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce      frontend.Variable                                           `gnark:",secret"` // secret nonce
+	ChainID    frontend.Variable                                           `gnark:",public"` // chain id of the account
+	Account    frontend.Variable                                           `gnark:",public"` // address of the account using the proof
+	Nullifier  frontend.Variable                                           `gnark:",public"` // h(nonce, msg, chainId, account)
+	Com        frontend.Variable                                           `gnark:",public"` // public commitment, last public input
+
+	CommitHash    string `gnark:"-"` // commitment hash, mimc or poseidon2
+	CommitVersion int    `gnark:"-"` // commitment layout, 0: h(address, nonce), 1: h(tag, chainId, account, address, nonce)
+}
+
+func (c *SessionCircuit) Define(api frontend.API) error {
+	curveParams := sw_emulated.GetSecp256k1Params()
+
+	// the committed key signed the delegation to the session key
+	sessionAddress, err := EthAddress(api, &c.SessionPub)
+	if err != nil {
+		return err
+	}
+	delegation, err := delegationDigest(api, c.ChainID, c.Account, sessionAddress, c.ValidUntil, c.Scope)
+	if err != nil {
+		return err
+	}
+	c.Pub.Verify(api, curveParams, delegation, &c.Delegation)
+
+	// the session key signed the message
+	c.SessionPub.Verify(api, curveParams, &c.Msg, &c.Sig)
+
+	// the committed address must be the one of the hardware key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+	// h(preImage) == hash, the version 1 layout binding the commitment to
+	// the chain and the account
+	if c.CommitVersion == 1 {
+		h.Write(new(big.Int).SetBytes([]byte(CommitTagV1)), c.ChainID, c.Account)
+	}
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// the nullifier is unique per message, chain and account, and the secret
+	// nonce keeps it unlinkable to the commitment
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg.Limbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
+
+// delegationDigest computes in-circuit the digest of the delegation, the
+// keccak256 of its 32-byte big-endian words. The chain id and validUntil fit
+// in 64 bits, the addresses in 160 bits and the scope in 253 bits.
+func delegationDigest(api frontend.API, chainID, account, session, validUntil, scope frontend.Variable) (*emulated.Element[emulated.Secp256k1Fr], error) {
+	scalarApi, err := emulated.NewField[emulated.Secp256k1Fr](api)
+	if err != nil {
+		return nil, err
+	}
+	uapi, err := uints.New[uints.U64](api)
+	if err != nil {
+		return nil, err
+	}
+	keccak, err := sha3.NewLegacyKeccak256(api)
+	if err != nil {
+		return nil, err
+	}
+	tag := cryptosha3.NewLegacyKeccak256()
+	tag.Write([]byte(DelegationTag))
+	keccak.Write(uints.NewU8Array(tag.Sum(nil)))
+	words := []struct {
+		v      frontend.Variable
+		nbBits int
+	}{{chainID, 64}, {account, 160}, {session, 160}, {validUntil, 64}, {scope, 253}}
+	for _, w := range words {
+		wordBits := bits.ToBinary(api, w.v, bits.WithNbDigits(w.nbBits))
+		for len(wordBits) < 256 {
+			wordBits = append(wordBits, 0)
+		}
+		wordBytes := make([]uints.U8, 32)
+		for i := range wordBytes {
+			wordBytes[31-i] = uapi.ByteValueOf(bits.FromBinary(api, wordBits[8*i:8*i+8]))
+		}
+		keccak.Write(wordBytes)
+	}
+
+	// the digest, read big-endian, is reduced by the signature verification
+	digest := keccak.Sum()
+	digestBits := make([]frontend.Variable, 0, 256)
+	for i := len(digest) - 1; i >= 0; i-- {
+		digestBits = append(digestBits, bits.ToBinary(api, digest[i].Val, bits.WithNbDigits(8))...)
+	}
+	return scalarApi.FromBits(digestBits...), nil
+}
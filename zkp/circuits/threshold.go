@@ -0,0 +1,78 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// NbSigners is the number m of committed keys of the k-of-m circuit.
+const NbSigners = 3
+
+// ThresholdCircuit proves that at least K of the committed keys signed Msg.
+// Slots without a signature verify a throwaway padding key instead and do not
+// count towards the threshold.
+type ThresholdCircuit[T, S emulated.FieldParams] struct {
+	Sigs   []ecdsa.Signature[S]    `gnark:",secret"` // one signature per slot
+	Signed []frontend.Variable     `gnark:",secret"` // 1 if the committed key of the slot signed
+	Pads   []ecdsa.PublicKey[T, S] `gnark:",secret"` // padding keys of the unsigned slots
+	Msg    emulated.Element[S]     `gnark:",public"` // message
+	Pubs   []ecdsa.PublicKey[T, S] `gnark:",secret"` // committed keys
+	K      frontend.Variable       `gnark:",secret"` // threshold, bound by the commitment
+	Nonce  frontend.Variable       `gnark:",secret"` // secret nonce
+	Com    frontend.Variable       `gnark:",public"` // public commitment
+
+	CommitHash string `gnark:"-"` // commitment hash, mimc or poseidon2
+}
+
+// NewThresholdCircuit allocates a threshold circuit over m committed keys.
+func NewThresholdCircuit[T, S emulated.FieldParams](m int) ThresholdCircuit[T, S] {
+	return ThresholdCircuit[T, S]{
+		Sigs:   make([]ecdsa.Signature[S], m),
+		Signed: make([]frontend.Variable, m),
+		Pads:   make([]ecdsa.PublicKey[T, S], m),
+		Pubs:   make([]ecdsa.PublicKey[T, S], m),
+	}
+}
+
+func (c *ThresholdCircuit[T, S]) Define(api frontend.API) error {
+	curveParams := sw_emulated.GetCurveParams[T]()
+	curve, err := sw_emulated.New[T, S](api, curveParams)
+	if err != nil {
+		return err
+	}
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+
+	// specify constraints
+	// h(k, address_1, ..., address_m, nonce) == hash
+	h.Write(c.K)
+
+	var count frontend.Variable = 0
+	for i := range c.Pubs {
+		api.AssertIsBoolean(c.Signed[i])
+		count = api.Add(count, c.Signed[i])
+
+		// unsigned slots verify the padding key instead of the committed one
+		pub := curve.Select(c.Signed[i],
+			(*sw_emulated.AffinePoint[T])(&c.Pubs[i]),
+			(*sw_emulated.AffinePoint[T])(&c.Pads[i]))
+		ecdsa.PublicKey[T, S](*pub).Verify(api, curveParams, &c.Msg, &c.Sigs[i])
+
+		address, err := EthAddress(api, &c.Pubs[i])
+		if err != nil {
+			return err
+		}
+		h.Write(address)
+	}
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// at least k committed keys signed
+	api.AssertIsLessOrEqual(c.K, count)
+	return nil
+}
@@ -0,0 +1,89 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits/eip1559"
+)
+
+// TransactionCircuit proves that a committed key signed an EIP-1559
+// transaction, and exposes the fields the account applies its policy on.
+type TransactionCircuit[T, S emulated.FieldParams] struct {
+	Sig       ecdsa.Signature[S]         `gnark:",secret"` // transaction signature
+	Pub       ecdsa.PublicKey[T, S]      `gnark:",secret"` // now secret
+	Tx        [eip1559.MaxTxLen]uints.U8 `gnark:",secret"` // unsigned transaction 0x02 || rlp(fields), zero padded
+	TxLen     frontend.Variable          `gnark:",secret"` // length of the unsigned transaction
+	Address   frontend.Variable          `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce     frontend.Variable          `gnark:",secret"` // secret nonce
+	To        frontend.Variable          `gnark:",public"` // destination of the transaction
+	Value     frontend.Variable          `gnark:",public"` // value of the transaction, in wei
+	Selector  frontend.Variable          `gnark:",public"` // first 4 bytes of the calldata, 0 without calldata
+	ChainID   frontend.Variable          `gnark:",public"` // chain id of the transaction and of the account
+	Account   frontend.Variable          `gnark:",public"` // address of the account using the proof
+	Nullifier frontend.Variable          `gnark:",public"` // h(nonce, msg, chainId, account)
+	Com       frontend.Variable          `gnark:",public"` // public commitment, last public input
+
+	CommitHash string `gnark:"-"` // commitment hash, mimc or poseidon2
+}
+
+func (c *TransactionCircuit[T, S]) Define(api frontend.API) error {
+	// the public fields are those of the signed transaction
+	tx, err := eip1559.Decode(api, c.Tx[:], c.TxLen)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.ChainID, tx.ChainID)
+	api.AssertIsEqual(c.To, tx.To)
+	api.AssertIsEqual(c.Value, tx.Value)
+	api.AssertIsEqual(c.Selector, tx.Selector)
+	msgBytes := tx.Hash
+
+	scalarApi, err := emulated.NewField[S](api)
+	if err != nil {
+		return err
+	}
+	msgBits := make([]frontend.Variable, 0, 8*len(msgBytes))
+	for i := len(msgBytes) - 1; i >= 0; i-- {
+		msgBits = append(msgBits, api.ToBinary(msgBytes[i].Val, 8)...)
+	}
+	msg := scalarApi.FromBits(msgBits...)
+
+	curveParams := sw_emulated.GetCurveParams[T]()
+	c.Pub.Verify(api, curveParams, msg, &c.Sig)
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+
+	// specify constraints
+	// h(preImage) == hash
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// the nullifier is unique per transaction hash, chain and account, the
+	// hash being split in little-endian 64-bit limbs as in the other circuits
+	msgLimbs := make([]frontend.Variable, 4)
+	for k := range msgLimbs {
+		msgLimbs[k] = bits.FromBinary(api, msgBits[64*k:64*k+64])
+	}
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, msgLimbs, c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
@@ -0,0 +1,154 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/hash"
+	"github.com/consensys/gnark/std/hash/sha2"
+	"github.com/consensys/gnark/std/lookup/logderivlookup"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+)
+
+// AuthDataLen is the length of an authenticatorData without extensions,
+// rpIdHash (32) || flags (1) || signCount (4).
+const AuthDataLen = 37
+
+// MaxClientDataLen is the maximum length of the clientDataJSON.
+const MaxClientDataLen = 384
+
+// ClientDataPrefix starts the clientDataJSON of every assertion, the challenge
+// follows as the base64url encoding of the 32-byte digest.
+const ClientDataPrefix = `{"type":"webauthn.get","challenge":"`
+
+// base64URLAlphabet is the alphabet of the unpadded base64url encoding.
+const base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// WebAuthnCircuit proves that a committed key signed a WebAuthn assertion
+// whose challenge is the public digest.
+type WebAuthnCircuit[T, S emulated.FieldParams] struct {
+	Sig           ecdsa.Signature[S]         `gnark:",secret"` // assertion signature
+	Msg           [4]frontend.Variable       `gnark:",public"` // digest, as little-endian 64-bit limbs
+	Pub           ecdsa.PublicKey[T, S]      `gnark:",secret"` // passkey
+	AuthData      [AuthDataLen]uints.U8      `gnark:",secret"` // authenticatorData
+	ClientData    [MaxClientDataLen]uints.U8 `gnark:",secret"` // clientDataJSON, zero padded
+	ClientDataLen frontend.Variable          `gnark:",secret"` // length of the clientDataJSON
+	Address       frontend.Variable          `gnark:",secret"` // secret address, keccak256(X||Y)[12:]
+	Nonce         frontend.Variable          `gnark:",secret"` // secret nonce
+	ChainID       frontend.Variable          `gnark:",public"` // chain id of the account
+	Account       frontend.Variable          `gnark:",public"` // address of the account using the proof
+	Nullifier     frontend.Variable          `gnark:",public"` // h(nonce, msg, chainId, account)
+	Com           frontend.Variable          `gnark:",public"` // public commitment, last public input
+
+	CommitHash string `gnark:"-"` // commitment hash, mimc or poseidon2
+}
+
+func (c *WebAuthnCircuit[T, S]) Define(api frontend.API) error {
+	// the assertion bytes are witnesses, constrain them to be bytes
+	uapi, err := uints.New[uints.U32](api)
+	if err != nil {
+		return err
+	}
+	authData := make([]uints.U8, len(c.AuthData))
+	for i := range authData {
+		authData[i] = uapi.ByteValueOf(c.AuthData[i].Val)
+	}
+	clientData := make([]uints.U8, len(c.ClientData))
+	for i := range clientData {
+		clientData[i] = uapi.ByteValueOf(c.ClientData[i].Val)
+	}
+
+	// big-endian bits of the digest
+	digestBits := make([]frontend.Variable, 256)
+	for k, limb := range c.Msg {
+		for j, b := range api.ToBinary(limb, 64) {
+			digestBits[255-64*k-j] = b
+		}
+	}
+
+	// the challenge of the clientDataJSON is the base64url encoding of the digest
+	alphabet := logderivlookup.New(api)
+	for _, ch := range []byte(base64URLAlphabet) {
+		alphabet.Insert(ch)
+	}
+	sextets := make([]frontend.Variable, (256+5)/6)
+	for i := range sextets {
+		var v frontend.Variable = 0
+		for t := 6 * i; t < 6*i+6; t++ {
+			v = api.Mul(v, 2)
+			if t < len(digestBits) {
+				v = api.Add(v, digestBits[t])
+			}
+		}
+		sextets[i] = v
+	}
+	challenge := alphabet.Lookup(sextets...)
+	for i, ch := range []byte(ClientDataPrefix) {
+		api.AssertIsEqual(clientData[i].Val, ch)
+	}
+	for i := range challenge {
+		api.AssertIsEqual(clientData[len(ClientDataPrefix)+i].Val, challenge[i])
+	}
+	api.AssertIsEqual(clientData[len(ClientDataPrefix)+len(challenge)].Val, '"')
+
+	// the user was present
+	flags := api.ToBinary(authData[32].Val, 8)
+	api.AssertIsEqual(flags[0], 1)
+
+	// the signed message is SHA-256(authenticatorData || SHA-256(clientDataJSON))
+	api.AssertIsLessOrEqual(c.ClientDataLen, MaxClientDataLen)
+	clientDataHasher, err := sha2.New(api, hash.WithMinimalLength(len(ClientDataPrefix)+len(challenge)+1))
+	if err != nil {
+		return err
+	}
+	clientDataHasher.Write(clientData)
+	clientDataHash := clientDataHasher.FixedLengthSum(c.ClientDataLen)
+
+	msgHasher, err := sha2.New(api)
+	if err != nil {
+		return err
+	}
+	msgHasher.Write(authData)
+	msgHasher.Write(clientDataHash)
+	msgBytes := msgHasher.Sum()
+
+	scalarApi, err := emulated.NewField[S](api)
+	if err != nil {
+		return err
+	}
+	msgBits := make([]frontend.Variable, 0, 8*len(msgBytes))
+	for i := len(msgBytes) - 1; i >= 0; i-- {
+		msgBits = append(msgBits, api.ToBinary(msgBytes[i].Val, 8)...)
+	}
+	msg := scalarApi.FromBits(msgBits...)
+
+	curveParams := sw_emulated.GetCurveParams[T]()
+	c.Pub.Verify(api, curveParams, msg, &c.Sig)
+
+	// the committed address must be the one of the verifying key
+	address, err := EthAddress(api, &c.Pub)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Address, address)
+
+	h, err := NewCommitmentHasher(api, c.CommitHash)
+	if err != nil {
+		return err
+	}
+
+	// specify constraints
+	// h(preImage) == hash
+	h.Write(c.Address)
+	h.Write(c.Nonce)
+	api.AssertIsEqual(c.Com, h.Sum())
+
+	// the nullifier is unique per digest, chain and account
+	nullifier, err := Nullifier(api, c.CommitHash, c.Nonce, c.Msg[:], c.ChainID, c.Account)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Nullifier, nullifier)
+	return nil
+}
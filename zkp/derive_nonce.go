@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	cryptosha3 "golang.org/x/crypto/sha3"
+)
+
+// commitmentTag is the domain-separated message signed by the key to derive
+// the nonce of its commitment.
+const commitmentTag = "ZKeeper commitment v1"
+
+// testVector is a software key (the key of RFC6979 A.2.5) with the signature
+// of the commitment message and the nonce derived from it, RFC6979 with SHA-256.
+// The secp256k1 signature is the one of go-ethereum crypto.Sign.
+var testVector = []struct {
+	Curve string
+	Key   string
+	R     string
+	S     string
+	Nonce string
+}{
+	{
+		Curve: "secp256k1",
+		Key:   "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+		R:     "97bbc23b6d38fcbf0dcff8a5f4f75b269ab331994f04292c9c2a04133496eb10",
+		S:     "0e7d32e759670b995155b91dde24b3bb26de3dcab4d5df9f6cc9fadd055d86d3",
+		Nonce: "8c15401aa6045c2b77b8cbcd58eae7477cb323df",
+	},
+	{
+		Curve: "p256",
+		Key:   "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+		R:     "f2146e22696144084a2570e9e578d95b0ef61b414f2acedf56ebde7e4ad1ba8e",
+		S:     "7c88903fba1a6c7c11d7cadd1c0f14f7e64d61674721fabfb865b4a9ff4df773",
+		Nonce: "07ca4367f0b2b13929f0568b5b12491f0290cbe4",
+	},
+}
+
+// Input struct for JSON deserialization of the public key.
+type Input struct {
+	PubX string `json:"pubX"` // Hex string of public key X
+	PubY string `json:"pubY"` // Hex string of public key Y
+}
+
+// weierstrassCurve holds the parameters of y^2 = x^3 + ax + b over F_p, of
+// order n and generator (gx, gy).
+type weierstrassCurve struct {
+	p, n, a, b, gx, gy *big.Int
+}
+
+func main() {
+	curve := flag.String("curve", "secp256k1", "curve of the signer: secp256k1 or p256")
+	rHex := flag.String("r", "", "hex r of the signature of the commitment message")
+	sHex := flag.String("s", "", "hex s of the signature of the commitment message")
+	keyHex := flag.String("key", "", "hex private key of a software signer, signing the commitment message with RFC6979")
+	vector := flag.Bool("vector", false, "check the derivation against the test vector")
+	flag.Parse()
+
+	if *vector {
+		checkTestVector()
+		return
+	}
+
+	c, err := newWeierstrassCurve(*curve)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	digest := commitmentDigest()
+
+	var r, s *big.Int
+	switch {
+	case *keyHex != "":
+		key, err := hex.DecodeString(*keyHex)
+		if err != nil {
+			fmt.Printf("Error decoding key hex: %v\n", err)
+			os.Exit(1)
+		}
+		r, s, err = signRFC6979(c, new(big.Int).SetBytes(key), digest)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("r: %x\ns: %x\n", r, s)
+	case *rHex != "" && *sHex != "":
+		rBytes, err := hex.DecodeString(*rHex)
+		if err != nil {
+			fmt.Printf("Error decoding r hex: %v\n", err)
+			os.Exit(1)
+		}
+		sBytes, err := hex.DecodeString(*sHex)
+		if err != nil {
+			fmt.Printf("Error decoding s hex: %v\n", err)
+			os.Exit(1)
+		}
+		r, s = new(big.Int).SetBytes(rBytes), new(big.Int).SetBytes(sBytes)
+
+		// the signature must be the one of the committed key
+		var pubKey Input
+		err = readFromFile("pub_key.json", &pubKey)
+		if err != nil {
+			fmt.Printf("Error reading pub_key.json: %v\n", err)
+			os.Exit(1)
+		}
+		pubX, err := hex.DecodeString(pubKey.PubX)
+		if err != nil {
+			fmt.Printf("Error decoding PubX hex: %v\n", err)
+			os.Exit(1)
+		}
+		pubY, err := hex.DecodeString(pubKey.PubY)
+		if err != nil {
+			fmt.Printf("Error decoding PubY hex: %v\n", err)
+			os.Exit(1)
+		}
+		if !verify(c, new(big.Int).SetBytes(pubX), new(big.Int).SetBytes(pubY), digest, r, s) {
+			fmt.Printf("Error: the signature of the commitment message is not from the key of pub_key.json\n")
+			os.Exit(1)
+		}
+	default:
+		// the device signs the digest, deterministically (RFC6979)
+		fmt.Printf("Sign with the key (RFC6979) the commitment message digest keccak256(%q):\n%x\n", commitmentTag, digest)
+		fmt.Println("then derive the nonce with go run derive_nonce.go -r <r> -s <s>")
+		return
+	}
+
+	nonce := deriveNonce(c, r, s)
+	fmt.Printf("nonce: %x\n", nonce)
+	fmt.Printf("The commitment is computed with go run pub_commit.go -curve %s -nonce %x\n", *curve, nonce)
+}
+
+// commitmentDigest returns keccak256(commitmentTag), the digest signed by the key.
+func commitmentDigest() []byte {
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write([]byte(commitmentTag))
+	return keccak.Sum(nil)
+}
+
+// deriveNonce returns the 20-byte nonce keccak256(r || s)[12:], s being taken
+// in its low-s form so that both forms of the signature give the same nonce.
+func deriveNonce(c *weierstrassCurve, r, s *big.Int) []byte {
+	lowS := new(big.Int).Set(s)
+	if lowS.Cmp(new(big.Int).Rsh(c.n, 1)) > 0 {
+		lowS.Sub(c.n, lowS)
+	}
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(r.FillBytes(make([]byte, 32)))
+	keccak.Write(lowS.FillBytes(make([]byte, 32)))
+	return keccak.Sum(nil)[12:]
+}
+
+// checkTestVector derives the nonce of the test vector keys, and exits with an
+// error when a value differs.
+func checkTestVector() {
+	for _, v := range testVector {
+		c, err := newWeierstrassCurve(v.Curve)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		key, _ := new(big.Int).SetString(v.Key, 16)
+		r, s, err := signRFC6979(c, key, commitmentDigest())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		nonce := hex.EncodeToString(deriveNonce(c, r, s))
+		if fmt.Sprintf("%064x", r) != v.R || fmt.Sprintf("%064x", s) != v.S || nonce != v.Nonce {
+			fmt.Printf("Error: %s test vector mismatch, got r=%064x s=%064x nonce=%s\n", v.Curve, r, s, nonce)
+			os.Exit(1)
+		}
+		fmt.Printf("%s test vector OK, nonce %s\n", v.Curve, nonce)
+	}
+}
+
+// newWeierstrassCurve returns the parameters of the signer curve.
+func newWeierstrassCurve(curve string) (*weierstrassCurve, error) {
+	switch curve {
+	case "secp256k1":
+		c := &weierstrassCurve{a: big.NewInt(0), b: big.NewInt(7)}
+		c.p, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+		c.n, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+		c.gx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+		c.gy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+		return c, nil
+	case "p256":
+		params := elliptic.P256().Params()
+		return &weierstrassCurve{
+			p:  params.P,
+			n:  params.N,
+			a:  new(big.Int).Sub(params.P, big.NewInt(3)),
+			b:  params.B,
+			gx: params.Gx,
+			gy: params.Gy,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown curve %q", curve)
+	}
+}
+
+// signRFC6979 signs the digest with the ECDSA nonce k of RFC6979 with SHA-256,
+// and returns the signature in its low-s form.
+func signRFC6979(c *weierstrassCurve, key *big.Int, digest []byte) (*big.Int, *big.Int, error) {
+	if key.Sign() <= 0 || key.Cmp(c.n) >= 0 {
+		return nil, nil, fmt.Errorf("the private key must be in [1, n-1]")
+	}
+	z := bits2int(c.n, digest)
+	z.Mod(z, c.n)
+	k := rfc6979Nonce(c.n, key, z)
+
+	rx, _ := c.scalarMul(c.gx, c.gy, k)
+	r := new(big.Int).Mod(rx, c.n)
+	s := new(big.Int).Mul(r, key)
+	s.Add(s, z).Mul(s, new(big.Int).ModInverse(k, c.n)).Mod(s, c.n)
+	if r.Sign() == 0 || s.Sign() == 0 {
+		return nil, nil, fmt.Errorf("invalid signature, r or s is zero")
+	}
+	if s.Cmp(new(big.Int).Rsh(c.n, 1)) > 0 {
+		s.Sub(c.n, s)
+	}
+	return r, s, nil
+}
+
+// rfc6979Nonce returns the deterministic nonce k of RFC6979 section 3.2 for
+// the private key and the reduced digest z, with HMAC-SHA-256.
+func rfc6979Nonce(n, key, z *big.Int) *big.Int {
+	size := (n.BitLen() + 7) / 8
+	seed := append(key.FillBytes(make([]byte, size)), z.FillBytes(make([]byte, size))...)
+
+	mac := func(k []byte, data ...[]byte) []byte {
+		h := hmac.New(sha256.New, k)
+		for _, d := range data {
+			h.Write(d)
+		}
+		return h.Sum(nil)
+	}
+	v := bytes.Repeat([]byte{0x01}, sha256.Size)
+	k := make([]byte, sha256.Size)
+	k = mac(k, v, []byte{0x00}, seed)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, seed)
+	v = mac(k, v)
+	for {
+		var t []byte
+		for len(t) < size {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+		nonce := bits2int(n, t[:size])
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			return nonce
+		}
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
+
+// bits2int converts bytes to an integer of at most the bit length of n, as in
+// RFC6979 section 2.3.2.
+func bits2int(n *big.Int, b []byte) *big.Int {
+	x := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - n.BitLen(); excess > 0 {
+		x.Rsh(x, uint(excess))
+	}
+	return x
+}
+
+// verify checks the ECDSA signature (r, s) of the digest by the public key.
+func verify(c *weierstrassCurve, x, y *big.Int, digest []byte, r, s *big.Int) bool {
+	if r.Sign() <= 0 || r.Cmp(c.n) >= 0 || s.Sign() <= 0 || s.Cmp(c.n) >= 0 {
+		return false
+	}
+	z := bits2int(c.n, digest)
+	sInv := new(big.Int).ModInverse(s, c.n)
+	u1 := new(big.Int).Mul(z, sInv)
+	u1.Mod(u1, c.n)
+	u2 := new(big.Int).Mul(r, sInv)
+	u2.Mod(u2, c.n)
+	x1, y1 := c.scalarMul(c.gx, c.gy, u1)
+	x2, y2 := c.scalarMul(x, y, u2)
+	qx, _ := c.add(x1, y1, x2, y2)
+	return qx != nil && new(big.Int).Mod(qx, c.n).Cmp(r) == 0
+}
+
+// add adds two affine points of the curve, the point at infinity being nil.
+func (c *weierstrassCurve) add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	lambda := new(big.Int)
+	if x1.Cmp(x2) == 0 {
+		sum := new(big.Int).Add(y1, y2)
+		if sum.Mod(sum, c.p).Sign() == 0 {
+			return nil, nil
+		}
+		// (3x^2 + a) / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3)).Add(num, c.a)
+		den := new(big.Int).Lsh(y1, 1)
+		lambda.Mul(num, den.ModInverse(den.Mod(den, c.p), c.p))
+	} else {
+		// (y2 - y1) / (x2 - x1)
+		den := new(big.Int).Sub(x2, x1)
+		lambda.Mul(new(big.Int).Sub(y2, y1), den.ModInverse(den.Mod(den, c.p), c.p))
+	}
+	lambda.Mod(lambda, c.p)
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, x1).Sub(x, x2).Mod(x, c.p)
+	y := new(big.Int).Sub(x1, x)
+	y.Mul(y, lambda).Sub(y, y1).Mod(y, c.p)
+	return x, y
+}
+
+// scalarMul computes k(x, y) by double-and-add.
+func (c *weierstrassCurve) scalarMul(x, y, k *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		rx, ry = c.add(rx, ry, rx, ry)
+		if k.Bit(i) == 1 {
+			rx, ry = c.add(rx, ry, x, y)
+		}
+	}
+	return rx, ry
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *Input: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxTxLen is the maximum length of the unsigned transaction 0x02 || rlp(fields).
+const maxTxLen = 512
+
+// Transaction struct for JSON deserialization of an EIP-1559 transaction and
+// of its signature. The integers are hex strings.
+type Transaction struct {
+	ChainID              string `json:"chainId"`
+	Nonce                string `json:"nonce"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         string `json:"maxFeePerGas"`
+	Gas                  string `json:"gas"`
+	To                   string `json:"to"`
+	Value                string `json:"value"`
+	Data                 string `json:"data"`
+	R                    string `json:"r"` // Hex string of signature R, empty before signing
+	S                    string `json:"s"` // Hex string of signature S, empty before signing
+}
+
+// InputWithCommit struct for JSON deserialization of the commitment of the signer.
+type InputWithCommit struct {
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, must be secp256k1
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+}
+
+// ProveInputTransaction struct for JSON serialization of EIP-1559 witness inputs.
+type ProveInputTransaction struct {
+	Tx       string `json:"tx"`       // Hex string of the unsigned transaction 0x02 || rlp(fields)
+	MsgHash  string `json:"msgHash"`  // Hex string of keccak256(tx), the signed digest
+	R        string `json:"r"`        // Hex string of signature R
+	S        string `json:"s"`        // Hex string of signature S
+	PubX     string `json:"pubX"`     // Hex string of public key X
+	PubY     string `json:"pubY"`     // Hex string of public key Y
+	Address  string `json:"address"`  // Hex string of address
+	Nonce    string `json:"nonce"`    // Hex string of nonce
+	To       string `json:"to"`       // Hex string of the destination
+	Value    string `json:"value"`    // Hex string of the value
+	Selector string `json:"selector"` // Hex string of the selector, 0 without calldata
+	ChainID  string `json:"chainId"`  // Hex string of the chain id of the transaction and of the account
+	Account  string `json:"account"`  // Hex string of the account using the proof
+	Com      string `json:"com"`      // Hex string of Com
+	Hash     string `json:"hash"`     // Hash of the commitment, mimc or poseidon2
+}
+
+func main() {
+	txFile := flag.String("tx", "transaction.json", "EIP-1559 transaction and its signature")
+	account := flag.String("account", "", "hex address of the account using the proof")
+	flag.Parse()
+
+	var transaction Transaction
+	err := readFromFile(*txFile, &transaction)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", *txFile, err)
+		os.Exit(1)
+	}
+	var commitment InputWithCommit
+	err = readFromFile("witness_input.json", &commitment)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	if commitment.Version != 0 {
+		fmt.Printf("Error: only the single signer circuit supports version 1 commitments, commit with pub_commit.go -version 0\n")
+		os.Exit(1)
+	}
+	if commitment.Curve != "" && commitment.Curve != "secp256k1" {
+		fmt.Printf("Error: witness_input.json commits to a %q key, a secp256k1 key is expected\n", commitment.Curve)
+		os.Exit(1)
+	}
+	if _, err = hex.DecodeString(*account); err != nil || *account == "" {
+		fmt.Printf("Error: -account must be a hex string\n")
+		os.Exit(1)
+	}
+
+	// the circuit supports calls without access list, the integers must fit
+	// in a native field element
+	ints := make([]*big.Int, 6)
+	for i, v := range []string{transaction.ChainID, transaction.Nonce, transaction.MaxPriorityFeePerGas, transaction.MaxFeePerGas, transaction.Gas, transaction.Value} {
+		ints[i] = mustDecodeHex("integer field", v)
+		if ints[i].BitLen() > 8*31 {
+			fmt.Printf("Error: %s does not fit in 31 bytes\n", v)
+			os.Exit(1)
+		}
+	}
+	toBytes, err := hex.DecodeString(transaction.To)
+	if err != nil || len(toBytes) != 20 {
+		fmt.Printf("Error: to must be a hex encoded 20-byte address, contract creations are not supported\n")
+		os.Exit(1)
+	}
+	data, err := hex.DecodeString(transaction.Data)
+	if err != nil {
+		fmt.Printf("Error decoding data hex: %v\n", err)
+		os.Exit(1)
+	}
+	if len(data) > 0 && len(data) < 4 {
+		fmt.Printf("Error: the calldata must be empty or start with a 4-byte selector\n")
+		os.Exit(1)
+	}
+	to := common.BytesToAddress(toBytes)
+
+	// 0x02 || rlp([chainId, nonce, maxPriorityFeePerGas, maxFeePerGas, gasLimit, to, value, data, accessList])
+	payload, err := rlp.EncodeToBytes([]interface{}{ints[0], ints[1], ints[2], ints[3], ints[4], to, ints[5], data, []interface{}{}})
+	if err != nil {
+		fmt.Printf("Error encoding transaction: %v\n", err)
+		os.Exit(1)
+	}
+	unsignedTx := append([]byte{0x02}, payload...)
+	if len(unsignedTx) > maxTxLen {
+		fmt.Printf("Error: the transaction is %d bytes long, at most %d is supported\n", len(unsignedTx), maxTxLen)
+		os.Exit(1)
+	}
+
+	// same digest as the one signed by wallets
+	msgHash := crypto.Keccak256(unsignedTx)
+	fmt.Printf("Transaction hash to sign: %x\n", msgHash)
+
+	selector := new(big.Int)
+	if len(data) > 0 {
+		selector.SetBytes(data[:4])
+	}
+
+	if transaction.R == "" || transaction.S == "" {
+		fmt.Printf("Sign the transaction hash and fill r and s in %s\n", *txFile)
+		os.Exit(1)
+	}
+
+	// check the signature against the committed key before proving
+	var pub ecdsa.PublicKey
+	pub.A.X.SetBytes(mustDecodeHex("PubX", commitment.PubX).Bytes())
+	pub.A.Y.SetBytes(mustDecodeHex("PubY", commitment.PubY).Bytes())
+	sigBytes := make([]byte, 64)
+	mustDecodeHex("R", transaction.R).FillBytes(sigBytes[:32])
+	mustDecodeHex("S", transaction.S).FillBytes(sigBytes[32:])
+	ok, err := pub.Verify(sigBytes, msgHash, nil)
+	if err != nil || !ok {
+		fmt.Printf("Error: the transaction is not signed by the committed key\n")
+		os.Exit(1)
+	}
+
+	Output := ProveInputTransaction{
+		Tx:       hex.EncodeToString(unsignedTx),
+		MsgHash:  hex.EncodeToString(msgHash),
+		R:        transaction.R,
+		S:        transaction.S,
+		PubX:     commitment.PubX,
+		PubY:     commitment.PubY,
+		Address:  commitment.Address,
+		Nonce:    commitment.Nonce,
+		To:       hex.EncodeToString(toBytes),
+		Value:    hex.EncodeToString(ints[5].Bytes()),
+		Selector: hex.EncodeToString(selector.Bytes()),
+		ChainID:  hex.EncodeToString(ints[0].Bytes()),
+		Account:  *account,
+		Com:      commitment.Com,
+		Hash:     commitment.Hash,
+	}
+
+	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling prove input JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeToFile("eip1559_witness_input.json", bytes.NewReader(OutputJSON))
+}
+
+// mustDecodeHex decodes a hex string into a big.Int, an empty string being 0,
+// and exits on error.
+func mustDecodeHex(name, s string) *big.Int {
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		fmt.Printf("Error decoding %s hex: %v\n", name, err)
+		os.Exit(1)
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case *Transaction, *InputWithCommit: // For the JSON inputs
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	"circuits"
+)
+
+// InputWithCommit struct for JSON deserialization of a member witness_input.json.
+type InputWithCommit struct {
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+}
+
+// MemberPath struct for JSON serialization of the inclusion path of a member.
+type MemberPath struct {
+	Com   string   `json:"com"`   // Hex string of the member commitment
+	Index int      `json:"index"` // Position of the leaf in the tree
+	Path  []string `json:"path"`  // Hex strings of the siblings, from the leaf up to the root
+}
+
+// MembershipTree struct for JSON serialization of the Merkle tree of commitments.
+type MembershipTree struct {
+	Hash    string       `json:"hash"`    // Hash of the commitments and of the tree, mimc or poseidon2
+	Depth   int          `json:"depth"`   // Depth of the tree
+	Root    string       `json:"root"`    // Hex string of the Merkle root, public input of the proof
+	Members []MemberPath `json:"members"` // Inclusion paths of the members
+}
+
+func main() {
+	depth := flag.Int("depth", 4, "depth of the Merkle tree, as chosen at setup")
+	output := flag.String("o", "membership_tree.json", "output file")
+	flag.Usage = func() {
+		fmt.Println("Usage: go run membership_tree.go [-depth 4] [-o membership_tree.json] <witness_input.json>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if flag.NArg() > 1<<*depth {
+		fmt.Printf("Error: %d members do not fit in a tree of depth %d\n", flag.NArg(), *depth)
+		os.Exit(1)
+	}
+
+	// the leaves are the commitments of the members, empty leaves are 0
+	coms := make([]*big.Int, 1<<*depth)
+	for i := range coms {
+		coms[i] = new(big.Int)
+	}
+	commitHash := ""
+	seen := make(map[string]bool)
+	for i, filename := range flag.Args() {
+		var member InputWithCommit
+		err := readFromFile(filename, &member)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", filename, err)
+			os.Exit(1)
+		}
+		if member.Version != 0 {
+			fmt.Printf("Error: %s is a version 1 commitment, only the single signer circuit supports version 1 commitments, commit with pub_commit.go -version 0\n", filename)
+			os.Exit(1)
+		}
+		if member.Hash == "" {
+			member.Hash = "mimc"
+		}
+		if commitHash == "" {
+			commitHash = member.Hash
+		}
+		if member.Hash != commitHash {
+			fmt.Printf("Error: %s is committed with %s, previous members with %s\n", filename, member.Hash, commitHash)
+			os.Exit(1)
+		}
+		comBytes, err := hex.DecodeString(member.Com)
+		if err != nil {
+			fmt.Printf("Error decoding Com hex of %s: %v\n", filename, err)
+			os.Exit(1)
+		}
+		coms[i].SetBytes(comBytes)
+		if coms[i].Sign() == 0 || seen[coms[i].String()] {
+			fmt.Printf("Error: commitment of %s is empty or already in the tree\n", filename)
+			os.Exit(1)
+		}
+		seen[coms[i].String()] = true
+	}
+
+	// levels[0] are the hashed leaves, levels[depth] is the root
+	levels := make([][]*big.Int, *depth+1)
+	levels[0] = make([]*big.Int, len(coms))
+	for i, com := range coms {
+		leaf, err := hashFieldElements(commitHash, com)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		levels[0][i] = leaf
+	}
+	for d := 1; d <= *depth; d++ {
+		levels[d] = make([]*big.Int, len(levels[d-1])/2)
+		for i := range levels[d] {
+			node, err := hashFieldElements(commitHash, levels[d-1][2*i], levels[d-1][2*i+1])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			levels[d][i] = node
+		}
+	}
+
+	members := make([]MemberPath, flag.NArg())
+	for i := range members {
+		path := make([]string, *depth)
+		index := i
+		for d := 0; d < *depth; d++ {
+			path[d] = hex.EncodeToString(levels[d][index^1].Bytes())
+			index >>= 1
+		}
+		members[i] = MemberPath{
+			Com:   hex.EncodeToString(coms[i].Bytes()),
+			Index: i,
+			Path:  path,
+		}
+	}
+
+	Output := MembershipTree{
+		Hash:    commitHash,
+		Depth:   *depth,
+		Root:    hex.EncodeToString(levels[*depth][0].Bytes()),
+		Members: members,
+	}
+
+	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling membership tree JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeToFile(*output, bytes.NewReader(OutputJSON))
+	fmt.Printf("Root: %s\n", Output.Root)
+}
+
+// hashFieldElements hashes field elements written as 32-byte big-endian
+// integers, as the in-circuit Merkle proof does.
+func hashFieldElements(name string, values ...*big.Int) (*big.Int, error) {
+	h, err := circuits.NewNativeCommitmentHasher(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		buf := make([]byte, 32)
+		_, err = h.Write(v.FillBytes(buf))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case *InputWithCommit: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"zkbackend"
+)
+
+// InputWithCommit struct for JSON deserialization of the commitment of the signer.
+type InputWithCommit struct {
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1 (default) or p256
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+}
+
+// BatchSignature struct for JSON deserialization of one signed digest of the batch.
+type BatchSignature struct {
+	MsgHash string `json:"msgHash"` // Hex string of the message hash
+	R       string `json:"r"`       // Hex string of signature R
+	S       string `json:"s"`       // Hex string of signature S
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"` // Hash of the public key commitment, mimc or poseidon2
+	Curve      string `json:"curve"`      // Curve of the signer, secp256k1 or p256
+	LowS       bool   `json:"lowS"`       // The circuit rejects high-s signatures
+	Backend    string `json:"backend"`    // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`     // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	signaturesFile := flag.String("signatures", "batch_signatures.json", "JSON array of the signed digests, {msgHash, r, s}")
+	flag.Parse()
+
+	var commitment InputWithCommit
+	err := readFromFile("witness_input.json", &commitment)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	if commitment.Version != 0 {
+		fmt.Printf("Error: only the single signer circuit supports version 1 commitments, commit with pub_commit.go -version 0\n")
+		os.Exit(1)
+	}
+	fmt.Println("Read witness_input.json")
+	var signatures []BatchSignature
+	err = readFromFile(*signaturesFile, &signatures)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", *signaturesFile, err)
+		os.Exit(1)
+	}
+	if len(signatures) == 0 || len(signatures) > circuits.BatchSize {
+		fmt.Printf("Error: %s holds %d signatures, between 1 and %d are expected\n", *signaturesFile, len(signatures), circuits.BatchSize)
+		os.Exit(1)
+	}
+	// a digest signed twice would give the same nullifier twice
+	seen := make(map[string]bool)
+	for _, sig := range signatures {
+		msgHash, err := hex.DecodeString(sig.MsgHash)
+		if err != nil {
+			fmt.Printf("Error decoding msgHash hex: %v\n", err)
+			os.Exit(1)
+		}
+		key := new(big.Int).SetBytes(msgHash).String()
+		if seen[key] {
+			fmt.Printf("Error: the digest %s appears twice in the batch\n", sig.MsgHash)
+			os.Exit(1)
+		}
+		seen[key] = true
+	}
+	if commitment.Curve == "" {
+		commitment.Curve = "secp256k1"
+	}
+	curve := commitment.Curve
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc", Curve: curve}
+	configName := artifactName(curve, "batch_setup_config.json")
+	if _, statErr := os.Stat(configName); statErr == nil {
+		err = readFromFile(configName, &config)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", configName, err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: %s is a test setup, its proofs must not be trusted on-chain\n", configName)
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile(artifactName(curve, "batch_r1cs.bin"), loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "batch_r1cs.bin"), err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read %s (Constraints: %d)\n", artifactName(curve, "batch_r1cs.bin"), loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile(artifactName(curve, "batch_proving_key.bin"), loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "batch_proving_key.bin"), err)
+		os.Exit(1)
+	}
+	fmt.Println("Read", artifactName(curve, "batch_proving_key.bin"))
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile(artifactName(curve, "batch_verifying_key.bin"), loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "batch_verifying_key.bin"), err)
+		os.Exit(1)
+	}
+	fmt.Println("Read", artifactName(curve, "batch_verifying_key.bin"))
+
+	// 4. the commitment must have been computed with the hash of the setup
+	if commitment.Hash == "" {
+		commitment.Hash = "mimc"
+	}
+	if commitment.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", commitment.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if config.Curve != curve {
+		fmt.Printf("Error: signer on %s, setup uses %s\n", curve, config.Curve)
+		os.Exit(1)
+	}
+
+	// 5. Create the witness, the unused slots repeating the first signature
+	var witnessFull witness.Witness
+	switch curve {
+	case "secp256k1":
+		witnessFull, err = newBatchWitness[emulated.Secp256k1Fp, emulated.Secp256k1Fr](commitment, signatures, config.CommitHash)
+	case "p256":
+		witnessFull, err = newBatchWitness[emulated.P256Fp, emulated.P256Fr](commitment, signatures, config.CommitHash)
+	default:
+		err = fmt.Errorf("unknown curve %q", curve)
+	}
+	if err != nil {
+		fmt.Printf("Error creating witness: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitness, err := witnessFull.Public()
+	if err != nil {
+		fmt.Printf("Error creating public witness: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Prove and verify
+	fmt.Printf("\n--- Proving %d digests ---\n", len(signatures))
+	startProve := time.Now()
+	proof, err := b.Prove(loadedR1CS, loadedPK, witnessFull)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
+
+	err = b.Verify(proof, loadedVK, publicWitness)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proof)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Verification SUCCEEDED")
+
+	// 7. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	publicInputs := fmt.Sprintf("%v", publicWitness.Vector())
+	nbInputs := len(publicWitness.Vector().(fr.Vector))
+	nullifiersIndex := nbInputs - 1 - circuits.BatchSize
+	countIndex := nullifiersIndex - 3
+	name := batchVerifierName(curve)
+	verifierTestFile, err := os.Create("solidity/test/" + name + ".t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/%s.t.sol: %v\n", name, err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/` + name + `.sol";
+
+contract ` + name + `Test is Test {
+    PlonkVerifier ZkBatch;
+
+    // public inputs: msgs (4 limbs each), count, chainId, account, nullifiers, commitment
+    uint256 constant NB_INPUTS = ` + fmt.Sprint(nbInputs) + `;
+    uint256 constant COUNT_INDEX = ` + fmt.Sprint(countIndex) + `;
+    uint256 constant NULLIFIERS_INDEX = ` + fmt.Sprint(nullifiersIndex) + `;
+    mapping(uint256 => bool) usedNullifiers;
+
+    function setUp() public {
+        ZkBatch = new PlonkVerifier();
+    }
+
+    // useProof accepts the digests of a batch only once, the padding slots
+    // after count being ignored
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        uint256 count = inputs[COUNT_INDEX];
+        for (uint256 i = 0; i < count; i++) {
+            if (usedNullifiers[inputs[NULLIFIERS_INDEX + i]]) return false;
+        }
+        if (!ZkBatch.Verify(proof, inputs)) return false;
+        for (uint256 i = 0; i < count; i++) {
+            usedNullifiers[inputs[NULLIFIERS_INDEX + i]] = true;
+        }
+        return true;
+    }
+
+    function test_` + curve + `Batch() public {
+        bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";
+        uint256[NB_INPUTS] memory public_inputs = ` + publicInputs + `;
+
+        uint256[] memory inputs = new uint256[](NB_INPUTS);
+        for (uint i = 0; i < NB_INPUTS; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = useProof(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifiers are spent, the same batch cannot be replayed
+        assertFalse(useProof(proof, inputs));
+    }
+}
+`))
+	fmt.Printf("Successfully exported solidity/test/%s.t.sol\n", name)
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitness.Vector(), "\"\n")
+}
+
+// newBatchWitness builds the full witness of the batch circuit instantiated on
+// the curve of the signer. The slots after the signed digests repeat the first
+// signature, with a zero nullifier.
+func newBatchWitness[T, S emulated.FieldParams](in InputWithCommit, signatures []BatchSignature, commitHash string) (witness.Witness, error) {
+	// Decode hex strings back to big.Int for witness construction
+	fields := []string{in.PubX, in.PubY, in.Address, in.Nonce, in.ChainID, in.Account, in.Com}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	pubX, pubY, address, nonce := values[0], values[1], values[2], values[3]
+	chainID, account, com := values[4], values[5], values[6]
+
+	assignment := circuits.BatchCircuit[T, S]{
+		Count: len(signatures),
+		Pub: ecdsa.PublicKey[T, S]{
+			X: emulated.ValueOf[T](pubX),
+			Y: emulated.ValueOf[T](pubY),
+		},
+		Address: address,
+		Nonce:   nonce,
+		ChainID: chainID,
+		Account: account,
+		Com:     com,
+
+		CommitHash: commitHash,
+	}
+	for i := 0; i < circuits.BatchSize; i++ {
+		sig := signatures[0]
+		if i < len(signatures) {
+			sig = signatures[i]
+		}
+		sigFields := []string{sig.MsgHash, sig.R, sig.S}
+		sigValues := make([]*big.Int, len(sigFields))
+		for j, field := range sigFields {
+			b, err := hex.DecodeString(field)
+			if err != nil {
+				return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+			}
+			sigValues[j] = new(big.Int).SetBytes(b)
+		}
+		msgHash, r, s := sigValues[0], sigValues[1], sigValues[2]
+		normalizeS[S](s)
+
+		assignment.Sigs[i] = ecdsa.Signature[S]{
+			R: emulated.ValueOf[S](r),
+			S: emulated.ValueOf[S](s),
+		}
+		assignment.Msgs[i] = emulated.ValueOf[S](msgHash)
+		assignment.Nullifiers[i] = 0
+		if i < len(signatures) {
+			// the nullifier binds the proof to this message, chain and account
+			nullifier, err := circuits.ComputeNullifier(commitHash, nonce, circuits.ScalarLimbs[S](msgHash), chainID, account)
+			if err != nil {
+				return nil, err
+			}
+			assignment.Nullifiers[i] = nullifier
+		}
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// normalizeS replaces s by n - s when s > (n-1)/2. (r, n - s) is also a valid
+// signature, so the witness is the same for both forms of the signature.
+func normalizeS[S emulated.FieldParams](s *big.Int) {
+	var fr S
+	if s.Cmp(new(big.Int).Rsh(fr.Modulus(), 1)) > 0 {
+		s.Sub(fr.Modulus(), s)
+	}
+}
+
+// artifactName returns the file name of a setup artifact for the signer curve.
+// The secp256k1 artifacts keep their historical names.
+func artifactName(curve, name string) string {
+	if curve == "" || curve == "secp256k1" {
+		return name
+	}
+	return curve + "_" + name
+}
+
+// batchVerifierName returns the name of the Solidity batch verifier for the
+// signer curve.
+func batchVerifierName(curve string) string {
+	if curve == "p256" {
+		return "P256BatchVerifier"
+	}
+	return "BatchVerifier"
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *InputWithCommit, *[]BatchSignature: // For the JSON inputs
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
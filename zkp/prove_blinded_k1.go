@@ -7,180 +7,695 @@ import (
 
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
+	"strings"
 
 	// Added for performance timing
 	"github.com/consensys/gnark-crypto/ecc"
-
-	// cryptoposeidon2 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	cryptosha3 "golang.org/x/crypto/sha3"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 
-	"github.com/consensys/gnark/backend/plonk"
-	plonk_bn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
-	"github.com/consensys/gnark/std/hash/mimc"
 	"github.com/consensys/gnark/std/math/emulated"
+	recursion_plonk "github.com/consensys/gnark/std/recursion/plonk"
 	"github.com/consensys/gnark/std/signature/ecdsa"
-)
-
-// Circuit defines the circuit structure as provided by you.
-type Circuit[T, S emulated.FieldParams] struct {
-	Sig     ecdsa.Signature[S]    `gnark:",secret"` // signature
-	Msg     emulated.Element[S]   `gnark:",public"` // message
-	Pub     ecdsa.PublicKey[T, S] `gnark:",secret"` // now secret
-	Address frontend.Variable     `gnark:",secret"` // secret address
-	Nonce   frontend.Variable     `gnark:",secret"` // secret nonce
-	Com     frontend.Variable     `gnark:",public"` // public commitment
-}
 
-func (c *Circuit[T, S]) Define(api frontend.API) error {
-	curveParams := sw_emulated.GetCurveParams[T]()
-	c.Pub.Verify(api, curveParams, &c.Msg, &c.Sig)
-
-	mimc, _ := mimc.NewMiMC(api)
-
-	// specify constraints
-	// mimc(preImage) == hash
-	mimc.Write(c.Address)
-	mimc.Write(c.Nonce)
-	api.AssertIsEqual(c.Com, mimc.Sum())
-	return nil
-}
+	"circuits"
+	"zkbackend"
+)
 
 // ProveInputEcdsa struct for JSON serialization of witness inputs.
 type ProveInputEcdsa struct {
 	MsgHash string `json:"msgHash"` // Hex string of the message hash
 	R       string `json:"r"`       // Hex string of signature R
 	S       string `json:"s"`       // Hex string of signature S
+	V       string `json:"v"`       // Hex string of the recovery id (0, 1, 27 or 28), the public key is then recovered
 	PubX    string `json:"pubX"`    // Hex string of public key X
 	PubY    string `json:"pubY"`    // Hex string of public key Y
 	Address string `json:"address"` // Hex string of address
 	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
 	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1 (default) or p256
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+	Scheme  string `json:"scheme"`  // Signature scheme of the key, ecdsa (default), bip340 or ed25519
+
+	ValidUntil string `json:"validUntil,omitempty"` // Hex string of the expiry of the proof, a block number or a timestamp signed with msgHash
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash    string `json:"commitHash"`    // Hash of the public key commitment, mimc or poseidon2
+	Curve         string `json:"curve"`         // Curve of the signer, secp256k1 or p256
+	LowS          bool   `json:"lowS"`          // The circuit rejects high-s signatures
+	CommitVersion int    `json:"commitVersion"` // Layout of the commitment, 0 or 1
+	Expiry        bool   `json:"expiry"`        // The proof carries a validUntil public input, signed with the message
+	Backend       string `json:"backend"`       // Proof system, plonk or groth16
+	Unsafe        bool   `json:"unsafe"`        // The secret randomness of the setup was drawn locally, for tests only
 }
 
 func main() {
+	aggregateDir := flag.String("aggregate", "", "directory where to write the proof for aggregate_proofs.go, instead of the Solidity test")
+	sig := flag.String("sig", "", "hex raw 65-byte Ethereum signature r||s||v, the public key is recovered from it")
+	backendName := flag.String("backend", "plonk", "proof system of the setup: plonk or groth16")
+	flag.Parse()
+	if *aggregateDir != "" && *backendName != "plonk" {
+		fmt.Printf("Error: only PLONK proofs are aggregated\n")
+		os.Exit(1)
+	}
+
+	// the curve of the signer selects the setup artifacts
+	var loadedProveInput ProveInputEcdsa
+	err := readFromFile("witness_input.json", &loadedProveInput)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read witness_input.json")
+	if loadedProveInput.Curve == "" {
+		loadedProveInput.Curve = "secp256k1"
+	}
+	// BIP-340 and Ed25519 keys are proven by their own circuits, with their own setups
+	switch loadedProveInput.Scheme {
+	case "", "ecdsa":
+	case "bip340":
+		fmt.Printf("Error: bip340 keys are proven with prove_schnorr_k1.go\n")
+		os.Exit(1)
+	case "ed25519":
+		fmt.Printf("Error: ed25519 keys are proven with prove_ed25519.go\n")
+		os.Exit(1)
+	default:
+		fmt.Printf("Error: unknown signature scheme %q\n", loadedProveInput.Scheme)
+		os.Exit(1)
+	}
+	curve := loadedProveInput.Curve
+
+	// with the recovery id, the public key is recovered from the signature
+	if *sig != "" {
+		err = setSignature(&loadedProveInput, *sig)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	switch curve {
+	case "secp256k1":
+		err = recoverPublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr](&loadedProveInput)
+	case "p256":
+		err = recoverPublicKey[emulated.P256Fp, emulated.P256Fr](&loadedProveInput)
+	}
+	if err != nil {
+		fmt.Printf("Error recovering the public key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// the artifacts of the backend
+	b, err := zkbackend.New(*backendName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	loadedR1CS, loadedPK, loadedVK := b.NewCS(), b.NewProvingKey(), b.NewVerifyingKey()
 
 	// 8. Test the ReadFromFile functionality
 	// 1. Read back the compiled circuit
-	loadedR1CS := plonk.NewCS(ecc.BN254)
-	err := readFromFile("r1cs.bin", loadedR1CS)
-	fmt.Printf("Read r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+	r1csName := artifactName(*backendName, curve, "r1cs.bin")
+	err = readFromFile(r1csName, loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", r1csName, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read %s (Constraints: %d)\n", r1csName, loadedR1CS.GetNbConstraints())
 
 	// 2. Read back the proving key
-	loadedPK := plonk.NewProvingKey(ecc.BN254)
-	err = readFromFile("proving_key.bin", loadedPK)
-	fmt.Println("Read proving_key.bin")
+	pkName := artifactName(*backendName, curve, "proving_key.bin")
+	err = readFromFile(pkName, loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", pkName, err)
+		os.Exit(1)
+	}
+	fmt.Println("Read", pkName)
 
 	// 3. Read back the verifying key
-	loadedVK := plonk.NewVerifyingKey(ecc.BN254)
-	err = readFromFile("verifying_key.bin", loadedVK)
-	fmt.Println("Read verifying_key.bin")
-
-	// 4. Read back the prove input JSON
-	var loadedProveInput ProveInputEcdsa
-	err = readFromFile("witness_input.json", &loadedProveInput)
-	fmt.Println("Read witness_input.json")
-
-	// Decode hex strings back to big.Int and byte slices for witness construction
-	rBytes, err := hex.DecodeString(loadedProveInput.R)
-	sBytes, err := hex.DecodeString(loadedProveInput.S)
+	vkName := artifactName(*backendName, curve, "verifying_key.bin")
+	err = readFromFile(vkName, loadedVK)
 	if err != nil {
-		fmt.Printf("Error decoding S hex: %v\n", err)
+		fmt.Printf("Error reading %s: %v\n", vkName, err)
 		os.Exit(1)
 	}
-	msgHashBytes, err := hex.DecodeString(loadedProveInput.MsgHash)
-	pubXBytes, err := hex.DecodeString(loadedProveInput.PubX)
-	pubYBytes, err := hex.DecodeString(loadedProveInput.PubY)
-	addressBytes, err := hex.DecodeString(loadedProveInput.Address)
-	nonceBytes, err := hex.DecodeString(loadedProveInput.Nonce)
-	comBytes, err := hex.DecodeString(loadedProveInput.Com)
+	fmt.Println("Read", vkName)
 
-	rLoaded := new(big.Int).SetBytes(rBytes)
-	sLoaded := new(big.Int).SetBytes(sBytes)
-	pubXLoaded := new(big.Int).SetBytes(pubXBytes)
-	pubYLoaded := new(big.Int).SetBytes(pubYBytes)
-	addressLoaded := new(big.Int).SetBytes(addressBytes)
-	nonceLoaded := new(big.Int).SetBytes(nonceBytes)
-	comLoaded := new(big.Int).SetBytes(comBytes)
+	// 4. the commitment must have been computed with the hash of the setup
+	config := SetupConfig{CommitHash: "mimc", Curve: curve, Backend: *backendName}
+	configName := artifactName(*backendName, curve, "setup_config.json")
+	if _, statErr := os.Stat(configName); statErr == nil {
+		err = readFromFile(configName, &config)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", configName, err)
+			os.Exit(1)
+		}
+	}
+	if loadedProveInput.Hash == "" {
+		loadedProveInput.Hash = "mimc"
+	}
+	if loadedProveInput.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", loadedProveInput.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if config.Curve != curve {
+		fmt.Printf("Error: signer on %s, setup uses %s\n", curve, config.Curve)
+		os.Exit(1)
+	}
+	if config.Backend != "" && config.Backend != *backendName {
+		fmt.Printf("Error: proving with %s, %s is a %s setup\n", *backendName, configName, config.Backend)
+		os.Exit(1)
+	}
+	if loadedProveInput.Version != config.CommitVersion {
+		fmt.Printf("Error: commitment version %d, setup uses version %d\n", loadedProveInput.Version, config.CommitVersion)
+		os.Exit(1)
+	}
+	if config.Expiry != (loadedProveInput.ValidUntil != "") {
+		fmt.Printf("Error: expiry of the setup %v, validUntil of witness_input.json %q\n", config.Expiry, loadedProveInput.ValidUntil)
+		os.Exit(1)
+	}
+	err = checkCommitment(loadedProveInput, config.CommitHash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: %s is a test setup, its proofs must not be trusted on-chain\n", configName)
+	}
 
 	// 5. Create a new witness using the loaded input data
-	witnessCircuitLoaded := Circuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
-		Sig: ecdsa.Signature[emulated.Secp256k1Fr]{
-			R: emulated.ValueOf[emulated.Secp256k1Fr](rLoaded),
-			S: emulated.ValueOf[emulated.Secp256k1Fr](sLoaded),
-		},
-		Msg: emulated.ValueOf[emulated.Secp256k1Fr](msgHashBytes),
-		Pub: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
-			X: emulated.ValueOf[emulated.Secp256k1Fp](pubXLoaded),
-			Y: emulated.ValueOf[emulated.Secp256k1Fp](pubYLoaded),
-		},
-		Address: addressLoaded,
-		Nonce:   nonceLoaded,
-		Com:     comLoaded,
+	var witnessFullLoaded witness.Witness
+	switch curve {
+	case "secp256k1":
+		witnessFullLoaded, err = newEcdsaWitness[emulated.Secp256k1Fp, emulated.Secp256k1Fr](loadedProveInput, config.CommitHash)
+	case "p256":
+		witnessFullLoaded, err = newEcdsaWitness[emulated.P256Fp, emulated.P256Fr](loadedProveInput, config.CommitHash)
+	default:
+		err = fmt.Errorf("unknown curve %q", curve)
+	}
+	if err != nil {
+		fmt.Printf("Error creating witness: %v\n", err)
+		os.Exit(1)
 	}
-	witnessFullLoaded, err := frontend.NewWitness(&witnessCircuitLoaded, ecc.BN254.ScalarField())
 	publicWitnessLoaded, err := witnessFullLoaded.Public()
 
 	// 6. Perform a new proof and verification using the loaded artifacts
 	fmt.Println("\n--- Proving with loaded setup ---")
 
-	// Prove
+	if *aggregateDir != "" {
+		writeAggregationProof(*aggregateDir, b, loadedR1CS, loadedPK, loadedVK, witnessFullLoaded, publicWitnessLoaded)
+		return
+	}
+
+	// Prove and verify
 	startProveLoaded := time.Now()
-	proofLoaded, err := plonk.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
+	proofLoaded, err := b.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
 	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProveLoaded).Milliseconds()))
-
-	// Verify
-	// err = plonk.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
+	err = b.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proofLoaded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// 9. Export the Solidity verifier test
 	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
-	verifierTestFile, err := os.Create("solidity/test/Verifier.t.sol")
+	verifierTestFile, err := os.Create("solidity/test/" + verifierName(*backendName, curve) + ".t.sol")
 	defer verifierTestFile.Close()
 
+	// with an expiry, validUntil follows the message in the public inputs, and
+	// the test account compares it to the timestamp
+	nbInputs := 8
+	inputsLayout := "msg (4 limbs), chainId, account, nullifier, commitment"
+	inputIndexes := "    uint256 constant NULLIFIER_INDEX = 6;\n"
+	expiryCheck, warp := "", ""
+	if config.Expiry {
+		nbInputs = 9
+		inputsLayout = "msg (4 limbs), validUntil, chainId, account, nullifier, commitment"
+		inputIndexes = "    uint256 constant VALID_UNTIL_INDEX = 4;\n    uint256 constant NULLIFIER_INDEX = 7;\n"
+		expiryCheck = "        // validUntil is a timestamp here, a block number for an account counting blocks\n        if (block.timestamp > inputs[VALID_UNTIL_INDEX]) return false;\n"
+		warp = "        vm.warp(inputs[VALID_UNTIL_INDEX]);\n"
+	}
+
+	// the PLONK verifier returns the result, the Groth16 one reverts on an
+	// invalid proof and takes the proof, its commitment and the inputs apart
+	verifierContract, testName := "PlonkVerifier", curve+"Plonk"
+	verifyCall := "        if (!ZkK1.Verify(proof, inputs)) return false;\n"
+	if *backendName == "groth16" {
+		verifierContract, testName = "Verifier", curve+"Groth16"
+		verifyCall = groth16VerifyCall(proofBytes, nbInputs)
+	}
+
 	// header
 	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
 pragma solidity ^0.8.25;
 
 import {Test, console} from "forge-std/Test.sol";
-import {PlonkVerifier} from "../src/Verifier.sol";
+import {` + verifierContract + `} from "../src/` + verifierName(*backendName, curve) + `.sol";
+
+contract ` + verifierName(*backendName, curve) + `Test is Test {
+    ` + verifierContract + ` ZkK1;
 
-contract VerifierTest is Test {
-    PlonkVerifier ZkK1;
+    // public inputs: ` + inputsLayout + `
+` + inputIndexes + `    mapping(uint256 => bool) usedNullifiers;
 
     function setUp() public {
-        ZkK1 = new PlonkVerifier();
+        ZkK1 = new ` + verifierContract + `();
+    }
+
+    // useProof accepts a proof only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+` + expiryCheck + verifyCall + `        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
     }
 
-    function test_k1Plonk() public view {
+    function test_` + testName + `() public {
 `))
 
-	Proof := proofLoaded.(*plonk_bn254.Proof)
-	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(Proof.MarshalSolidity())[2:] + `";`))
+	proofHex := hexutil.Encode(proofBytes)[2:]
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + proofHex + `";`))
 	verifierTestFile.Write([]byte("\n"))
 
 	PI := fmt.Sprintf("%v", publicWitnessLoaded.Vector())
 
-	verifierTestFile.Write([]byte("uint256[5] memory public_inputs = " + PI + ";\n"))
+	inputsDecl := fmt.Sprintf(`uint256[%d] memory public_inputs = %s;
 
-	// footer
-	verifierTestFile.Write([]byte(`
-        uint256[] memory inputs = new uint256[](5);
-        for (uint i = 0; i < 5; i++) inputs[i] = uint256(public_inputs[i]);
+        uint256[] memory inputs = new uint256[](%d);
+        for (uint i = 0; i < %d; i++) inputs[i] = uint256(public_inputs[i]);
+`, nbInputs, PI, nbInputs, nbInputs)
+	verifierTestFile.Write([]byte(inputsDecl))
 
-        bool res = ZkK1.Verify(proof, inputs);
+	// footer
+	verifierTestFile.Write([]byte(warp + `
+        bool res = useProof(proof, inputs);
         assertTrue(res);
         console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
+    }
+`))
+	if config.Expiry {
+		verifierTestFile.Write([]byte(`
+    function test_` + testName + `Expired() public {
+        bytes memory proof = hex"` + proofHex + `";
+        ` + inputsDecl + `
+        // used after its expiry, the proof is rejected
+        vm.warp(inputs[VALID_UNTIL_INDEX] + 1);
+        assertFalse(useProof(proof, inputs));
     }
-}
 `))
-	fmt.Println("Successfully exported solidty/test/Verifier.t.sol")
+	}
+	verifierTestFile.Write([]byte("}\n"))
+	fmt.Printf("Successfully exported solidity/test/%s.t.sol\n", verifierName(*backendName, curve))
 
-	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(Proof.MarshalSolidity())[2:], " \"", publicWitnessLoaded.Vector(), "\"\n")
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", proofHex, " \"", publicWitnessLoaded.Vector(), "\"\n")
+}
+
+// newEcdsaWitness builds the full witness of the circuit instantiated on the
+// curve of the signer.
+func newEcdsaWitness[T, S emulated.FieldParams](in ProveInputEcdsa, commitHash string) (witness.Witness, error) {
+	// Decode hex strings back to big.Int for witness construction
+	fields := []string{in.R, in.S, in.MsgHash, in.PubX, in.PubY, in.Address, in.Nonce, in.ChainID, in.Account, in.Com}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	r, s, msgHash, pubX, pubY := values[0], values[1], values[2], values[3], values[4]
+	normalizeS[S](s)
+	address, nonce, chainID, account, com := values[5], values[6], values[7], values[8], values[9]
+
+	// with an expiry, the signed digest is keccak256(msgHash || validUntil)
+	var validUntil []frontend.Variable
+	if in.ValidUntil != "" {
+		if _, err := signedDigest[S](in); err != nil {
+			return nil, err
+		}
+		b, err := hex.DecodeString(in.ValidUntil)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", in.ValidUntil, err)
+		}
+		validUntil = []frontend.Variable{new(big.Int).SetBytes(b)}
+	}
+
+	// the nullifier binds the proof to this message, chain and account
+	nullifier, err := circuits.ComputeNullifier(commitHash, nonce, circuits.ScalarLimbs[S](msgHash), chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := circuits.Circuit[T, S]{
+		Sig: ecdsa.Signature[S]{
+			R: emulated.ValueOf[S](r),
+			S: emulated.ValueOf[S](s),
+		},
+		Msg:        emulated.ValueOf[S](msgHash),
+		ValidUntil: validUntil,
+		Pub: ecdsa.PublicKey[T, S]{
+			X: emulated.ValueOf[T](pubX),
+			Y: emulated.ValueOf[T](pubY),
+		},
+		Address:   address,
+		Nonce:     nonce,
+		ChainID:   chainID,
+		Account:   account,
+		Nullifier: nullifier,
+		Com:       com,
+
+		CommitHash: commitHash,
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// signedDigest returns the digest signed by the key: the message hash, or
+// keccak256(msgHash || validUntil) for a proof that expires.
+func signedDigest[S emulated.FieldParams](in ProveInputEcdsa) (*big.Int, error) {
+	msg, err := hex.DecodeString(in.MsgHash)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex %q: %w", in.MsgHash, err)
+	}
+	msgHash := new(big.Int).SetBytes(msg)
+	if in.ValidUntil == "" {
+		return msgHash, nil
+	}
+	b, err := hex.DecodeString(in.ValidUntil)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex %q: %w", in.ValidUntil, err)
+	}
+	validUntil := new(big.Int).SetBytes(b)
+	if validUntil.BitLen() > 64 {
+		return nil, fmt.Errorf("validUntil %s does not fit in 64 bits", in.ValidUntil)
+	}
+	// the circuit hashes the message read from the limbs of the reduced scalar
+	var fr S
+	if msgHash.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("the digest %x is not reduced modulo the group order, it cannot expire", msgHash)
+	}
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(msgHash.FillBytes(make([]byte, 32)))
+	keccak.Write(validUntil.FillBytes(make([]byte, 32)))
+	return new(big.Int).SetBytes(keccak.Sum(nil)), nil
+}
+
+// normalizeS replaces s by n - s when s > (n-1)/2. (r, n - s) is also a valid
+// signature, so the witness is the same for both forms of the signature.
+func normalizeS[S emulated.FieldParams](s *big.Int) {
+	var fr S
+	if s.Cmp(new(big.Int).Rsh(fr.Modulus(), 1)) > 0 {
+		s.Sub(fr.Modulus(), s)
+	}
+}
+
+// setSignature splits a raw 65-byte Ethereum signature r||s||v into the
+// signature fields of the input.
+func setSignature(in *ProveInputEcdsa, sig string) error {
+	raw, err := hex.DecodeString(strings.TrimPrefix(sig, "0x"))
+	if err != nil {
+		return fmt.Errorf("decoding signature hex: %w", err)
+	}
+	if len(raw) != 65 {
+		return fmt.Errorf("the signature is %d bytes long, r||s||v (65 bytes) is expected", len(raw))
+	}
+	in.R = hex.EncodeToString(raw[:32])
+	in.S = hex.EncodeToString(raw[32:64])
+	in.V = hex.EncodeToString(raw[64:])
+	return nil
+}
+
+// recoverPublicKey sets the public key of the input to the one recovered from
+// the signature and the message hash, as ecrecover does, and checks that it is
+// the key of the committed address. The input is unchanged when v is empty.
+func recoverPublicKey[T, S emulated.FieldParams](in *ProveInputEcdsa) error {
+	if in.V == "" {
+		return nil
+	}
+	fields := []string{in.R, in.S, in.V, in.Address}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	r, s, v, address := values[0], values[1], values[2], values[3]
+	msgHash, err := signedDigest[S](*in)
+	if err != nil {
+		return err
+	}
+
+	// Ethereum signatures carry v = 27 + recovery id
+	if v.Cmp(big.NewInt(27)) >= 0 {
+		v.Sub(v, big.NewInt(27))
+	}
+	if v.Cmp(big.NewInt(3)) > 0 {
+		return fmt.Errorf("invalid recovery id %s", in.V)
+	}
+	x, y, err := ecRecover[T, S](msgHash, r, s, uint(v.Uint64()))
+	if err != nil {
+		return err
+	}
+
+	pubX := x.FillBytes(make([]byte, 32))
+	pubY := y.FillBytes(make([]byte, 32))
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(pubX)
+	keccak.Write(pubY)
+	recovered := keccak.Sum(nil)[12:]
+	if new(big.Int).SetBytes(recovered).Cmp(address) != 0 {
+		return fmt.Errorf("the signature is from address %x, the commitment is to address %s", recovered, in.Address)
+	}
+	in.PubX = hex.EncodeToString(pubX)
+	in.PubY = hex.EncodeToString(pubY)
+	return nil
+}
+
+// ecRecover returns the public key Q = r^-1 (s R - z G) of an ECDSA signature,
+// R being the point of x-coordinate r + (v>>1) n and of parity v&1, and z the
+// message hash reduced modulo n, as in the circuit.
+func ecRecover[T, S emulated.FieldParams](msgHash, r, s *big.Int, v uint) (*big.Int, *big.Int, error) {
+	var fp T
+	var fr S
+	p, n := fp.Modulus(), fr.Modulus()
+	params := sw_emulated.GetCurveParams[T]()
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf("r and s must be in [1, n-1]")
+	}
+
+	rx := new(big.Int).Set(r)
+	if v>>1 == 1 {
+		rx.Add(rx, n)
+	}
+	if rx.Cmp(p) >= 0 {
+		return nil, nil, fmt.Errorf("r + n is not a coordinate of the curve")
+	}
+	// y^2 = x^3 + ax + b
+	y2 := new(big.Int).Exp(rx, big.NewInt(3), p)
+	y2.Add(y2, new(big.Int).Mul(params.A, rx))
+	y2.Add(y2, params.B).Mod(y2, p)
+	ry := new(big.Int).ModSqrt(y2, p)
+	if ry == nil {
+		return nil, nil, fmt.Errorf("r is not the x-coordinate of a point of the curve")
+	}
+	if ry.Bit(0) != v&1 {
+		ry.Sub(p, ry)
+	}
+
+	rInv := new(big.Int).ModInverse(r, n)
+	u1 := new(big.Int).Mod(msgHash, n)
+	u1.Mul(u1, rInv).Neg(u1).Mod(u1, n)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, n)
+	x1, y1 := scalarMul(params.A, p, params.Gx, params.Gy, u1)
+	x2, y2 := scalarMul(params.A, p, rx, ry, u2)
+	qx, qy := pointAdd(params.A, p, x1, y1, x2, y2)
+	if qx == nil {
+		return nil, nil, fmt.Errorf("the recovered key is the point at infinity")
+	}
+	return qx, qy, nil
+}
+
+// pointAdd adds two affine points of y^2 = x^3 + ax + b over F_p, the point at
+// infinity being nil.
+func pointAdd(a, p, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	lambda := new(big.Int)
+	if x1.Cmp(x2) == 0 {
+		sum := new(big.Int).Add(y1, y2)
+		if sum.Mod(sum, p).Sign() == 0 {
+			return nil, nil
+		}
+		// (3x^2 + a) / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3)).Add(num, a)
+		den := new(big.Int).Lsh(y1, 1)
+		lambda.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	} else {
+		// (y2 - y1) / (x2 - x1)
+		den := new(big.Int).Sub(x2, x1)
+		lambda.Mul(new(big.Int).Sub(y2, y1), den.ModInverse(den.Mod(den, p), p))
+	}
+	lambda.Mod(lambda, p)
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, x1).Sub(x, x2).Mod(x, p)
+	y := new(big.Int).Sub(x1, x)
+	y.Mul(y, lambda).Sub(y, y1).Mod(y, p)
+	return x, y
+}
+
+// scalarMul computes k(x, y) by double-and-add.
+func scalarMul(a, p, x, y, k *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		rx, ry = pointAdd(a, p, rx, ry, rx, ry)
+		if k.Bit(i) == 1 {
+			rx, ry = pointAdd(a, p, rx, ry, x, y)
+		}
+	}
+	return rx, ry
+}
+
+// writeAggregationProof proves again with the transcript hash of the
+// aggregation circuit, and writes the proof and its public witness in dir,
+// named after the nullifier. These proofs are not accepted by Verifier.sol.
+func writeAggregationProof(dir string, b zkbackend.Backend, ccs constraint.ConstraintSystem, pk zkbackend.ProvingKey, vk zkbackend.VerifyingKey, fullWitness, publicWitness witness.Witness) {
+	field := ecc.BN254.ScalarField()
+	proof, err := b.Prove(ccs, pk, fullWitness, recursion_plonk.GetNativeProverOptions(field, field))
+	if err != nil {
+		fmt.Printf("Error generating proof for aggregation: %v\n", err)
+		os.Exit(1)
+	}
+	err = b.Verify(proof, vk, publicWitness, recursion_plonk.GetNativeVerifierOptions(field, field))
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		fmt.Printf("Error creating %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+	publicInputs := publicWitness.Vector().(fr.Vector)
+	name := publicInputs[len(publicInputs)-2].Text(16) // nullifier
+	writeToFile(filepath.Join(dir, name+".proof"), proof)
+	writeToFile(filepath.Join(dir, name+".pub"), publicWitness)
+}
+
+// groth16VerifyCall decodes a proof of zkbackend.Groth16.MarshalProof and
+// calls Verifier.verifyProof, whose arguments depend on the number of
+// commitments of the circuit: 8 words without commitment, otherwise 2 words
+// per commitment and 2 for their proof of knowledge after the 8 words.
+func groth16VerifyCall(proofBytes []byte, nbInputs int) string {
+	call := fmt.Sprintf("        uint256[%d] memory input;\n        for (uint i = 0; i < %d; i++) input[i] = inputs[i];\n", nbInputs, nbInputs)
+	if len(proofBytes) == 8*32 {
+		return "        uint256[8] memory p = abi.decode(proof, (uint256[8]));\n" + call +
+			"        try ZkK1.verifyProof(p, input) {} catch { return false; }\n"
+	}
+	nbWords := (len(proofBytes) - 10*32) / 32
+	return fmt.Sprintf(`        (uint256[8] memory p, uint256[%d] memory commitments, uint256[2] memory commitmentPok) =
+            abi.decode(proof, (uint256[8], uint256[%d], uint256[2]));
+`, nbWords, nbWords) + call + "        try ZkK1.verifyProof(p, commitments, commitmentPok, input) {} catch { return false; }\n"
+}
+
+// artifactName returns the file name of a setup artifact for the backend and
+// the signer curve. The secp256k1 PLONK artifacts keep their historical names,
+// the Groth16 artifacts are prefixed so that they are never mixed with them.
+func artifactName(backendName, curve, name string) string {
+	if curve != "" && curve != "secp256k1" {
+		name = curve + "_" + name
+	}
+	if backendName == "groth16" {
+		name = "groth16_" + name
+	}
+	return name
+}
+
+// verifierName returns the name of the Solidity verifier for the backend and
+// the signer curve.
+func verifierName(backendName, curve string) string {
+	name := "Verifier"
+	if curve == "p256" {
+		name = "P256Verifier"
+	}
+	if backendName == "groth16" {
+		name = "Groth16" + name
+	}
+	return name
+}
+
+// checkCommitment recomputes natively the commitment of the input, so that a
+// commitment made for another chain, account or layout is reported before proving.
+func checkCommitment(in ProveInputEcdsa, commitHash string) error {
+	h, err := circuits.NewNativeCommitmentHasher(commitHash)
+	if err != nil {
+		return err
+	}
+	if in.Version == 1 {
+		// h(tag, chainId, account, address, nonce)
+		prefix := []*big.Int{new(big.Int).SetBytes([]byte(circuits.CommitTagV1))}
+		for _, field := range []string{in.ChainID, in.Account} {
+			b, err := hex.DecodeString(field)
+			if err != nil {
+				return fmt.Errorf("decoding hex %q: %w", field, err)
+			}
+			prefix = append(prefix, new(big.Int).SetBytes(b))
+		}
+		for _, v := range prefix {
+			h.Write(v.FillBytes(make([]byte, 32)))
+		}
+	}
+	for _, field := range []string{in.Address, in.Nonce} {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		h.Write(b)
+	}
+	com, err := hex.DecodeString(in.Com)
+	if err != nil {
+		return fmt.Errorf("decoding hex %q: %w", in.Com, err)
+	}
+	if new(big.Int).SetBytes(h.Sum(nil)).Cmp(new(big.Int).SetBytes(com)) != 0 {
+		if in.Version == 1 {
+			return fmt.Errorf("the commitment does not open to the address, the nonce, the chain id %s and the account %s", in.ChainID, in.Account)
+		}
+		return fmt.Errorf("the commitment does not open to the address and the nonce")
+	}
+	return nil
 }
 
 // writeToFile is a helper to serialize and write gnark objects or byte readers to files.
@@ -228,6 +743,12 @@ func readFromFile(filename string, data interface{}) error {
 		if err != nil {
 			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
 		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
 	default:
 		return fmt.Errorf("unsupported type for reading from file: %T", data)
 	}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptosha3 "golang.org/x/crypto/sha3"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+
+	"circuits"
+	"zkbackend"
+)
+
+// ProveInputEcdsa struct for JSON serialization of witness inputs.
+type ProveInputEcdsa struct {
+	MsgHash string `json:"msgHash"` // Hex string of the message hash
+	R       string `json:"r"`       // Hex string of signature R, the encoded nonce point
+	S       string `json:"s"`       // Hex string of signature S, little-endian
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, ed25519
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+	Scheme  string `json:"scheme"`  // Signature scheme of the key, ecdsa (default), bip340 or ed25519
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash    string `json:"commitHash"`    // Hash of the public key commitment, mimc or poseidon2
+	CommitVersion int    `json:"commitVersion"` // Layout of the commitment, 0 or 1
+	Backend       string `json:"backend"`       // Proof system, plonk
+	Unsafe        bool   `json:"unsafe"`        // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	sig := flag.String("sig", "", "hex 64-byte Ed25519 signature R||S, instead of the r and s of witness_input.json")
+	flag.Parse()
+
+	var in ProveInputEcdsa
+	err := readFromFile("witness_input.json", &in)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read witness_input.json")
+	if in.Scheme != "ed25519" {
+		fmt.Printf("Error: witness_input.json is not an Ed25519 key, commit with pub_commit.go -scheme ed25519\n")
+		os.Exit(1)
+	}
+	if *sig != "" {
+		raw, err := hex.DecodeString(strings.TrimPrefix(*sig, "0x"))
+		if err != nil || len(raw) != 64 {
+			fmt.Printf("Error: -sig must be a 64-byte hex signature R||S\n")
+			os.Exit(1)
+		}
+		in.R = hex.EncodeToString(raw[:32])
+		in.S = hex.EncodeToString(raw[32:])
+	}
+	err = verifyEd25519(in)
+	if err != nil {
+		fmt.Printf("Error: invalid Ed25519 signature: %v\n", err)
+		os.Exit(1)
+	}
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc"}
+	if _, statErr := os.Stat("ed25519_setup_config.json"); statErr == nil {
+		err = readFromFile("ed25519_setup_config.json", &config)
+		if err != nil {
+			fmt.Printf("Error reading ed25519_setup_config.json: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: ed25519_setup_config.json is a test setup, its proofs must not be trusted on-chain\n")
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile("ed25519_r1cs.bin", loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading ed25519_r1cs.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read ed25519_r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile("ed25519_proving_key.bin", loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading ed25519_proving_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read ed25519_proving_key.bin")
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile("ed25519_verifying_key.bin", loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading ed25519_verifying_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read ed25519_verifying_key.bin")
+
+	// 4. the commitment must have been computed with the options of the setup
+	if in.Hash == "" {
+		in.Hash = "mimc"
+	}
+	if in.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", in.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if in.Version != config.CommitVersion {
+		fmt.Printf("Error: commitment version %d, setup uses version %d\n", in.Version, config.CommitVersion)
+		os.Exit(1)
+	}
+	err = checkCommitment(in, config.CommitHash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Create the witness
+	witnessFull, err := newEd25519Witness(in, config.CommitHash)
+	if err != nil {
+		fmt.Printf("Error creating witness: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitness, err := witnessFull.Public()
+	if err != nil {
+		fmt.Printf("Error creating public witness: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Prove and verify
+	fmt.Println("\n--- Proving Ed25519 signature ---")
+	startProve := time.Now()
+	proof, err := b.Prove(loadedR1CS, loadedPK, witnessFull)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
+
+	err = b.Verify(proof, loadedVK, publicWitness)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proof)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Verification SUCCEEDED")
+
+	// 7. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	publicInputs := fmt.Sprintf("%v", publicWitness.Vector())
+	verifierTestFile, err := os.Create("solidity/test/Ed25519Verifier.t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/Ed25519Verifier.t.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/Ed25519Verifier.sol";
+
+contract Ed25519VerifierTest is Test {
+    PlonkVerifier ZkEd25519;
+
+    // public inputs: msg (4 limbs), chainId, account, nullifier, commitment
+    uint256 constant NULLIFIER_INDEX = 6;
+    mapping(uint256 => bool) usedNullifiers;
+
+    function setUp() public {
+        ZkEd25519 = new PlonkVerifier();
+    }
+
+    // useProof accepts a proof only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+        if (!ZkEd25519.Verify(proof, inputs)) return false;
+        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
+    }
+
+    function test_Ed25519() public {
+        bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";
+        uint256[8] memory public_inputs = ` + publicInputs + `;
+
+        uint256[] memory inputs = new uint256[](8);
+        for (uint i = 0; i < 8; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = useProof(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
+    }
+}
+`))
+	fmt.Println("Successfully exported solidity/test/Ed25519Verifier.t.sol")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitness.Vector(), "\"\n")
+}
+
+// newEd25519Witness builds the full witness of the Ed25519 circuit.
+func newEd25519Witness(in ProveInputEcdsa, commitHash string) (witness.Witness, error) {
+	// Decode hex strings back to big.Int for witness construction
+	fields := []string{in.MsgHash, in.PubX, in.PubY, in.Address, in.Nonce, in.ChainID, in.Account, in.Com}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	msgHash, pubX, pubY := values[0], values[1], values[2]
+	address, nonce, chainID, account, com := values[3], values[4], values[5], values[6], values[7]
+
+	// the nonce point is decoded, s is little-endian
+	rBytes, err := hex.DecodeString(in.R)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex %q: %w", in.R, err)
+	}
+	rx, ry, err := decodeEd25519Point(rBytes)
+	if err != nil {
+		return nil, fmt.Errorf("decoding R: %w", err)
+	}
+	sBytes, err := hex.DecodeString(in.S)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex %q: %w", in.S, err)
+	}
+	s := new(big.Int)
+	for i := len(sBytes) - 1; i >= 0; i-- {
+		s.Lsh(s, 8).Or(s, big.NewInt(int64(sBytes[i])))
+	}
+
+	// the circuit reads the signed message from the limbs of the public
+	// message of Circuit, reduced modulo the secp256k1 group order
+	var fr emulated.Secp256k1Fr
+	if msgHash.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("the digest %x is not reduced modulo the secp256k1 group order", msgHash)
+	}
+
+	// the nullifier binds the proof to this message, chain and account
+	nullifier, err := circuits.ComputeNullifier(commitHash, nonce, circuits.ScalarLimbs[emulated.Secp256k1Fr](msgHash), chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := circuits.Ed25519Circuit{
+		Sig: circuits.Ed25519Signature{
+			R: circuits.Ed25519Point{X: emulated.ValueOf[circuits.Ed25519Fp](rx), Y: emulated.ValueOf[circuits.Ed25519Fp](ry)},
+			S: emulated.ValueOf[circuits.Ed25519Fr](s),
+		},
+		Msg:       emulated.ValueOf[emulated.Secp256k1Fr](msgHash),
+		Pub:       circuits.Ed25519Point{X: emulated.ValueOf[circuits.Ed25519Fp](pubX), Y: emulated.ValueOf[circuits.Ed25519Fp](pubY)},
+		Address:   address,
+		Nonce:     nonce,
+		ChainID:   chainID,
+		Account:   account,
+		Nullifier: nullifier,
+		Com:       com,
+
+		CommitHash: commitHash,
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// verifyEd25519 verifies natively the Ed25519 signature of the message hash,
+// and checks that the committed address is the one of the key.
+func verifyEd25519(in ProveInputEcdsa) error {
+	fields := []string{in.R, in.S, in.MsgHash, in.PubX, in.PubY}
+	raw := make([][]byte, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil || len(b) != 32 {
+			return fmt.Errorf("r, s, msgHash, pubX and pubY must be 32-byte hex strings")
+		}
+		raw[i] = b
+	}
+	pub := encodeEd25519Point(new(big.Int).SetBytes(raw[3]), new(big.Int).SetBytes(raw[4]))
+	x, y, err := decodeEd25519Point(pub)
+	if err != nil || x.Cmp(new(big.Int).SetBytes(raw[3])) != 0 || y.Cmp(new(big.Int).SetBytes(raw[4])) != 0 {
+		return fmt.Errorf("the public key is not a point of Ed25519")
+	}
+	if !ed25519.Verify(pub, raw[2], append(raw[0], raw[1]...)) {
+		return fmt.Errorf("the signature does not verify for the key %x", pub)
+	}
+
+	// keccak256 of the encoded key, as in-circuit
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(pub)
+	address, err := hex.DecodeString(in.Address)
+	if err != nil {
+		return fmt.Errorf("decoding hex %q: %w", in.Address, err)
+	}
+	if new(big.Int).SetBytes(keccak.Sum(nil)[12:]).Cmp(new(big.Int).SetBytes(address)) != 0 {
+		return fmt.Errorf("the commitment is to address %s, not to the key %x", in.Address, pub)
+	}
+	return nil
+}
+
+// encodeEd25519Point returns the 32-byte encoding of the point (x, y): y
+// little-endian, the top bit holding the parity of x.
+func encodeEd25519Point(x, y *big.Int) []byte {
+	be := new(big.Int).Set(y)
+	if x.Bit(0) == 1 {
+		be.SetBit(be, 255, 1)
+	}
+	enc := be.FillBytes(make([]byte, 32))
+	for i, j := 0, len(enc)-1; i < j; i, j = i+1, j-1 {
+		enc[i], enc[j] = enc[j], enc[i]
+	}
+	return enc
+}
+
+// decodeEd25519Point decodes the 32-byte encoding of an Ed25519 point, y
+// little-endian and the parity of x in the top bit, and refuses a non
+// canonical encoding.
+func decodeEd25519Point(enc []byte) (*big.Int, *big.Int, error) {
+	if len(enc) != 32 {
+		return nil, nil, fmt.Errorf("an Ed25519 point is encoded in 32 bytes")
+	}
+	var fp circuits.Ed25519Fp
+	p := fp.Modulus()
+	be := make([]byte, 32)
+	for i := range enc {
+		be[31-i] = enc[i]
+	}
+	y := new(big.Int).SetBytes(be)
+	sign := y.Bit(255)
+	y.SetBit(y, 255, 0)
+	if y.Cmp(p) >= 0 {
+		return nil, nil, fmt.Errorf("non canonical encoding of y")
+	}
+
+	// x^2 = (y^2 - 1) / (d y^2 + 1)
+	d, _ := new(big.Int).SetString(circuits.Ed25519D, 10)
+	yy := new(big.Int).Mul(y, y)
+	u := new(big.Int).Sub(yy, big.NewInt(1))
+	v := new(big.Int).Mul(d, yy)
+	v.Add(v, big.NewInt(1)).Mod(v, p)
+	xx := new(big.Int).Mul(u, v.ModInverse(v, p))
+	x := new(big.Int).ModSqrt(xx.Mod(xx, p), p)
+	if x == nil {
+		return nil, nil, fmt.Errorf("not the encoding of a point of Ed25519")
+	}
+	if x.Sign() == 0 && sign == 1 {
+		return nil, nil, fmt.Errorf("non canonical encoding of x = 0")
+	}
+	if x.Bit(0) != sign {
+		x.Sub(p, x)
+	}
+	return x, y, nil
+}
+
+// checkCommitment recomputes natively the commitment of the input, so that a
+// commitment made for another chain, account or layout is reported before proving.
+func checkCommitment(in ProveInputEcdsa, commitHash string) error {
+	h, err := circuits.NewNativeCommitmentHasher(commitHash)
+	if err != nil {
+		return err
+	}
+	if in.Version == 1 {
+		// h(tag, chainId, account, address, nonce)
+		prefix := []*big.Int{new(big.Int).SetBytes([]byte(circuits.CommitTagV1))}
+		for _, field := range []string{in.ChainID, in.Account} {
+			b, err := hex.DecodeString(field)
+			if err != nil {
+				return fmt.Errorf("decoding hex %q: %w", field, err)
+			}
+			prefix = append(prefix, new(big.Int).SetBytes(b))
+		}
+		for _, v := range prefix {
+			h.Write(v.FillBytes(make([]byte, 32)))
+		}
+	}
+	for _, field := range []string{in.Address, in.Nonce} {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		h.Write(b)
+	}
+	com, err := hex.DecodeString(in.Com)
+	if err != nil {
+		return fmt.Errorf("decoding hex %q: %w", in.Com, err)
+	}
+	if new(big.Int).SetBytes(h.Sum(nil)).Cmp(new(big.Int).SetBytes(com)) != 0 {
+		if in.Version == 1 {
+			return fmt.Errorf("the commitment does not open to the address, the nonce, the chain id %s and the account %s", in.ChainID, in.Account)
+		}
+		return fmt.Errorf("the commitment does not open to the address and the nonce")
+	}
+	return nil
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *ProveInputEcdsa: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+	cryptosha3 "golang.org/x/crypto/sha3"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"zkbackend"
+)
+
+// ProveInputEcdsa struct for JSON deserialization of witness_input.json.
+type ProveInputEcdsa struct {
+	MsgHash   string `json:"msgHash"`   // Hex string of msgHash
+	R         string `json:"r"`         // Hex string of r
+	S         string `json:"s"`         // Hex string of s
+	PubX      string `json:"pubX"`      // Hex string of public key X
+	PubY      string `json:"pubY"`      // Hex string of public key Y
+	Address   string `json:"address"`   // Hex string of address
+	Nonce     string `json:"nonce"`     // Hex string of nonce
+	ChainID   string `json:"chainId"`   // Hex string of the chain id of the account
+	Account   string `json:"account"`   // Hex string of the account using the proof
+	Com       string `json:"com"`       // Hex string of Com
+	Hash      string `json:"hash"`      // Hash of the commitment, mimc or poseidon2
+	Curve     string `json:"curve"`     // Curve of the signer, secp256k1 (default) or p256
+	Version   int    `json:"version"`   // Layout of the commitment, 0 or 1
+	FalconKey string `json:"falconKey"` // Hex string of the hash of the committed Falcon public key
+}
+
+// FalconInput struct for JSON deserialization of the Falcon half of the hybrid signature.
+type FalconInput struct {
+	Pk string `json:"pk"` // Hex string of the Falcon-512 public key, NIST encoding
+	Sm string `json:"sm"` // Hex string of the signed message, NIST KAT format
+}
+
+// SetupConfig struct for JSON deserialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"` // Hash of the public key commitment, mimc or poseidon2
+	Curve      string `json:"curve"`      // Curve of the signer, secp256k1 or p256
+	LowS       bool   `json:"lowS"`       // The circuit rejects high-s signatures
+	Backend    string `json:"backend"`    // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`     // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	falconFile := flag.String("falcon", "falcon_signature.json", "Falcon half of the hybrid signature, {pk, sm}")
+	flag.Parse()
+
+	var in ProveInputEcdsa
+	err := readFromFile("witness_input.json", &in)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	if in.Version != 0 || in.FalconKey == "" {
+		fmt.Printf("Error: witness_input.json is not a hybrid commitment, commit with pub_commit.go -falcon %s\n", *falconFile)
+		os.Exit(1)
+	}
+	fmt.Println("Read witness_input.json")
+	var falconIn FalconInput
+	err = readFromFile(*falconFile, &falconIn)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", *falconFile, err)
+		os.Exit(1)
+	}
+	if in.Curve == "" {
+		in.Curve = "secp256k1"
+	}
+	curve := in.Curve
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc", Curve: curve}
+	configName := artifactName(curve, "hybrid_setup_config.json")
+	if _, statErr := os.Stat(configName); statErr == nil {
+		err = readFromFile(configName, &config)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", configName, err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: %s is a test setup, its proofs must not be trusted on-chain\n", configName)
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile(artifactName(curve, "hybrid_r1cs.bin"), loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "hybrid_r1cs.bin"), err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read %s (Constraints: %d)\n", artifactName(curve, "hybrid_r1cs.bin"), loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile(artifactName(curve, "hybrid_proving_key.bin"), loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "hybrid_proving_key.bin"), err)
+		os.Exit(1)
+	}
+	fmt.Println("Read", artifactName(curve, "hybrid_proving_key.bin"))
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile(artifactName(curve, "hybrid_verifying_key.bin"), loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "hybrid_verifying_key.bin"), err)
+		os.Exit(1)
+	}
+	fmt.Println("Read", artifactName(curve, "hybrid_verifying_key.bin"))
+
+	// 4. the commitment must have been computed with the hash of the setup
+	if in.Hash == "" {
+		in.Hash = "mimc"
+	}
+	if in.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", in.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if config.Curve != curve {
+		fmt.Printf("Error: signer on %s, setup uses %s\n", curve, config.Curve)
+		os.Exit(1)
+	}
+
+	// 5. Check natively the Falcon half, the witness would not satisfy the circuit otherwise
+	falconSig, err := decodeFalconInput(falconIn)
+	if err != nil {
+		fmt.Printf("Error decoding %s: %v\n", *falconFile, err)
+		os.Exit(1)
+	}
+	msgHash, err := hex.DecodeString(in.MsgHash)
+	if err != nil {
+		fmt.Printf("Error decoding msgHash hex: %v\n", err)
+		os.Exit(1)
+	}
+	if !bytes.Equal(falconSig.msg, msgHash) {
+		fmt.Printf("Error: the Falcon signature is of the message %x, the ECDSA signature of %s\n", falconSig.msg, in.MsgHash)
+		os.Exit(1)
+	}
+	err = verifyFalcon(falconSig)
+	if err != nil {
+		fmt.Printf("Error: invalid Falcon signature: %v\n", err)
+		os.Exit(1)
+	}
+	keyHash, err := falconKeyHash(config.CommitHash, falconSig.h)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	committedKey, err := hex.DecodeString(in.FalconKey)
+	if err != nil || keyHash.Cmp(new(big.Int).SetBytes(committedKey)) != 0 {
+		fmt.Printf("Error: the Falcon public key of %s is not the committed one\n", *falconFile)
+		os.Exit(1)
+	}
+
+	// 6. Create the witness
+	var witnessFull witness.Witness
+	switch curve {
+	case "secp256k1":
+		witnessFull, err = newHybridWitness[emulated.Secp256k1Fp, emulated.Secp256k1Fr](in, falconSig, config.CommitHash)
+	case "p256":
+		witnessFull, err = newHybridWitness[emulated.P256Fp, emulated.P256Fr](in, falconSig, config.CommitHash)
+	default:
+		err = fmt.Errorf("unknown curve %q", curve)
+	}
+	if err != nil {
+		fmt.Printf("Error creating witness: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitness, err := witnessFull.Public()
+	if err != nil {
+		fmt.Printf("Error creating public witness: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 7. Prove and verify
+	fmt.Println("\n--- Proving hybrid signature ---")
+	startProve := time.Now()
+	proof, err := b.Prove(loadedR1CS, loadedPK, witnessFull)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
+
+	err = b.Verify(proof, loadedVK, publicWitness)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proof)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Verification SUCCEEDED")
+
+	// 8. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	publicInputs := fmt.Sprintf("%v", publicWitness.Vector())
+	name := hybridVerifierName(curve)
+	verifierTestFile, err := os.Create("solidity/test/" + name + ".t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/%s.t.sol: %v\n", name, err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/` + name + `.sol";
+
+contract ` + name + `Test is Test {
+    PlonkVerifier ZkHybrid;
+
+    // public inputs: msg (4 limbs), chainId, account, nullifier, commitment
+    uint256 constant NULLIFIER_INDEX = 6;
+    mapping(uint256 => bool) usedNullifiers;
+
+    function setUp() public {
+        ZkHybrid = new PlonkVerifier();
+    }
+
+    // useProof accepts a proof only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+        if (!ZkHybrid.Verify(proof, inputs)) return false;
+        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
+    }
+
+    function test_` + curve + `Hybrid() public {
+        bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";
+        uint256[8] memory public_inputs = ` + publicInputs + `;
+
+        uint256[] memory inputs = new uint256[](8);
+        for (uint i = 0; i < 8; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = useProof(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
+    }
+}
+`))
+	fmt.Printf("Successfully exported solidity/test/%s.t.sol\n", name)
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitness.Vector(), "\"\n")
+}
+
+// newHybridWitness builds the full witness of the hybrid circuit instantiated
+// on the curve of the signer.
+func newHybridWitness[T, S emulated.FieldParams](in ProveInputEcdsa, sig *falconSignature, commitHash string) (witness.Witness, error) {
+	// Decode hex strings back to big.Int for witness construction
+	fields := []string{in.R, in.S, in.MsgHash, in.PubX, in.PubY, in.Address, in.Nonce, in.ChainID, in.Account, in.Com}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	r, s, msgHash, pubX, pubY := values[0], values[1], values[2], values[3], values[4]
+	normalizeS[S](s)
+	address, nonce, chainID, account, com := values[5], values[6], values[7], values[8], values[9]
+
+	// the circuit reads the Falcon message from the limbs of the reduced digest
+	var fr S
+	if msgHash.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("the digest %x is not reduced modulo the group order", msgHash)
+	}
+
+	// h(address, nonce, h(falcon key))
+	keyHash, err := falconKeyHash(commitHash, sig.h)
+	if err != nil {
+		return nil, err
+	}
+	h, err := circuits.NewNativeCommitmentHasher(commitHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range []*big.Int{address, nonce, keyHash} {
+		h.Write(v.FillBytes(make([]byte, 32)))
+	}
+	if new(big.Int).SetBytes(h.Sum(nil)).Cmp(com) != 0 {
+		return nil, fmt.Errorf("the commitment does not open to the address, the nonce and the Falcon key")
+	}
+
+	// the nullifier binds the proof to this message, chain and account
+	nullifier, err := circuits.ComputeNullifier(commitHash, nonce, circuits.ScalarLimbs[S](msgHash), chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := circuits.HybridCircuit[T, S]{
+		Sig: ecdsa.Signature[S]{
+			R: emulated.ValueOf[S](r),
+			S: emulated.ValueOf[S](s),
+		},
+		Msg: emulated.ValueOf[S](msgHash),
+		Pub: ecdsa.PublicKey[T, S]{
+			X: emulated.ValueOf[T](pubX),
+			Y: emulated.ValueOf[T](pubY),
+		},
+		Address:   address,
+		Nonce:     nonce,
+		ChainID:   chainID,
+		Account:   account,
+		Nullifier: nullifier,
+		Com:       com,
+
+		CommitHash: commitHash,
+	}
+	for i := 0; i < circuits.FalconN; i++ {
+		assignment.FalconPub[i] = sig.h[i]
+		assignment.S2[i] = (sig.s2[i]%circuits.FalconQ + circuits.FalconQ) % circuits.FalconQ
+	}
+	for i := 0; i < circuits.FalconSalt; i++ {
+		assignment.Salt[i] = sig.salt[i]
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// falconSignature is a decoded Falcon-512 signature with its public key.
+type falconSignature struct {
+	h    []uint64 // public key, coefficients modulo q
+	salt []byte   // 40-byte salt
+	msg  []byte   // signed message
+	s2   []int64  // second half of the signature, centered coefficients
+}
+
+// decodeFalconInput decodes the public key from its NIST encoding, 0x09
+// followed by the 14-bit coefficients, and the signature from the NIST KAT
+// signed message: the 2-byte length of the signature, the salt, the message,
+// then 0x29 followed by the compressed s2.
+func decodeFalconInput(in FalconInput) (*falconSignature, error) {
+	pk, err := hex.DecodeString(in.Pk)
+	if err != nil {
+		return nil, fmt.Errorf("decoding pk hex: %w", err)
+	}
+	sm, err := hex.DecodeString(in.Sm)
+	if err != nil {
+		return nil, fmt.Errorf("decoding sm hex: %w", err)
+	}
+	h, err := decodeFalconPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	if len(sm) < 2+circuits.FalconSalt+1 {
+		return nil, fmt.Errorf("the signed message is too short")
+	}
+	slen := int(sm[0])<<8 | int(sm[1])
+	mlen := len(sm) - slen - 2 - circuits.FalconSalt
+	if slen < 1 || mlen < 0 {
+		return nil, fmt.Errorf("invalid signature length %d", slen)
+	}
+	esig := sm[2+circuits.FalconSalt+mlen:]
+	if esig[0] != 0x29 {
+		return nil, fmt.Errorf("the signature header is %#x, 0x29 is expected", esig[0])
+	}
+	s2, err := decompressFalcon(esig[1:])
+	if err != nil {
+		return nil, err
+	}
+	return &falconSignature{
+		h:    h,
+		salt: sm[2 : 2+circuits.FalconSalt],
+		msg:  sm[2+circuits.FalconSalt : 2+circuits.FalconSalt+mlen],
+		s2:   s2,
+	}, nil
+}
+
+// decodeFalconPublicKey decodes the NIST encoding of a Falcon-512 public key.
+func decodeFalconPublicKey(pk []byte) ([]uint64, error) {
+	if len(pk) != 1+circuits.FalconN*14/8 || pk[0] != 0x09 {
+		return nil, fmt.Errorf("not a Falcon-512 public key")
+	}
+	h := make([]uint64, 0, circuits.FalconN)
+	var acc uint64
+	accLen := 0
+	for _, b := range pk[1:] {
+		acc = acc<<8 | uint64(b)
+		accLen += 8
+		if accLen >= 14 {
+			accLen -= 14
+			w := (acc >> accLen) & 0x3fff
+			if w >= circuits.FalconQ {
+				return nil, fmt.Errorf("public key coefficient %d is not reduced modulo q", w)
+			}
+			h = append(h, w)
+		}
+	}
+	return h, nil
+}
+
+// decompressFalcon decodes the compressed s2: for each coefficient a sign bit,
+// the 7 low bits, and the high bits in unary. The encoding must use all the
+// bytes, with zero padding bits.
+func decompressFalcon(buf []byte) ([]int64, error) {
+	pos := 0
+	readBit := func() (uint64, error) {
+		if pos >= 8*len(buf) {
+			return 0, fmt.Errorf("truncated signature")
+		}
+		bit := uint64(buf[pos/8]>>(7-pos%8)) & 1
+		pos++
+		return bit, nil
+	}
+	s2 := make([]int64, circuits.FalconN)
+	for i := range s2 {
+		var head uint64
+		for j := 0; j < 8; j++ {
+			bit, err := readBit()
+			if err != nil {
+				return nil, err
+			}
+			head = head<<1 | bit
+		}
+		sign, m := head>>7, int64(head&0x7f)
+		for {
+			bit, err := readBit()
+			if err != nil {
+				return nil, err
+			}
+			if bit == 1 {
+				break
+			}
+			m += 128
+			if m > 2047 {
+				return nil, fmt.Errorf("signature coefficient out of range")
+			}
+		}
+		if sign == 1 && m == 0 {
+			return nil, fmt.Errorf("negative zero in the signature")
+		}
+		if sign == 1 {
+			m = -m
+		}
+		s2[i] = m
+	}
+	for ; pos < 8*len(buf); pos++ {
+		if pos%8 == 0 {
+			return nil, fmt.Errorf("trailing bytes after the signature")
+		}
+		if buf[pos/8]>>(7-pos%8)&1 != 0 {
+			return nil, fmt.Errorf("non-zero padding bits in the signature")
+		}
+	}
+	return s2, nil
+}
+
+// verifyFalcon verifies natively the Falcon-512 signature, with the same
+// hash to point as the circuit.
+func verifyFalcon(sig *falconSignature) error {
+	shake := cryptosha3.NewShake256()
+	shake.Write(sig.salt)
+	shake.Write(sig.msg)
+	c := make([]int64, 0, circuits.FalconN)
+	sample := make([]byte, 2)
+	for samples := 0; len(c) < circuits.FalconN; samples++ {
+		if samples == circuits.FalconSamples {
+			return fmt.Errorf("the hash to point needs more than %d samples", circuits.FalconSamples)
+		}
+		shake.Read(sample)
+		t := int64(sample[0])<<8 | int64(sample[1])
+		if t < 5*circuits.FalconQ {
+			c = append(c, t%circuits.FalconQ)
+		}
+	}
+
+	// s1 = c - s2 * h mod (q, x^512 + 1)
+	s1 := append([]int64{}, c...)
+	for i, a := range sig.s2 {
+		for j, b := range sig.h {
+			p := a * int64(b) % circuits.FalconQ
+			if i+j < circuits.FalconN {
+				s1[i+j] -= p
+			} else {
+				s1[i+j-circuits.FalconN] += p
+			}
+		}
+	}
+	var norm int64
+	for i := range s1 {
+		v := (s1[i]%circuits.FalconQ + circuits.FalconQ) % circuits.FalconQ
+		if v > circuits.FalconQ/2 {
+			v -= circuits.FalconQ
+		}
+		norm += v*v + sig.s2[i]*sig.s2[i]
+	}
+	if norm > circuits.FalconBound {
+		return fmt.Errorf("squared norm %d above %d", norm, circuits.FalconBound)
+	}
+	return nil
+}
+
+// falconKeyHash hashes the Falcon public key, its coefficients being packed
+// by circuits.FalconPacking in field elements as in-circuit.
+func falconKeyHash(name string, h []uint64) (*big.Int, error) {
+	kh, err := circuits.NewNativeCommitmentHasher(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(h); i += circuits.FalconPacking {
+		v := new(big.Int)
+		for j := min(i+circuits.FalconPacking, len(h)) - 1; j >= i; j-- {
+			v.Lsh(v, 14).Add(v, new(big.Int).SetUint64(h[j]))
+		}
+		kh.Write(v.FillBytes(make([]byte, 32)))
+	}
+	return new(big.Int).SetBytes(kh.Sum(nil)), nil
+}
+
+// normalizeS replaces s by n - s when s > (n-1)/2. (r, n - s) is also a valid
+// signature, so the witness is the same for both forms of the signature.
+func normalizeS[S emulated.FieldParams](s *big.Int) {
+	var fr S
+	if s.Cmp(new(big.Int).Rsh(fr.Modulus(), 1)) > 0 {
+		s.Sub(fr.Modulus(), s)
+	}
+}
+
+// artifactName returns the file name of a setup artifact for the signer curve.
+// The secp256k1 artifacts keep their historical names.
+func artifactName(curve, name string) string {
+	if curve == "" || curve == "secp256k1" {
+		return name
+	}
+	return curve + "_" + name
+}
+
+// hybridVerifierName returns the name of the Solidity hybrid verifier for the
+// signer curve.
+func hybridVerifierName(curve string) string {
+	if curve == "p256" {
+		return "P256HybridVerifier"
+	}
+	return "HybridVerifier"
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *ProveInputEcdsa, *FalconInput: // For the JSON inputs
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"zkbackend"
+)
+
+// ProveInputEcdsa struct for JSON serialization of witness inputs.
+type ProveInputEcdsa struct {
+	MsgHash string `json:"msgHash"` // Hex string of the message hash
+	R       string `json:"r"`       // Hex string of signature R
+	S       string `json:"s"`       // Hex string of signature S
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+}
+
+// MemberPath struct for JSON serialization of the inclusion path of a member.
+type MemberPath struct {
+	Com   string   `json:"com"`   // Hex string of the member commitment
+	Index int      `json:"index"` // Position of the leaf in the tree
+	Path  []string `json:"path"`  // Hex strings of the siblings, from the leaf up to the root
+}
+
+// MembershipTree struct for JSON serialization of the Merkle tree of commitments.
+type MembershipTree struct {
+	Hash    string       `json:"hash"`    // Hash of the commitments and of the tree, mimc or poseidon2
+	Depth   int          `json:"depth"`   // Depth of the tree
+	Root    string       `json:"root"`    // Hex string of the Merkle root, public input of the proof
+	Members []MemberPath `json:"members"` // Inclusion paths of the members
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"`               // Hash of the public key commitment, mimc or poseidon2
+	TreeDepth  int    `json:"circuits.MembershipDepth"` // Depth of the Merkle tree of commitments
+	Backend    string `json:"backend"`                  // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`                   // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc", TreeDepth: circuits.MembershipDepth}
+	if _, statErr := os.Stat("membership_setup_config.json"); statErr == nil {
+		err := readFromFile("membership_setup_config.json", &config)
+		if err != nil {
+			fmt.Printf("Error reading membership_setup_config.json: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: membership_setup_config.json is a test setup, its proofs must not be trusted on-chain\n")
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile("membership_r1cs.bin", loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading membership_r1cs.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read membership_r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile("membership_proving_key.bin", loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading membership_proving_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read membership_proving_key.bin")
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile("membership_verifying_key.bin", loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading membership_verifying_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read membership_verifying_key.bin")
+
+	// 4. Read back the prove input JSON and the tree
+	var loadedProveInput ProveInputEcdsa
+	err = readFromFile("witness_input.json", &loadedProveInput)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read witness_input.json")
+
+	var tree MembershipTree
+	err = readFromFile("membership_tree.json", &tree)
+	if err != nil {
+		fmt.Printf("Error reading membership_tree.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read membership_tree.json")
+
+	// the tree must have been built with the hash and depth of the setup
+	if loadedProveInput.Hash == "" {
+		loadedProveInput.Hash = "mimc"
+	}
+	if loadedProveInput.Hash != config.CommitHash || tree.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s and tree with %s, setup uses %s\n", loadedProveInput.Hash, tree.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if tree.Depth != config.TreeDepth {
+		fmt.Printf("Error: tree of depth %d, setup uses %d\n", tree.Depth, config.TreeDepth)
+		os.Exit(1)
+	}
+
+	// the inclusion path of the signer
+	comLoaded := mustDecodeHex("Com", loadedProveInput.Com)
+	var member *MemberPath
+	for i := range tree.Members {
+		if mustDecodeHex("Com", tree.Members[i].Com).Cmp(comLoaded) == 0 {
+			member = &tree.Members[i]
+			break
+		}
+	}
+	if member == nil {
+		fmt.Println("Error: the commitment of witness_input.json is not in membership_tree.json")
+		os.Exit(1)
+	}
+	if len(member.Path) != tree.Depth {
+		fmt.Printf("Error: inclusion path of length %d, expected %d\n", len(member.Path), tree.Depth)
+		os.Exit(1)
+	}
+
+	msgHashBytes, err := hex.DecodeString(loadedProveInput.MsgHash)
+	if err != nil {
+		fmt.Printf("Error decoding MsgHash hex: %v\n", err)
+		os.Exit(1)
+	}
+	nonceLoaded := mustDecodeHex("Nonce", loadedProveInput.Nonce)
+	chainIDLoaded := mustDecodeHex("ChainID", loadedProveInput.ChainID)
+	accountLoaded := mustDecodeHex("Account", loadedProveInput.Account)
+
+	// the nullifier binds the proof to this message, chain and account
+	nullifier, err := circuits.ComputeNullifier(config.CommitHash, nonceLoaded, circuits.ScalarLimbs[emulated.Secp256k1Fr](new(big.Int).SetBytes(msgHashBytes)), chainIDLoaded, accountLoaded)
+	if err != nil {
+		fmt.Printf("Error computing nullifier: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Create a new witness using the loaded input data
+	witnessCircuitLoaded := circuits.NewMembershipCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr](tree.Depth)
+	witnessCircuitLoaded.CommitHash = config.CommitHash
+	witnessCircuitLoaded.Sig = ecdsa.Signature[emulated.Secp256k1Fr]{
+		R: emulated.ValueOf[emulated.Secp256k1Fr](mustDecodeHex("R", loadedProveInput.R)),
+		S: emulated.ValueOf[emulated.Secp256k1Fr](mustDecodeHex("S", loadedProveInput.S)),
+	}
+	witnessCircuitLoaded.Msg = emulated.ValueOf[emulated.Secp256k1Fr](msgHashBytes)
+	witnessCircuitLoaded.Pub = ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+		X: emulated.ValueOf[emulated.Secp256k1Fp](mustDecodeHex("PubX", loadedProveInput.PubX)),
+		Y: emulated.ValueOf[emulated.Secp256k1Fp](mustDecodeHex("PubY", loadedProveInput.PubY)),
+	}
+	witnessCircuitLoaded.Address = mustDecodeHex("Address", loadedProveInput.Address)
+	witnessCircuitLoaded.Nonce = nonceLoaded
+	witnessCircuitLoaded.Path[0] = comLoaded
+	for i, sibling := range member.Path {
+		witnessCircuitLoaded.Path[i+1] = mustDecodeHex("Path", sibling)
+	}
+	witnessCircuitLoaded.Index = member.Index
+	witnessCircuitLoaded.ChainID = chainIDLoaded
+	witnessCircuitLoaded.Account = accountLoaded
+	witnessCircuitLoaded.Nullifier = nullifier
+	witnessCircuitLoaded.Root = mustDecodeHex("Root", tree.Root)
+
+	witnessFullLoaded, err := frontend.NewWitness(&witnessCircuitLoaded, ecc.BN254.ScalarField())
+	if err != nil {
+		fmt.Printf("Error creating full witness from loaded data: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitnessLoaded, err := witnessFullLoaded.Public()
+	if err != nil {
+		fmt.Printf("Error getting public witness from loaded data: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Perform a new proof and verification using the loaded artifacts
+	fmt.Println("\n--- Proving with loaded setup ---")
+
+	// Prove
+	startProveLoaded := time.Now()
+	proofLoaded, err := b.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProveLoaded).Milliseconds()))
+
+	// Verify
+	err = b.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proofLoaded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 7. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	verifierTestFile, err := os.Create("solidity/test/MembershipVerifier.t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/MembershipVerifier.t.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	// header
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/MembershipVerifier.sol";
+
+contract MembershipVerifierTest is Test {
+    PlonkVerifier ZkK1;
+
+    // public inputs: msg (4 limbs), chainId, account, nullifier, root
+    uint256 constant NULLIFIER_INDEX = 6;
+    mapping(uint256 => bool) usedNullifiers;
+
+    function setUp() public {
+        ZkK1 = new PlonkVerifier();
+    }
+
+    // useProof accepts a proof only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+        if (!ZkK1.Verify(proof, inputs)) return false;
+        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
+    }
+
+    function test_k1MembershipPlonk() public {
+`))
+
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";`))
+	verifierTestFile.Write([]byte("\n"))
+
+	PI := fmt.Sprintf("%v", publicWitnessLoaded.Vector())
+
+	verifierTestFile.Write([]byte("uint256[8] memory public_inputs = " + PI + ";\n"))
+
+	// footer
+	verifierTestFile.Write([]byte(`
+        uint256[] memory inputs = new uint256[](8);
+        for (uint i = 0; i < 8; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = useProof(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
+    }
+}
+`))
+	fmt.Println("Successfully exported solidity/test/MembershipVerifier.t.sol")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitnessLoaded.Vector(), "\"\n")
+}
+
+// mustDecodeHex decodes a hex string of the witness file into a big.Int.
+func mustDecodeHex(name, s string) *big.Int {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		fmt.Printf("Error decoding %s hex: %v\n", name, err)
+		os.Exit(1)
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *ProveInputEcdsa: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *MembershipTree: // For the tree of commitments
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
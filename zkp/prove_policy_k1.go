@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"encoding/hex"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/uints"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"circuits/eip1559"
+	"zkbackend"
+)
+
+// ProveInputTransaction struct for JSON serialization of EIP-1559 witness inputs.
+type ProveInputTransaction struct {
+	Tx       string `json:"tx"`       // Hex string of the unsigned transaction 0x02 || rlp(fields)
+	MsgHash  string `json:"msgHash"`  // Hex string of keccak256(tx), the signed digest
+	R        string `json:"r"`        // Hex string of signature R
+	S        string `json:"s"`        // Hex string of signature S
+	PubX     string `json:"pubX"`     // Hex string of public key X
+	PubY     string `json:"pubY"`     // Hex string of public key Y
+	Address  string `json:"address"`  // Hex string of address
+	Nonce    string `json:"nonce"`    // Hex string of nonce
+	To       string `json:"to"`       // Hex string of the destination
+	Value    string `json:"value"`    // Hex string of the value
+	Selector string `json:"selector"` // Hex string of the selector, 0 without calldata
+	ChainID  string `json:"chainId"`  // Hex string of the chain id of the transaction and of the account
+	Account  string `json:"account"`  // Hex string of the account using the proof
+	Com      string `json:"com"`      // Hex string of Com
+	Hash     string `json:"hash"`     // Hash of the commitment, mimc or poseidon2
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"` // Hash of the public key commitment, mimc or poseidon2
+	Curve      string `json:"curve"`      // Curve of the signer, secp256k1 for transactions
+	Backend    string `json:"backend"`    // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`     // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	capFlag := flag.String("cap", "", "maximum value of the user role in wei, and amount of its token transfers and approvals (decimal)")
+	toFlag := flag.String("to", "", "destination allowed to the user role (hex)")
+	selectorFlag := flag.String("selector", "", "function selector allowed to the user role (hex), empty for a transfer without calldata")
+	flag.Parse()
+
+	// the cap is a public input, the account checks it against its own limit
+	userCap, ok := new(big.Int).SetString(*capFlag, 10)
+	if !ok || userCap.Sign() < 0 {
+		fmt.Printf("Error: -cap must be a decimal amount of wei\n")
+		os.Exit(1)
+	}
+	// the destination and the selector are public inputs too
+	userTo, ok := new(big.Int).SetString(strings.TrimPrefix(*toFlag, "0x"), 16)
+	if !ok || userTo.BitLen() > 160 {
+		fmt.Printf("Error: -to must be a hex address\n")
+		os.Exit(1)
+	}
+	userSelector := new(big.Int)
+	if *selectorFlag != "" {
+		userSelector, ok = new(big.Int).SetString(strings.TrimPrefix(*selectorFlag, "0x"), 16)
+		if !ok || userSelector.BitLen() > 32 {
+			fmt.Printf("Error: -selector must be a hex 4-byte selector\n")
+			os.Exit(1)
+		}
+	}
+	// the circuit only checks the arguments of the ERC-20 transfer and approve
+	isToken := userSelector.Cmp(big.NewInt(eip1559.TransferSelector)) == 0 || userSelector.Cmp(big.NewInt(eip1559.ApproveSelector)) == 0
+	if userSelector.Sign() != 0 && !isToken {
+		fmt.Printf("Error: -selector must be empty, a9059cbb (transfer) or 095ea7b3 (approve)\n")
+		os.Exit(1)
+	}
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc"}
+	if _, statErr := os.Stat("policy_setup_config.json"); statErr == nil {
+		err := readFromFile("policy_setup_config.json", &config)
+		if err != nil {
+			fmt.Printf("Error reading policy_setup_config.json: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: policy_setup_config.json is a test setup, its proofs must not be trusted on-chain\n")
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile("policy_r1cs.bin", loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading policy_r1cs.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read policy_r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile("policy_proving_key.bin", loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading policy_proving_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read policy_proving_key.bin")
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile("policy_verifying_key.bin", loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading policy_verifying_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read policy_verifying_key.bin")
+
+	// 4. Read back the prove input JSON
+	var loadedProveInput ProveInputTransaction
+	err = readFromFile("eip1559_witness_input.json", &loadedProveInput)
+	if err != nil {
+		fmt.Printf("Error reading eip1559_witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read eip1559_witness_input.json")
+
+	// the commitment must have been computed with the hash of the setup
+	if loadedProveInput.Hash == "" {
+		loadedProveInput.Hash = "mimc"
+	}
+	if loadedProveInput.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", loadedProveInput.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+
+	unsignedTx, err := hex.DecodeString(loadedProveInput.Tx)
+	if err != nil || len(unsignedTx) > eip1559.MaxTxLen {
+		fmt.Printf("Error: tx must be at most %d hex encoded bytes\n", eip1559.MaxTxLen)
+		os.Exit(1)
+	}
+	digest, err := hex.DecodeString(loadedProveInput.MsgHash)
+	if err != nil || len(digest) != 32 {
+		fmt.Printf("Error: msgHash must be a hex encoded 32-byte digest\n")
+		os.Exit(1)
+	}
+	msgLimbs := circuits.DigestLimbs(digest)
+	nonceLoaded := mustDecodeHex("Nonce", loadedProveInput.Nonce)
+	chainIDLoaded := mustDecodeHex("ChainID", loadedProveInput.ChainID)
+	accountLoaded := mustDecodeHex("Account", loadedProveInput.Account)
+
+	value := mustDecodeHex("Value", loadedProveInput.Value)
+	if value.Cmp(userCap) > 0 {
+		fmt.Printf("Error: the value %s of the transaction exceeds the cap %s of the user role\n", value, userCap)
+		os.Exit(1)
+	}
+	if mustDecodeHex("To", loadedProveInput.To).Cmp(userTo) != 0 {
+		fmt.Printf("Error: the transaction is sent to %s, the user role is allowed %x\n", loadedProveInput.To, userTo)
+		os.Exit(1)
+	}
+	if mustDecodeHex("Selector", loadedProveInput.Selector).Cmp(userSelector) != 0 {
+		fmt.Printf("Error: the transaction calls the selector %s, the user role is allowed %x\n", loadedProveInput.Selector, userSelector)
+		os.Exit(1)
+	}
+	if isToken {
+		// the amount of the token, the second argument, is under the cap too
+		var fields []rlp.RawValue
+		var data []byte
+		if len(unsignedTx) == 0 || rlp.DecodeBytes(unsignedTx[1:], &fields) != nil || len(fields) != 9 || rlp.DecodeBytes(fields[7], &data) != nil {
+			fmt.Printf("Error: tx is not an unsigned EIP-1559 transaction\n")
+			os.Exit(1)
+		}
+		if len(data) < 4+32+32 {
+			fmt.Printf("Error: the calldata of the token call is %d bytes long, without its amount\n", len(data))
+			os.Exit(1)
+		}
+		amount := new(big.Int).SetBytes(data[4+32 : 4+32+32])
+		if amount.BitLen() > 248 || amount.Cmp(userCap) > 0 {
+			fmt.Printf("Error: the token amount %s of the transaction exceeds the cap %s of the user role\n", amount, userCap)
+			os.Exit(1)
+		}
+	}
+
+	// the nullifier binds the proof to this transaction, chain and account
+	nullifier, err := circuits.ComputeNullifier(config.CommitHash, nonceLoaded, msgLimbs, chainIDLoaded, accountLoaded)
+	if err != nil {
+		fmt.Printf("Error computing nullifier: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Create a new witness using the loaded input data
+	witnessCircuitLoaded := circuits.PolicyCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+		Sig: ecdsa.Signature[emulated.Secp256k1Fr]{
+			R: emulated.ValueOf[emulated.Secp256k1Fr](mustDecodeHex("R", loadedProveInput.R)),
+			S: emulated.ValueOf[emulated.Secp256k1Fr](mustDecodeHex("S", loadedProveInput.S)),
+		},
+		Pub: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](mustDecodeHex("PubX", loadedProveInput.PubX)),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](mustDecodeHex("PubY", loadedProveInput.PubY)),
+		},
+		TxLen:     len(unsignedTx),
+		Address:   mustDecodeHex("Address", loadedProveInput.Address),
+		Nonce:     nonceLoaded,
+		Value:     value,
+		Cap:       userCap,
+		To:        userTo,
+		Selector:  userSelector,
+		ChainID:   chainIDLoaded,
+		Account:   accountLoaded,
+		Nullifier: nullifier,
+		Com:       mustDecodeHex("Com", loadedProveInput.Com),
+
+		CommitHash: config.CommitHash,
+	}
+	for i := range witnessCircuitLoaded.Tx {
+		witnessCircuitLoaded.Tx[i] = uints.NewU8(0)
+		if i < len(unsignedTx) {
+			witnessCircuitLoaded.Tx[i] = uints.NewU8(unsignedTx[i])
+		}
+	}
+
+	witnessFullLoaded, err := frontend.NewWitness(&witnessCircuitLoaded, ecc.BN254.ScalarField())
+	if err != nil {
+		fmt.Printf("Error creating full witness from loaded data: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitnessLoaded, err := witnessFullLoaded.Public()
+	if err != nil {
+		fmt.Printf("Error getting public witness from loaded data: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Perform a new proof and verification using the loaded artifacts
+	fmt.Println("\n--- Proving with loaded setup ---")
+
+	// Prove
+	startProveLoaded := time.Now()
+	proofLoaded, err := b.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProveLoaded).Milliseconds()))
+
+	// Verify
+	err = b.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proofLoaded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 7. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	verifierTestFile, err := os.Create("solidity/test/PolicyVerifier.t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/PolicyVerifier.t.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	// header
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/PolicyVerifier.sol";
+
+contract PolicyVerifierTest is Test {
+    PlonkVerifier ZkR1;
+
+    // public inputs: cap, to, selector, chainId, account, nullifier, commitment
+    uint256 constant CAP_INDEX = 0;
+    uint256 constant TO_INDEX = 1;
+    uint256 constant SELECTOR_INDEX = 2;
+    uint256 constant NULLIFIER_INDEX = 5;
+    mapping(uint256 => bool) usedNullifiers;
+
+    // policy of the user role, set by the account
+    uint256 constant USER_CAP = ` + userCap.String() + `;
+    uint256 constant USER_TO = ` + userTo.String() + `;
+    uint256 constant USER_SELECTOR = 0x` + fmt.Sprintf("%08x", userSelector) + `;
+
+    function setUp() public {
+        ZkR1 = new PlonkVerifier();
+    }
+
+    // useProof accepts a proof of the policy of the user role only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (inputs[CAP_INDEX] != USER_CAP) return false;
+        if (inputs[TO_INDEX] != USER_TO || inputs[SELECTOR_INDEX] != USER_SELECTOR) return false;
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+        if (!ZkR1.Verify(proof, inputs)) return false;
+        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
+    }
+
+    function test_policyPlonk() public {
+`))
+
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";`))
+	verifierTestFile.Write([]byte("\n"))
+
+	PI := fmt.Sprintf("%v", publicWitnessLoaded.Vector())
+
+	verifierTestFile.Write([]byte("uint256[7] memory public_inputs = " + PI + ";\n"))
+
+	// footer
+	verifierTestFile.Write([]byte(`
+        uint256[] memory inputs = new uint256[](7);
+        for (uint i = 0; i < 7; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = useProof(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
+    }
+}
+`))
+	fmt.Println("Successfully exported solidity/test/PolicyVerifier.t.sol")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitnessLoaded.Vector(), "\"\n")
+}
+
+// mustDecodeHex decodes a hex string of the witness file into a big.Int.
+func mustDecodeHex(name, s string) *big.Int {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		fmt.Printf("Error decoding %s hex: %v\n", name, err)
+		os.Exit(1)
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *ProveInputTransaction: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
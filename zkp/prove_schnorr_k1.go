@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"zkbackend"
+)
+
+// ProveInputEcdsa struct for JSON serialization of witness inputs.
+type ProveInputEcdsa struct {
+	MsgHash string `json:"msgHash"` // Hex string of the message hash
+	R       string `json:"r"`       // Hex string of signature R, the x coordinate of the nonce point
+	S       string `json:"s"`       // Hex string of signature S
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y, even
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+	Scheme  string `json:"scheme"`  // Signature scheme of the key, ecdsa (default) or bip340
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash    string `json:"commitHash"`    // Hash of the public key commitment, mimc or poseidon2
+	CommitVersion int    `json:"commitVersion"` // Layout of the commitment, 0 or 1
+	Backend       string `json:"backend"`       // Proof system, plonk
+	Unsafe        bool   `json:"unsafe"`        // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	sig := flag.String("sig", "", "hex 64-byte BIP-340 signature r||s, instead of the r and s of witness_input.json")
+	flag.Parse()
+
+	var in ProveInputEcdsa
+	err := readFromFile("witness_input.json", &in)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read witness_input.json")
+	if in.Scheme != "bip340" {
+		fmt.Printf("Error: witness_input.json is not a BIP-340 key, commit with pub_commit.go -scheme bip340\n")
+		os.Exit(1)
+	}
+	if *sig != "" {
+		raw, err := hex.DecodeString(strings.TrimPrefix(*sig, "0x"))
+		if err != nil || len(raw) != 64 {
+			fmt.Printf("Error: -sig must be a 64-byte hex signature r||s\n")
+			os.Exit(1)
+		}
+		in.R = hex.EncodeToString(raw[:32])
+		in.S = hex.EncodeToString(raw[32:])
+	}
+	err = verifyBIP340(in)
+	if err != nil {
+		fmt.Printf("Error: invalid BIP-340 signature: %v\n", err)
+		os.Exit(1)
+	}
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc"}
+	if _, statErr := os.Stat("schnorr_setup_config.json"); statErr == nil {
+		err = readFromFile("schnorr_setup_config.json", &config)
+		if err != nil {
+			fmt.Printf("Error reading schnorr_setup_config.json: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: schnorr_setup_config.json is a test setup, its proofs must not be trusted on-chain\n")
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile("schnorr_r1cs.bin", loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading schnorr_r1cs.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read schnorr_r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile("schnorr_proving_key.bin", loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading schnorr_proving_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read schnorr_proving_key.bin")
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile("schnorr_verifying_key.bin", loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading schnorr_verifying_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read schnorr_verifying_key.bin")
+
+	// 4. the commitment must have been computed with the options of the setup
+	if in.Hash == "" {
+		in.Hash = "mimc"
+	}
+	if in.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", in.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if in.Version != config.CommitVersion {
+		fmt.Printf("Error: commitment version %d, setup uses version %d\n", in.Version, config.CommitVersion)
+		os.Exit(1)
+	}
+	err = checkCommitment(in, config.CommitHash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Create the witness
+	witnessFull, err := newSchnorrWitness(in, config.CommitHash)
+	if err != nil {
+		fmt.Printf("Error creating witness: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitness, err := witnessFull.Public()
+	if err != nil {
+		fmt.Printf("Error creating public witness: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Prove and verify
+	fmt.Println("\n--- Proving BIP-340 signature ---")
+	startProve := time.Now()
+	proof, err := b.Prove(loadedR1CS, loadedPK, witnessFull)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
+
+	err = b.Verify(proof, loadedVK, publicWitness)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proof)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Verification SUCCEEDED")
+
+	// 7. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	publicInputs := fmt.Sprintf("%v", publicWitness.Vector())
+	verifierTestFile, err := os.Create("solidity/test/SchnorrVerifier.t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/SchnorrVerifier.t.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/SchnorrVerifier.sol";
+
+contract SchnorrVerifierTest is Test {
+    PlonkVerifier ZkSchnorr;
+
+    // public inputs: msg (4 limbs), chainId, account, nullifier, commitment
+    uint256 constant NULLIFIER_INDEX = 6;
+    mapping(uint256 => bool) usedNullifiers;
+
+    function setUp() public {
+        ZkSchnorr = new PlonkVerifier();
+    }
+
+    // useProof accepts a proof only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+        if (!ZkSchnorr.Verify(proof, inputs)) return false;
+        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
+    }
+
+    function test_Schnorr() public {
+        bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";
+        uint256[8] memory public_inputs = ` + publicInputs + `;
+
+        uint256[] memory inputs = new uint256[](8);
+        for (uint i = 0; i < 8; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = useProof(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
+    }
+}
+`))
+	fmt.Println("Successfully exported solidity/test/SchnorrVerifier.t.sol")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitness.Vector(), "\"\n")
+}
+
+// newSchnorrWitness builds the full witness of the BIP-340 circuit.
+func newSchnorrWitness(in ProveInputEcdsa, commitHash string) (witness.Witness, error) {
+	// Decode hex strings back to big.Int for witness construction
+	fields := []string{in.R, in.S, in.MsgHash, in.PubX, in.PubY, in.Address, in.Nonce, in.ChainID, in.Account, in.Com}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	r, s, msgHash, pubX, pubY := values[0], values[1], values[2], values[3], values[4]
+	address, nonce, chainID, account, com := values[5], values[6], values[7], values[8], values[9]
+
+	// the circuit reads the signed message from the limbs of the reduced digest
+	var fr emulated.Secp256k1Fr
+	if msgHash.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("the digest %x is not reduced modulo the group order", msgHash)
+	}
+
+	// the nullifier binds the proof to this message, chain and account
+	nullifier, err := circuits.ComputeNullifier(commitHash, nonce, circuits.ScalarLimbs[emulated.Secp256k1Fr](msgHash), chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := circuits.SchnorrCircuit{
+		Sig: circuits.SchnorrSignature{
+			R: emulated.ValueOf[emulated.Secp256k1Fp](r),
+			S: emulated.ValueOf[emulated.Secp256k1Fr](s),
+		},
+		Msg: emulated.ValueOf[emulated.Secp256k1Fr](msgHash),
+		Pub: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](pubX),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](pubY),
+		},
+		Address:   address,
+		Nonce:     nonce,
+		ChainID:   chainID,
+		Account:   account,
+		Nullifier: nullifier,
+		Com:       com,
+
+		CommitHash: commitHash,
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// verifyBIP340 verifies natively the BIP-340 signature of the input, as the
+// circuit does.
+func verifyBIP340(in ProveInputEcdsa) error {
+	fields := []string{in.R, in.S, in.MsgHash, in.PubX, in.PubY}
+	raw := make([][]byte, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil || len(b) != 32 {
+			return fmt.Errorf("r, s, msgHash, pubX and pubY must be 32-byte hex strings")
+		}
+		raw[i] = b
+	}
+	var fp emulated.Secp256k1Fp
+	var fr emulated.Secp256k1Fr
+	p, n := fp.Modulus(), fr.Modulus()
+	params := sw_emulated.GetSecp256k1Params()
+	r, s := new(big.Int).SetBytes(raw[0]), new(big.Int).SetBytes(raw[1])
+	px, py := new(big.Int).SetBytes(raw[3]), new(big.Int).SetBytes(raw[4])
+	if r.Sign() == 0 || r.Cmp(p) >= 0 || s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return fmt.Errorf("r or s out of range")
+	}
+	if py.Bit(0) != 0 {
+		return fmt.Errorf("the public key must have an even y")
+	}
+
+	// e = sha256(tag || tag || r || x(P) || m) mod n
+	tag := sha256.Sum256([]byte(circuits.ChallengeTag))
+	challenge := sha256.New()
+	challenge.Write(tag[:])
+	challenge.Write(tag[:])
+	challenge.Write(raw[0])
+	challenge.Write(raw[3])
+	challenge.Write(raw[2])
+	e := new(big.Int).SetBytes(challenge.Sum(nil))
+	e.Mod(e, n)
+
+	// R = [s]G - [e]P
+	sx, sy := scalarMul(params.A, p, params.Gx, params.Gy, s)
+	ex, ey := scalarMul(params.A, p, px, py, new(big.Int).Sub(n, e))
+	rx, ry := pointAdd(params.A, p, sx, sy, ex, ey)
+	if rx == nil || ry.Bit(0) != 0 || rx.Cmp(r) != 0 {
+		return fmt.Errorf("the signature does not verify for the key %s", in.PubX)
+	}
+	return nil
+}
+
+// pointAdd adds two affine points of y^2 = x^3 + ax + b over F_p, the point at
+// infinity being nil.
+func pointAdd(a, p, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	lambda := new(big.Int)
+	if x1.Cmp(x2) == 0 {
+		sum := new(big.Int).Add(y1, y2)
+		if sum.Mod(sum, p).Sign() == 0 {
+			return nil, nil
+		}
+		// (3x^2 + a) / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3)).Add(num, a)
+		den := new(big.Int).Lsh(y1, 1)
+		lambda.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	} else {
+		// (y2 - y1) / (x2 - x1)
+		den := new(big.Int).Sub(x2, x1)
+		lambda.Mul(new(big.Int).Sub(y2, y1), den.ModInverse(den.Mod(den, p), p))
+	}
+	lambda.Mod(lambda, p)
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, x1).Sub(x, x2).Mod(x, p)
+	y := new(big.Int).Sub(x1, x)
+	y.Mul(y, lambda).Sub(y, y1).Mod(y, p)
+	return x, y
+}
+
+// scalarMul computes k(x, y) by double-and-add.
+func scalarMul(a, p, x, y, k *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		rx, ry = pointAdd(a, p, rx, ry, rx, ry)
+		if k.Bit(i) == 1 {
+			rx, ry = pointAdd(a, p, rx, ry, x, y)
+		}
+	}
+	return rx, ry
+}
+
+// checkCommitment recomputes natively the commitment of the input, so that a
+// commitment made for another chain, account or layout is reported before proving.
+func checkCommitment(in ProveInputEcdsa, commitHash string) error {
+	h, err := circuits.NewNativeCommitmentHasher(commitHash)
+	if err != nil {
+		return err
+	}
+	if in.Version == 1 {
+		// h(tag, chainId, account, address, nonce)
+		prefix := []*big.Int{new(big.Int).SetBytes([]byte(circuits.CommitTagV1))}
+		for _, field := range []string{in.ChainID, in.Account} {
+			b, err := hex.DecodeString(field)
+			if err != nil {
+				return fmt.Errorf("decoding hex %q: %w", field, err)
+			}
+			prefix = append(prefix, new(big.Int).SetBytes(b))
+		}
+		for _, v := range prefix {
+			h.Write(v.FillBytes(make([]byte, 32)))
+		}
+	}
+	for _, field := range []string{in.Address, in.Nonce} {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		h.Write(b)
+	}
+	com, err := hex.DecodeString(in.Com)
+	if err != nil {
+		return fmt.Errorf("decoding hex %q: %w", in.Com, err)
+	}
+	if new(big.Int).SetBytes(h.Sum(nil)).Cmp(new(big.Int).SetBytes(com)) != 0 {
+		if in.Version == 1 {
+			return fmt.Errorf("the commitment does not open to the address, the nonce, the chain id %s and the account %s", in.ChainID, in.Account)
+		}
+		return fmt.Errorf("the commitment does not open to the address and the nonce")
+	}
+	return nil
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *ProveInputEcdsa: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
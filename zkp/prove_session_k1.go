@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptosha3 "golang.org/x/crypto/sha3"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"zkbackend"
+)
+
+// ProveInputEcdsa struct for JSON serialization of witness inputs.
+type ProveInputEcdsa struct {
+	MsgHash string `json:"msgHash"` // Hex string of the message hash
+	R       string `json:"r"`       // Hex string of signature R
+	S       string `json:"s"`       // Hex string of signature S
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+	Scheme  string `json:"scheme"`  // Signature scheme of the key, ecdsa
+}
+
+// SessionInput struct for JSON deserialization of the delegation to the session
+// key and of the operation it signed.
+type SessionInput struct {
+	SessionPubX string `json:"sessionPubX"` // Hex string of the session key X
+	SessionPubY string `json:"sessionPubY"` // Hex string of the session key Y
+	ValidUntil  string `json:"validUntil"`  // Hex string of the expiry of the session key, a block number or a timestamp
+	Scope       string `json:"scope"`       // Hex string of the scope of the session key
+	DelegationR string `json:"delegationR"` // Hex string of R of the delegation, signed by the committed key
+	DelegationS string `json:"delegationS"` // Hex string of S of the delegation, signed by the committed key
+	MsgHash     string `json:"msgHash"`     // Hex string of the message hash, signed by the session key
+	R           string `json:"r"`           // Hex string of R of the message, signed by the session key
+	S           string `json:"s"`           // Hex string of S of the message, signed by the session key
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash    string `json:"commitHash"`    // Hash of the public key commitment, mimc or poseidon2
+	CommitVersion int    `json:"commitVersion"` // Layout of the commitment, 0 or 1
+	Backend       string `json:"backend"`       // Proof system, plonk
+	Unsafe        bool   `json:"unsafe"`        // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	sessionFile := flag.String("session", "session_input.json", "JSON file of the delegation to the session key and of the message it signed")
+	printDigest := flag.Bool("digest", false, "print the delegation digest to sign with the committed key, and exit")
+	flag.Parse()
+
+	var in ProveInputEcdsa
+	err := readFromFile("witness_input.json", &in)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read witness_input.json")
+	if (in.Curve != "" && in.Curve != "secp256k1") || (in.Scheme != "" && in.Scheme != "ecdsa") {
+		fmt.Printf("Error: session keys are delegated by secp256k1 ECDSA keys\n")
+		os.Exit(1)
+	}
+	var session SessionInput
+	err = readFromFile(*sessionFile, &session)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", *sessionFile, err)
+		os.Exit(1)
+	}
+	fmt.Println("Read", *sessionFile)
+
+	delegation, err := computeDelegationDigest(in, session)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *printDigest {
+		fmt.Printf("Delegation digest, to sign with the committed key: %064x\n", delegation)
+		return
+	}
+	err = verifySession(in, session, delegation)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc"}
+	if _, statErr := os.Stat("session_setup_config.json"); statErr == nil {
+		err = readFromFile("session_setup_config.json", &config)
+		if err != nil {
+			fmt.Printf("Error reading session_setup_config.json: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: session_setup_config.json is a test setup, its proofs must not be trusted on-chain\n")
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile("session_r1cs.bin", loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading session_r1cs.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read session_r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile("session_proving_key.bin", loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading session_proving_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read session_proving_key.bin")
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile("session_verifying_key.bin", loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading session_verifying_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read session_verifying_key.bin")
+
+	// 4. the commitment must have been computed with the options of the setup
+	if in.Hash == "" {
+		in.Hash = "mimc"
+	}
+	if in.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", in.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if in.Version != config.CommitVersion {
+		fmt.Printf("Error: commitment version %d, setup uses version %d\n", in.Version, config.CommitVersion)
+		os.Exit(1)
+	}
+	err = checkCommitment(in, config.CommitHash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Create the witness
+	witnessFull, err := newSessionWitness(in, session, config.CommitHash)
+	if err != nil {
+		fmt.Printf("Error creating witness: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitness, err := witnessFull.Public()
+	if err != nil {
+		fmt.Printf("Error creating public witness: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Prove and verify
+	fmt.Println("\n--- Proving session key signature ---")
+	startProve := time.Now()
+	proof, err := b.Prove(loadedR1CS, loadedPK, witnessFull)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
+
+	err = b.Verify(proof, loadedVK, publicWitness)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proof)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Verification SUCCEEDED")
+
+	// 7. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	publicInputs := fmt.Sprintf("%v", publicWitness.Vector())
+	verifierTestFile, err := os.Create("solidity/test/SessionVerifier.t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/SessionVerifier.t.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	proofHex := hexutil.Encode(proofBytes)[2:]
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/SessionVerifier.sol";
+
+contract SessionVerifierTest is Test {
+    PlonkVerifier ZkSession;
+
+    // public inputs: msg (4 limbs), validUntil, scope, chainId, account, nullifier, commitment
+    uint256 constant VALID_UNTIL_INDEX = 4;
+    uint256 constant SCOPE_INDEX = 5;
+    uint256 constant NULLIFIER_INDEX = 8;
+    mapping(uint256 => bool) usedNullifiers;
+
+    function setUp() public {
+        ZkSession = new PlonkVerifier();
+    }
+
+    // useProof accepts a proof only once and while the session key is valid,
+    // as the account does. The account also checks the operation against the
+    // scope of the session key.
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+        // validUntil is a timestamp here, a block number for an account counting blocks
+        if (block.timestamp > inputs[VALID_UNTIL_INDEX]) return false;
+        if (!ZkSession.Verify(proof, inputs)) return false;
+        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
+    }
+
+    function inputs() internal pure returns (uint256[] memory) {
+        uint256[10] memory public_inputs = ` + publicInputs + `;
+
+        uint256[] memory res = new uint256[](10);
+        for (uint i = 0; i < 10; i++) res[i] = uint256(public_inputs[i]);
+        return res;
+    }
+
+    function test_Session() public {
+        bytes memory proof = hex"` + proofHex + `";
+        vm.warp(inputs()[VALID_UNTIL_INDEX]);
+
+        bool res = useProof(proof, inputs());
+        assertTrue(res);
+        console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs()));
+    }
+
+    function test_SessionExpired() public {
+        bytes memory proof = hex"` + proofHex + `";
+
+        // used after the expiry of the session key, the proof is rejected
+        vm.warp(inputs()[VALID_UNTIL_INDEX] + 1);
+        assertFalse(useProof(proof, inputs()));
+    }
+}
+`))
+	fmt.Println("Successfully exported solidity/test/SessionVerifier.t.sol")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", proofHex, " \"", publicWitness.Vector(), "\"\n")
+}
+
+// newSessionWitness builds the full witness of the session key circuit.
+func newSessionWitness(in ProveInputEcdsa, session SessionInput, commitHash string) (witness.Witness, error) {
+	// Decode hex strings back to big.Int for witness construction
+	fields := []string{
+		session.DelegationR, session.DelegationS, session.R, session.S, session.MsgHash, session.ValidUntil, session.Scope,
+		in.PubX, in.PubY, session.SessionPubX, session.SessionPubY, in.Address, in.Nonce, in.ChainID, in.Account, in.Com,
+	}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	delegationR, delegationS, r, s, msgHash, validUntil, scope := values[0], values[1], values[2], values[3], values[4], values[5], values[6]
+	pubX, pubY, sessionX, sessionY := values[7], values[8], values[9], values[10]
+	address, nonce, chainID, account, com := values[11], values[12], values[13], values[14], values[15]
+
+	// the nullifier binds the proof to this message, chain and account
+	nullifier, err := circuits.ComputeNullifier(commitHash, nonce, circuits.ScalarLimbs[emulated.Secp256k1Fr](msgHash), chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := circuits.SessionCircuit{
+		Delegation: ecdsa.Signature[emulated.Secp256k1Fr]{
+			R: emulated.ValueOf[emulated.Secp256k1Fr](delegationR),
+			S: emulated.ValueOf[emulated.Secp256k1Fr](delegationS),
+		},
+		Sig: ecdsa.Signature[emulated.Secp256k1Fr]{
+			R: emulated.ValueOf[emulated.Secp256k1Fr](r),
+			S: emulated.ValueOf[emulated.Secp256k1Fr](s),
+		},
+		Msg:        emulated.ValueOf[emulated.Secp256k1Fr](msgHash),
+		ValidUntil: validUntil,
+		Scope:      scope,
+		Pub: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](pubX),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](pubY),
+		},
+		SessionPub: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](sessionX),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](sessionY),
+		},
+		Address:   address,
+		Nonce:     nonce,
+		ChainID:   chainID,
+		Account:   account,
+		Nullifier: nullifier,
+		Com:       com,
+
+		CommitHash: commitHash,
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// computeDelegationDigest computes natively the delegation digest
+// keccak256(abi.encode(keccak256(delegationTag), chainId, account, session, validUntil, scope)),
+// as in-circuit.
+func computeDelegationDigest(in ProveInputEcdsa, session SessionInput) (*big.Int, error) {
+	fields := []string{in.ChainID, in.Account, session.SessionPubX, session.SessionPubY, session.ValidUntil, session.Scope}
+	raw := make([][]byte, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		raw[i] = b
+	}
+	if len(raw[2]) != 32 || len(raw[3]) != 32 {
+		return nil, fmt.Errorf("the session key coordinates must be 32 bytes long")
+	}
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(raw[2])
+	keccak.Write(raw[3])
+	sessionAddress := keccak.Sum(nil)[12:]
+
+	words := []*big.Int{
+		new(big.Int).SetBytes(raw[0]),
+		new(big.Int).SetBytes(raw[1]),
+		new(big.Int).SetBytes(sessionAddress),
+		new(big.Int).SetBytes(raw[4]),
+		new(big.Int).SetBytes(raw[5]),
+	}
+	for i, nbBits := range []int{64, 160, 160, 64, 253} {
+		if words[i].BitLen() > nbBits {
+			return nil, fmt.Errorf("the delegation word %x does not fit in %d bits", words[i], nbBits)
+		}
+	}
+
+	tag := cryptosha3.NewLegacyKeccak256()
+	tag.Write([]byte(circuits.DelegationTag))
+	keccak = cryptosha3.NewLegacyKeccak256()
+	keccak.Write(tag.Sum(nil))
+	for _, w := range words {
+		keccak.Write(w.FillBytes(make([]byte, 32)))
+	}
+	return new(big.Int).SetBytes(keccak.Sum(nil)), nil
+}
+
+// verifySession verifies natively the signature of the delegation by the
+// committed key and the signature of the message by the session key.
+func verifySession(in ProveInputEcdsa, session SessionInput, delegation *big.Int) error {
+	fields := []string{in.PubX, in.PubY, session.DelegationR, session.DelegationS, session.SessionPubX, session.SessionPubY, session.MsgHash, session.R, session.S}
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		values[i] = new(big.Int).SetBytes(b)
+	}
+	if !verifyECDSA(delegation, values[2], values[3], values[0], values[1]) {
+		return fmt.Errorf("the delegation %064x is not signed by the committed key, see -digest", delegation)
+	}
+	if !verifyECDSA(values[6], values[7], values[8], values[4], values[5]) {
+		return fmt.Errorf("the message %s is not signed by the session key", session.MsgHash)
+	}
+	return nil
+}
+
+// verifyECDSA verifies natively the secp256k1 ECDSA signature (r, s) of the
+// digest by the key (x, y), the digest being reduced modulo n as in-circuit.
+func verifyECDSA(digest, r, s, x, y *big.Int) bool {
+	var fp emulated.Secp256k1Fp
+	var fr emulated.Secp256k1Fr
+	p, n := fp.Modulus(), fr.Modulus()
+	params := sw_emulated.GetSecp256k1Params()
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+
+	// x(z/s G + r/s Q) = r mod n
+	sInv := new(big.Int).ModInverse(s, n)
+	u1 := new(big.Int).Mod(digest, n)
+	u1.Mul(u1, sInv).Mod(u1, n)
+	u2 := new(big.Int).Mul(r, sInv)
+	u2.Mod(u2, n)
+	x1, y1 := scalarMul(params.A, p, params.Gx, params.Gy, u1)
+	x2, y2 := scalarMul(params.A, p, x, y, u2)
+	qx, _ := pointAdd(params.A, p, x1, y1, x2, y2)
+	return qx != nil && new(big.Int).Mod(qx, n).Cmp(r) == 0
+}
+
+// pointAdd adds two affine points of y^2 = x^3 + ax + b over F_p, the point at
+// infinity being nil.
+func pointAdd(a, p, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	lambda := new(big.Int)
+	if x1.Cmp(x2) == 0 {
+		sum := new(big.Int).Add(y1, y2)
+		if sum.Mod(sum, p).Sign() == 0 {
+			return nil, nil
+		}
+		// (3x^2 + a) / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3)).Add(num, a)
+		den := new(big.Int).Lsh(y1, 1)
+		lambda.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	} else {
+		// (y2 - y1) / (x2 - x1)
+		den := new(big.Int).Sub(x2, x1)
+		lambda.Mul(new(big.Int).Sub(y2, y1), den.ModInverse(den.Mod(den, p), p))
+	}
+	lambda.Mod(lambda, p)
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, x1).Sub(x, x2).Mod(x, p)
+	y := new(big.Int).Sub(x1, x)
+	y.Mul(y, lambda).Sub(y, y1).Mod(y, p)
+	return x, y
+}
+
+// scalarMul computes k(x, y) by double-and-add.
+func scalarMul(a, p, x, y, k *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		rx, ry = pointAdd(a, p, rx, ry, rx, ry)
+		if k.Bit(i) == 1 {
+			rx, ry = pointAdd(a, p, rx, ry, x, y)
+		}
+	}
+	return rx, ry
+}
+
+// checkCommitment recomputes natively the commitment of the input, so that a
+// commitment made for another chain, account or layout is reported before proving.
+func checkCommitment(in ProveInputEcdsa, commitHash string) error {
+	h, err := circuits.NewNativeCommitmentHasher(commitHash)
+	if err != nil {
+		return err
+	}
+	if in.Version == 1 {
+		// h(tag, chainId, account, address, nonce)
+		prefix := []*big.Int{new(big.Int).SetBytes([]byte(circuits.CommitTagV1))}
+		for _, field := range []string{in.ChainID, in.Account} {
+			b, err := hex.DecodeString(field)
+			if err != nil {
+				return fmt.Errorf("decoding hex %q: %w", field, err)
+			}
+			prefix = append(prefix, new(big.Int).SetBytes(b))
+		}
+		for _, v := range prefix {
+			h.Write(v.FillBytes(make([]byte, 32)))
+		}
+	}
+	for _, field := range []string{in.Address, in.Nonce} {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return fmt.Errorf("decoding hex %q: %w", field, err)
+		}
+		h.Write(b)
+	}
+	com, err := hex.DecodeString(in.Com)
+	if err != nil {
+		return fmt.Errorf("decoding hex %q: %w", in.Com, err)
+	}
+	if new(big.Int).SetBytes(h.Sum(nil)).Cmp(new(big.Int).SetBytes(com)) != 0 {
+		if in.Version == 1 {
+			return fmt.Errorf("the commitment does not open to the address, the nonce, the chain id %s and the account %s", in.ChainID, in.Account)
+		}
+		return fmt.Errorf("the commitment does not open to the address and the nonce")
+	}
+	return nil
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *ProveInputEcdsa: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SessionInput: // For the session input
+		err = json.NewDecoder(file).Decode(v)
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"crypto/rand"
+
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+
+	cryptoecdsa "github.com/consensys/gnark-crypto/ecc/secp256k1/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"zkbackend"
+)
+
+// SignerInput struct for JSON serialization of one slot of the threshold witness.
+type SignerInput struct {
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	R       string `json:"r"`       // Hex string of signature R, empty if the key did not sign
+	S       string `json:"s"`       // Hex string of signature S, empty if the key did not sign
+}
+
+// ProveInputThreshold struct for JSON serialization of threshold witness inputs.
+type ProveInputThreshold struct {
+	MsgHash   string        `json:"msgHash"`   // Hex string of the message hash
+	Threshold int           `json:"threshold"` // Number k of required signatures
+	Signers   []SignerInput `json:"signers"`   // The m committed signers
+	Nonce     string        `json:"nonce"`     // Hex string of nonce
+	Com       string        `json:"com"`       // Hex string of Com
+	Hash      string        `json:"hash"`      // Hash of the commitment, mimc or poseidon2
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"` // Hash of the public key commitment, mimc or poseidon2
+	Backend    string `json:"backend"`    // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`     // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc"}
+	if _, statErr := os.Stat("threshold_setup_config.json"); statErr == nil {
+		err := readFromFile("threshold_setup_config.json", &config)
+		if err != nil {
+			fmt.Printf("Error reading threshold_setup_config.json: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: threshold_setup_config.json is a test setup, its proofs must not be trusted on-chain\n")
+	}
+
+	// 1. Read back the compiled circuit
+	loadedR1CS := b.NewCS()
+	err = readFromFile("threshold_r1cs.bin", loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading threshold_r1cs.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read threshold_r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+
+	// 2. Read back the proving key
+	loadedPK := b.NewProvingKey()
+	err = readFromFile("threshold_proving_key.bin", loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading threshold_proving_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read threshold_proving_key.bin")
+
+	// 3. Read back the verifying key
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile("threshold_verifying_key.bin", loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading threshold_verifying_key.bin: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read threshold_verifying_key.bin")
+
+	// 4. Read back the prove input JSON
+	var loadedProveInput ProveInputThreshold
+	err = readFromFile("threshold_witness_input.json", &loadedProveInput)
+	if err != nil {
+		fmt.Printf("Error reading threshold_witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Read threshold_witness_input.json")
+
+	// the commitment must have been computed with the hash of the setup
+	if loadedProveInput.Hash == "" {
+		loadedProveInput.Hash = "mimc"
+	}
+	if loadedProveInput.Hash != config.CommitHash {
+		fmt.Printf("Error: commitment computed with %s, setup uses %s\n", loadedProveInput.Hash, config.CommitHash)
+		os.Exit(1)
+	}
+	if len(loadedProveInput.Signers) != circuits.NbSigners {
+		fmt.Printf("Error: expected %d signers, got %d\n", circuits.NbSigners, len(loadedProveInput.Signers))
+		os.Exit(1)
+	}
+
+	msgHashBytes, err := hex.DecodeString(loadedProveInput.MsgHash)
+	if err != nil {
+		fmt.Printf("Error decoding MsgHash hex: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Create a new witness using the loaded input data
+	witnessCircuitLoaded := circuits.NewThresholdCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr](circuits.NbSigners)
+	witnessCircuitLoaded.CommitHash = config.CommitHash
+	witnessCircuitLoaded.Msg = emulated.ValueOf[emulated.Secp256k1Fr](msgHashBytes)
+	witnessCircuitLoaded.K = loadedProveInput.Threshold
+	witnessCircuitLoaded.Nonce = mustDecodeHex("Nonce", loadedProveInput.Nonce)
+	witnessCircuitLoaded.Com = mustDecodeHex("Com", loadedProveInput.Com)
+
+	nbSigned := 0
+	for i, signer := range loadedProveInput.Signers {
+		pub := ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](mustDecodeHex("PubX", signer.PubX)),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](mustDecodeHex("PubY", signer.PubY)),
+		}
+		witnessCircuitLoaded.Pubs[i] = pub
+
+		if signer.R != "" && signer.S != "" {
+			witnessCircuitLoaded.Signed[i] = 1
+			witnessCircuitLoaded.Sigs[i] = ecdsa.Signature[emulated.Secp256k1Fr]{
+				R: emulated.ValueOf[emulated.Secp256k1Fr](mustDecodeHex("R", signer.R)),
+				S: emulated.ValueOf[emulated.Secp256k1Fr](mustDecodeHex("S", signer.S)),
+			}
+			witnessCircuitLoaded.Pads[i] = pub
+			nbSigned++
+			continue
+		}
+
+		// the slot is filled with a signature by a throwaway key
+		padKey, err := cryptoecdsa.GenerateKey(rand.Reader)
+		if err != nil {
+			fmt.Printf("Error generating padding key: %v\n", err)
+			os.Exit(1)
+		}
+		sigBin, err := padKey.Sign(msgHashBytes, nil)
+		if err != nil {
+			fmt.Printf("Error signing with padding key: %v\n", err)
+			os.Exit(1)
+		}
+		var sig cryptoecdsa.Signature
+		sig.SetBytes(sigBin)
+		witnessCircuitLoaded.Signed[i] = 0
+		witnessCircuitLoaded.Sigs[i] = ecdsa.Signature[emulated.Secp256k1Fr]{
+			R: emulated.ValueOf[emulated.Secp256k1Fr](new(big.Int).SetBytes(sig.R[:])),
+			S: emulated.ValueOf[emulated.Secp256k1Fr](new(big.Int).SetBytes(sig.S[:])),
+		}
+		witnessCircuitLoaded.Pads[i] = ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](padKey.PublicKey.A.X),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](padKey.PublicKey.A.Y),
+		}
+	}
+	if nbSigned < loadedProveInput.Threshold {
+		fmt.Printf("Error: %d signatures provided, threshold is %d\n", nbSigned, loadedProveInput.Threshold)
+		os.Exit(1)
+	}
+
+	witnessFullLoaded, err := frontend.NewWitness(&witnessCircuitLoaded, ecc.BN254.ScalarField())
+	if err != nil {
+		fmt.Printf("Error creating full witness from loaded data: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitnessLoaded, err := witnessFullLoaded.Public()
+	if err != nil {
+		fmt.Printf("Error getting public witness from loaded data: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 6. Perform a new proof and verification using the loaded artifacts
+	fmt.Println("\n--- Proving with loaded setup ---")
+
+	// Prove
+	startProveLoaded := time.Now()
+	proofLoaded, err := b.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
+	if err != nil {
+		fmt.Printf("Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProveLoaded).Milliseconds()))
+
+	// Verify
+	err = b.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proofLoaded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 7. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	verifierTestFile, err := os.Create("solidity/test/ThresholdVerifier.t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/ThresholdVerifier.t.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	// header
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/ThresholdVerifier.sol";
+
+contract ThresholdVerifierTest is Test {
+    PlonkVerifier ZkK1;
+
+    function setUp() public {
+        ZkK1 = new PlonkVerifier();
+    }
+
+    function test_k1ThresholdPlonk() public view {
+`))
+
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";`))
+	verifierTestFile.Write([]byte("\n"))
+
+	PI := fmt.Sprintf("%v", publicWitnessLoaded.Vector())
+
+	verifierTestFile.Write([]byte("uint256[5] memory public_inputs = " + PI + ";\n"))
+
+	// footer
+	verifierTestFile.Write([]byte(`
+        uint256[] memory inputs = new uint256[](5);
+        for (uint i = 0; i < 5; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = ZkK1.Verify(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+    }
+}
+`))
+	fmt.Println("Successfully exported solidity/test/ThresholdVerifier.t.sol")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitnessLoaded.Vector(), "\"\n")
+}
+
+// mustDecodeHex decodes a hex string of the witness file into a big.Int.
+func mustDecodeHex(name, s string) *big.Int {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		fmt.Printf("Error decoding %s hex: %v\n", name, err)
+		os.Exit(1)
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *ProveInputThreshold: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -4,22 +4,27 @@ import (
 	"bytes"
 	"io"
 
+	"crypto/elliptic"
 	"crypto/rand"
 
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"math/big"
 	"os"
 
-	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/fp"
+	cryptosha3 "golang.org/x/crypto/sha3"
 
-	// cryptoposeidon2 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
-	cryptomimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"circuits"
 )
 
 // ProveInputEcdsa struct for JSON serialization of witness inputs.
 type Input struct {
 	PubX string `json:"pubX"` // Hex string of public key X
+	PubY string `json:"pubY"` // Hex string of public key Y, may be empty for a BIP-340 x-only key
+	Pub  string `json:"pub"`  // Hex string of the 32-byte encoding of an Ed25519 key, instead of X and Y
 }
 
 // ProveInputEcdsa struct for JSON serialization of witness inputs.
@@ -31,18 +36,80 @@ type InputWithCommit struct {
 	PubY    string `json:"pubY"`    // Hex string of public key Y
 	Address string `json:"address"` // Hex string of address
 	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account, filled at proving time for version 0
+	Account string `json:"account"` // Hex string of the account using the proof, filled at proving time for version 0
 	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1, p256 or ed25519
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+	Scheme  string `json:"scheme"`  // Signature scheme of the key, ecdsa, bip340 or ed25519
+
+	FalconKey string `json:"falconKey,omitempty"` // Hex string of the hash of the committed Falcon public key
+}
+
+// FalconInput struct for JSON deserialization of the Falcon public key of a hybrid signer.
+type FalconInput struct {
+	Pk string `json:"pk"` // Hex string of the Falcon-512 public key, NIST encoding
 }
 
 func main() {
+	commitHash := flag.String("hash", "mimc", "hash of the public key commitment: mimc or poseidon2, as chosen at setup")
+	curve := flag.String("curve", "secp256k1", "curve of the signer: secp256k1 or p256")
+	nonceHex := flag.String("nonce", "", "hex 20-byte nonce derived with derive_nonce.go, random when empty")
+	version := flag.Int("version", 0, "commitment layout, as chosen at setup: 0 for h(address, nonce), 1 for h(tag, chainId, account, address, nonce)")
+	chainID := flag.String("chainId", "", "hex chain id of the account, bound to the commitment with -version 1")
+	account := flag.String("account", "", "hex address of the account, bound to the commitment with -version 1")
+	scheme := flag.String("scheme", "ecdsa", "signature scheme of the key: ecdsa, bip340 for a Schnorr x-only key proven with prove_schnorr_k1.go, or ed25519 for a key proven with prove_ed25519.go")
+	falconFile := flag.String("falcon", "", "JSON file holding the Falcon-512 public key {pk} of a hybrid signer, committed with the address")
+	flag.Parse()
+	if *version != 0 && *version != 1 {
+		fmt.Printf("Error: unknown commitment version %d\n", *version)
+		os.Exit(1)
+	}
+	if *scheme != "ecdsa" && *scheme != "bip340" && *scheme != "ed25519" {
+		fmt.Printf("Error: unknown signature scheme %q\n", *scheme)
+		os.Exit(1)
+	}
+	if *scheme == "bip340" && (*curve != "secp256k1" || *falconFile != "") {
+		fmt.Printf("Error: BIP-340 keys are secp256k1 keys, without a Falcon key\n")
+		os.Exit(1)
+	}
+	if *scheme == "ed25519" {
+		if *curve != "secp256k1" || *falconFile != "" {
+			fmt.Printf("Error: Ed25519 keys take neither -curve nor a Falcon key\n")
+			os.Exit(1)
+		}
+		*curve = "ed25519"
+	}
+	if *falconFile != "" && *version != 0 {
+		fmt.Printf("Error: the hybrid circuit only supports version 0 commitments\n")
+		os.Exit(1)
+	}
 
 	var loadedInput Input
-	err := readFromFile("pub_x.json", &loadedInput)
+	err := readFromFile("pub_key.json", &loadedInput)
 	if err != nil {
-		fmt.Printf("Error reading pub_x.json: %v\n", err)
+		fmt.Printf("Error reading pub_key.json: %v\n", err)
 		os.Exit(1)
 	}
 
+	// an Ed25519 key is given encoded, and committed with its coordinates
+	var pubEd25519 []byte
+	if *scheme == "ed25519" {
+		pubEd25519, err = hex.DecodeString(loadedInput.Pub)
+		if err != nil {
+			fmt.Printf("Error decoding Pub hex: %v\n", err)
+			os.Exit(1)
+		}
+		x, y, err := decodeEd25519Point(pubEd25519)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		loadedInput.PubX = hex.EncodeToString(x.FillBytes(make([]byte, 32)))
+		loadedInput.PubY = hex.EncodeToString(y.FillBytes(make([]byte, 32)))
+	}
+
 	// Decode hex strings back to big.Int and byte slices for witness construction
 	pubXBytes, err := hex.DecodeString(loadedInput.PubX)
 	if err != nil {
@@ -50,18 +117,91 @@ func main() {
 		os.Exit(1)
 	}
 
-	// address-like derived from public key x-coordinate
-	address := pubXBytes[:20]
+	pubYBytes, err := hex.DecodeString(loadedInput.PubY)
+	if err != nil {
+		fmt.Printf("Error decoding PubY hex: %v\n", err)
+		os.Exit(1)
+	}
+	if *scheme == "bip340" {
+		// the x-only key stands for the point of even y, the one the circuit checks
+		pubY, err := liftX(new(big.Int).SetBytes(pubXBytes))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(pubYBytes) != 0 && new(big.Int).SetBytes(pubYBytes).Cmp(pubY) != 0 {
+			fmt.Printf("Error: the y coordinate of a BIP-340 key must be even\n")
+			os.Exit(1)
+		}
+		pubYBytes = pubY.FillBytes(make([]byte, 32))
+	}
+	if len(pubXBytes) != 32 || len(pubYBytes) != 32 {
+		fmt.Printf("Error: public key coordinates must be 32 bytes long\n")
+		os.Exit(1)
+	}
+	onCurve, err := isOnCurve(*curve, new(big.Int).SetBytes(pubXBytes), new(big.Int).SetBytes(pubYBytes))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !onCurve {
+		fmt.Printf("Error: the public key is not a point of %s\n", *curve)
+		os.Exit(1)
+	}
+
+	// Ethereum address of the public key, keccak256(X||Y)[12:], as checked
+	// in-circuit, keccak256 of the 32-byte encoding for an Ed25519 key
+	keccak := cryptosha3.NewLegacyKeccak256()
+	if pubEd25519 != nil {
+		keccak.Write(pubEd25519)
+	} else {
+		keccak.Write(pubXBytes)
+		keccak.Write(pubYBytes)
+	}
+	address := keccak.Sum(nil)[12:]
 
-	// 160 bits
+	// 160 bits, random unless derived from the key with derive_nonce.go
 	nonce := make([]byte, 20)
-	_, err = rand.Read(nonce)
-	if err != nil {
-		panic(err)
+	if *nonceHex != "" {
+		nonce, err = hex.DecodeString(*nonceHex)
+		if err != nil || len(nonce) != 20 {
+			fmt.Printf("Error: -nonce must be a 20-byte hex string\n")
+			os.Exit(1)
+		}
+	} else {
+		_, err = rand.Read(nonce)
+		if err != nil {
+			panic(err)
+		}
 	}
 
 	// PK Commitment
-	h := cryptomimc.NewMiMC()
+	h, err := circuits.NewNativeCommitmentHasher(*commitHash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *version == 1 {
+		// h(tag, chainId, account, address, nonce)
+		prefix := []*big.Int{new(big.Int).SetBytes([]byte(circuits.CommitTagV1))}
+		for _, field := range []string{*chainID, *account} {
+			b, err := hex.DecodeString(field)
+			if err != nil || field == "" {
+				fmt.Printf("Error: -chainId and -account must be hex strings with -version 1\n")
+				os.Exit(1)
+			}
+			prefix = append(prefix, new(big.Int).SetBytes(b))
+		}
+		for _, v := range prefix {
+			_, err = h.Write(v.FillBytes(make([]byte, 32)))
+			if err != nil {
+				panic(err)
+			}
+		}
+	} else {
+		// chainId and account are filled at proving time
+		*chainID, *account = "", ""
+	}
 	_, err = h.Write(address)
 	if err != nil {
 		panic(err)
@@ -70,6 +210,31 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	// h(address, nonce, h(falcon key)) for a hybrid signer
+	falconKey := ""
+	if *falconFile != "" {
+		var falconIn FalconInput
+		err = readFromFile(*falconFile, &falconIn)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", *falconFile, err)
+			os.Exit(1)
+		}
+		pk, err := hex.DecodeString(falconIn.Pk)
+		if err != nil {
+			fmt.Printf("Error decoding pk hex: %v\n", err)
+			os.Exit(1)
+		}
+		keyHash, err := falconKeyHash(*commitHash, pk)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		_, err = h.Write(keyHash)
+		if err != nil {
+			panic(err)
+		}
+		falconKey = hex.EncodeToString(keyHash)
+	}
 	ComPK := h.Sum(nil)
 
 	Output := InputWithCommit{
@@ -77,10 +242,18 @@ func main() {
 		R:       "",
 		S:       "",
 		PubX:    hex.EncodeToString(pubXBytes[:]),
-		PubY:    "",
+		PubY:    hex.EncodeToString(pubYBytes[:]),
 		Address: hex.EncodeToString(address[:]),
 		Nonce:   hex.EncodeToString(nonce[:]),
+		ChainID: *chainID,
+		Account: *account,
 		Com:     hex.EncodeToString(ComPK),
+		Hash:    *commitHash,
+		Curve:   *curve,
+		Version: *version,
+		Scheme:  *scheme,
+
+		FalconKey: falconKey,
 	}
 
 	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
@@ -93,6 +266,142 @@ func main() {
 
 }
 
+// falconKeyHash decodes the NIST encoding of a Falcon-512 public key, 0x09
+// followed by the 14-bit coefficients, and hashes the coefficients packed by
+// circuits.FalconPacking in field elements, as the hybrid circuit does.
+func falconKeyHash(name string, pk []byte) ([]byte, error) {
+	if len(pk) != 1+circuits.FalconN*14/8 || pk[0] != 0x09 {
+		return nil, fmt.Errorf("not a Falcon-512 public key")
+	}
+	coeffs := make([]uint64, 0, circuits.FalconN)
+	var acc uint64
+	accLen := 0
+	for _, b := range pk[1:] {
+		acc = acc<<8 | uint64(b)
+		accLen += 8
+		if accLen >= 14 {
+			accLen -= 14
+			w := (acc >> accLen) & 0x3fff
+			if w >= circuits.FalconQ {
+				return nil, fmt.Errorf("public key coefficient %d is not reduced modulo q", w)
+			}
+			coeffs = append(coeffs, w)
+		}
+	}
+	kh, err := circuits.NewNativeCommitmentHasher(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(coeffs); i += circuits.FalconPacking {
+		v := new(big.Int)
+		for j := min(i+circuits.FalconPacking, len(coeffs)) - 1; j >= i; j-- {
+			v.Lsh(v, 14).Add(v, new(big.Int).SetUint64(coeffs[j]))
+		}
+		kh.Write(v.FillBytes(make([]byte, 32)))
+	}
+	return new(big.Int).SetBytes(kh.Sum(nil)).FillBytes(make([]byte, 32)), nil
+}
+
+// liftX returns the even y coordinate of the secp256k1 point of x coordinate
+// x, as BIP-340 does for x-only keys.
+func liftX(x *big.Int) (*big.Int, error) {
+	// y = (x^3 + 7)^((p+1)/4), p = 3 mod 4
+	p := fp.Modulus()
+	if x.Cmp(p) >= 0 {
+		return nil, fmt.Errorf("the x-only key is not reduced modulo p")
+	}
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	y2.Add(y2, big.NewInt(7)).Mod(y2, p)
+	e := new(big.Int).Add(p, big.NewInt(1))
+	y := new(big.Int).Exp(y2, e.Rsh(e, 2), p)
+	if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(y2) != 0 {
+		return nil, fmt.Errorf("the x-only key is not the x coordinate of a point of secp256k1")
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(p, y)
+	}
+	return y, nil
+}
+
+// ed25519Params returns the modulus 2^255 - 19 and the constant d of the curve
+// -x^2 + y^2 = 1 + d x^2 y^2.
+func ed25519Params() (*big.Int, *big.Int) {
+	p := new(big.Int).Lsh(big.NewInt(1), 255)
+	p.Sub(p, big.NewInt(19))
+	d, _ := new(big.Int).SetString("37095705934669439343138083508754565189542113879843219016388785533085940283555", 10)
+	return p, d
+}
+
+// decodeEd25519Point decodes the 32-byte encoding of an Ed25519 point, y
+// little-endian and the parity of x in the top bit, and refuses a non
+// canonical encoding.
+func decodeEd25519Point(enc []byte) (*big.Int, *big.Int, error) {
+	if len(enc) != 32 {
+		return nil, nil, fmt.Errorf("an Ed25519 point is encoded in 32 bytes")
+	}
+	p, d := ed25519Params()
+	be := make([]byte, 32)
+	for i := range enc {
+		be[31-i] = enc[i]
+	}
+	y := new(big.Int).SetBytes(be)
+	sign := y.Bit(255)
+	y.SetBit(y, 255, 0)
+	if y.Cmp(p) >= 0 {
+		return nil, nil, fmt.Errorf("non canonical encoding of y")
+	}
+
+	// x^2 = (y^2 - 1) / (d y^2 + 1)
+	yy := new(big.Int).Mul(y, y)
+	u := new(big.Int).Sub(yy, big.NewInt(1))
+	v := new(big.Int).Mul(d, yy)
+	v.Add(v, big.NewInt(1)).Mod(v, p)
+	xx := new(big.Int).Mul(u, v.ModInverse(v, p))
+	x := new(big.Int).ModSqrt(xx.Mod(xx, p), p)
+	if x == nil {
+		return nil, nil, fmt.Errorf("not the encoding of a point of Ed25519")
+	}
+	if x.Sign() == 0 && sign == 1 {
+		return nil, nil, fmt.Errorf("non canonical encoding of x = 0")
+	}
+	if x.Bit(0) != sign {
+		x.Sub(p, x)
+	}
+	return x, y, nil
+}
+
+// isOnCurve checks that (x, y) is a point of the signer curve.
+func isOnCurve(curve string, x, y *big.Int) (bool, error) {
+	switch curve {
+	case "secp256k1":
+		// y^2 = x^3 + 7
+		p := fp.Modulus()
+		if x.Cmp(p) >= 0 || y.Cmp(p) >= 0 {
+			return false, nil
+		}
+		lhs := new(big.Int).Exp(y, big.NewInt(2), p)
+		rhs := new(big.Int).Exp(x, big.NewInt(3), p)
+		rhs.Add(rhs, big.NewInt(7)).Mod(rhs, p)
+		return lhs.Cmp(rhs) == 0, nil
+	case "p256":
+		return elliptic.P256().IsOnCurve(x, y), nil
+	case "ed25519":
+		// -x^2 + y^2 = 1 + d x^2 y^2
+		p, d := ed25519Params()
+		if x.Cmp(p) >= 0 || y.Cmp(p) >= 0 {
+			return false, nil
+		}
+		xx := new(big.Int).Mul(x, x)
+		yy := new(big.Int).Mul(y, y)
+		lhs := new(big.Int).Sub(yy, xx)
+		rhs := new(big.Int).Mul(d, xx)
+		rhs.Mul(rhs, yy).Add(rhs, big.NewInt(1))
+		return lhs.Sub(lhs, rhs).Mod(lhs, p).Sign() == 0, nil
+	default:
+		return false, fmt.Errorf("unknown curve %q", curve)
+	}
+}
+
 // writeToFile is a helper to serialize and write gnark objects or byte readers to files.
 func writeToFile(filename string, data interface{}) {
 	file, err := os.Create(filename)
@@ -132,7 +441,7 @@ func readFromFile(filename string, data interface{}) error {
 		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
 			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
 		}
-	case *Input: // For the JSON input
+	case *Input, *FalconInput: // For the JSON input
 		decoder := json.NewDecoder(file)
 		err = decoder.Decode(v)
 		if err != nil {
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"crypto/rand"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	cryptosha3 "golang.org/x/crypto/sha3"
+
+	"circuits"
+)
+
+// PubKeyInput struct for JSON serialization of a public key.
+type PubKeyInput struct {
+	PubX string `json:"pubX"` // Hex string of public key X
+	PubY string `json:"pubY"` // Hex string of public key Y
+}
+
+// InputThreshold struct for JSON serialization of the committed key set.
+type InputThreshold struct {
+	Threshold int           `json:"threshold"` // Number k of required signatures
+	Keys      []PubKeyInput `json:"keys"`      // The m committed keys
+}
+
+// SignerInput struct for JSON serialization of one slot of the threshold witness.
+type SignerInput struct {
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	R       string `json:"r"`       // Hex string of signature R, empty if the key did not sign
+	S       string `json:"s"`       // Hex string of signature S, empty if the key did not sign
+}
+
+// ProveInputThreshold struct for JSON serialization of threshold witness inputs.
+type ProveInputThreshold struct {
+	MsgHash   string        `json:"msgHash"`   // Hex string of the message hash
+	Threshold int           `json:"threshold"` // Number k of required signatures
+	Signers   []SignerInput `json:"signers"`   // The m committed signers
+	Nonce     string        `json:"nonce"`     // Hex string of nonce
+	Com       string        `json:"com"`       // Hex string of Com
+	Hash      string        `json:"hash"`      // Hash of the commitment, mimc or poseidon2
+}
+
+func main() {
+	commitHash := flag.String("hash", "mimc", "hash of the public key commitment: mimc or poseidon2, as chosen at setup")
+	flag.Parse()
+
+	var loadedInput InputThreshold
+	err := readFromFile("threshold_keys.json", &loadedInput)
+	if err != nil {
+		fmt.Printf("Error reading threshold_keys.json: %v\n", err)
+		os.Exit(1)
+	}
+	if len(loadedInput.Keys) != circuits.NbSigners {
+		fmt.Printf("Error: expected %d keys, got %d\n", circuits.NbSigners, len(loadedInput.Keys))
+		os.Exit(1)
+	}
+	if loadedInput.Threshold < 1 || loadedInput.Threshold > circuits.NbSigners {
+		fmt.Printf("Error: threshold must be between 1 and %d, got %d\n", circuits.NbSigners, loadedInput.Threshold)
+		os.Exit(1)
+	}
+
+	// 160 bits
+	nonce := make([]byte, 20)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		panic(err)
+	}
+
+	// PK Commitment, h(k, address_1, ..., address_m, nonce)
+	h, err := circuits.NewNativeCommitmentHasher(*commitHash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	_, err = h.Write([]byte{byte(loadedInput.Threshold)})
+	if err != nil {
+		panic(err)
+	}
+
+	signers := make([]SignerInput, circuits.NbSigners)
+	seen := make(map[string]bool)
+	for i, key := range loadedInput.Keys {
+		pubXBytes, err := hex.DecodeString(key.PubX)
+		if err != nil {
+			fmt.Printf("Error decoding PubX hex of key %d: %v\n", i, err)
+			os.Exit(1)
+		}
+		pubYBytes, err := hex.DecodeString(key.PubY)
+		if err != nil {
+			fmt.Printf("Error decoding PubY hex of key %d: %v\n", i, err)
+			os.Exit(1)
+		}
+		if len(pubXBytes) != 32 || len(pubYBytes) != 32 {
+			fmt.Printf("Error: public key coordinates of key %d must be 32 bytes long\n", i)
+			os.Exit(1)
+		}
+
+		// Ethereum address of the public key, keccak256(X||Y)[12:], as checked in-circuit
+		keccak := cryptosha3.NewLegacyKeccak256()
+		keccak.Write(pubXBytes)
+		keccak.Write(pubYBytes)
+		address := keccak.Sum(nil)[12:]
+
+		// a key committed twice would count twice towards the threshold
+		if seen[string(address)] {
+			fmt.Printf("Error: key %d is committed more than once\n", i)
+			os.Exit(1)
+		}
+		seen[string(address)] = true
+
+		_, err = h.Write(address)
+		if err != nil {
+			panic(err)
+		}
+		signers[i] = SignerInput{
+			PubX:    hex.EncodeToString(pubXBytes),
+			PubY:    hex.EncodeToString(pubYBytes),
+			Address: hex.EncodeToString(address),
+		}
+	}
+
+	_, err = h.Write(nonce)
+	if err != nil {
+		panic(err)
+	}
+	ComPK := h.Sum(nil)
+
+	Output := ProveInputThreshold{
+		MsgHash:   "",
+		Threshold: loadedInput.Threshold,
+		Signers:   signers,
+		Nonce:     hex.EncodeToString(nonce[:]),
+		Com:       hex.EncodeToString(ComPK),
+		Hash:      *commitHash,
+	}
+
+	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling prove input JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeToFile("threshold_witness_input.json", bytes.NewReader(OutputJSON))
+
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	// fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *InputThreshold: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"circuits"
+)
+
+// InputWithCommit struct for JSON deserialization of the witness_input.json of a key.
+type InputWithCommit struct {
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+}
+
+// MemberPath struct for JSON serialization of the inclusion path of a key.
+type MemberPath struct {
+	Com      string   `json:"com"`      // Hex string of the key commitment
+	Role     string   `json:"role"`     // Role of the key, admin or user
+	Position int      `json:"position"` // Position of the leaf in the tree of the role
+	Path     []string `json:"path"`     // Hex strings of the siblings, from the leaf up to the root
+}
+
+// RoleTree struct for JSON serialization of the Merkle tree of commitments of the roles.
+type RoleTree struct {
+	Hash      string       `json:"hash"`      // Hash of the commitments and of the tree, mimc or poseidon2
+	Depth     int          `json:"depth"`     // Depth of the tree of a role
+	Root      string       `json:"root"`      // Hex string of h(user root, admin root), public input of the proof
+	UserRoot  string       `json:"userRoot"`  // Hex string of the Merkle root of the user keys
+	AdminRoot string       `json:"adminRoot"` // Hex string of the Merkle root of the admin keys
+	Members   []MemberPath `json:"members"`   // Inclusion paths of the keys
+}
+
+// roles lists the roles in the order of their subtrees under the root.
+var roles = []string{"user", "admin"}
+
+func main() {
+	depth := flag.Int("depth", 4, "depth of the Merkle tree of a role, as chosen at setup")
+	userFiles := flag.String("user", "", "comma-separated witness_input.json files of the user keys")
+	adminFiles := flag.String("admin", "", "comma-separated witness_input.json files of the admin keys")
+	output := flag.String("o", "role_tree.json", "output file")
+	flag.Usage = func() {
+		fmt.Println("Usage: go run role_tree.go [-depth 4] [-o role_tree.json] -admin <witness_input.json>,... -user <witness_input.json>,...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	files := [][]string{splitFiles(*userFiles), splitFiles(*adminFiles)}
+	if len(files[0])+len(files[1]) == 0 || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// the leaves of each role are the commitments of its keys, empty leaves are 0;
+	// a key may belong to both roles
+	commitHash := ""
+	coms := make([][]*big.Int, len(roles))
+	for role, filenames := range files {
+		if len(filenames) > 1<<*depth {
+			fmt.Printf("Error: %d %s keys do not fit in a tree of depth %d\n", len(filenames), roles[role], *depth)
+			os.Exit(1)
+		}
+		coms[role] = make([]*big.Int, 1<<*depth)
+		for i := range coms[role] {
+			coms[role][i] = new(big.Int)
+		}
+		seen := make(map[string]bool)
+		for i, filename := range filenames {
+			var member InputWithCommit
+			err := readFromFile(filename, &member)
+			if err != nil {
+				fmt.Printf("Error reading %s: %v\n", filename, err)
+				os.Exit(1)
+			}
+			if member.Version != 0 {
+				fmt.Printf("Error: %s is a version 1 commitment, only the single signer circuit supports version 1 commitments, commit with pub_commit.go -version 0\n", filename)
+				os.Exit(1)
+			}
+			if member.Hash == "" {
+				member.Hash = "mimc"
+			}
+			if commitHash == "" {
+				commitHash = member.Hash
+			}
+			if member.Hash != commitHash {
+				fmt.Printf("Error: %s is committed with %s, previous keys with %s\n", filename, member.Hash, commitHash)
+				os.Exit(1)
+			}
+			comBytes, err := hex.DecodeString(member.Com)
+			if err != nil {
+				fmt.Printf("Error decoding Com hex of %s: %v\n", filename, err)
+				os.Exit(1)
+			}
+			coms[role][i].SetBytes(comBytes)
+			if coms[role][i].Sign() == 0 || seen[coms[role][i].String()] {
+				fmt.Printf("Error: commitment of %s is empty or already in the %s tree\n", filename, roles[role])
+				os.Exit(1)
+			}
+			seen[coms[role][i].String()] = true
+		}
+	}
+
+	// levels[role][0] are the hashed leaves of the role, levels[role][depth] its root
+	levels := make([][][]*big.Int, len(roles))
+	for role := range roles {
+		levels[role] = make([][]*big.Int, *depth+1)
+		levels[role][0] = make([]*big.Int, len(coms[role]))
+		for i, com := range coms[role] {
+			leaf, err := hashFieldElements(commitHash, com)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			levels[role][0][i] = leaf
+		}
+		for d := 1; d <= *depth; d++ {
+			levels[role][d] = make([]*big.Int, len(levels[role][d-1])/2)
+			for i := range levels[role][d] {
+				node, err := hashFieldElements(commitHash, levels[role][d-1][2*i], levels[role][d-1][2*i+1])
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				levels[role][d][i] = node
+			}
+		}
+	}
+	userRoot, adminRoot := levels[circuits.RoleUser][*depth][0], levels[circuits.RoleAdmin][*depth][0]
+	root, err := hashFieldElements(commitHash, userRoot, adminRoot)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// the path of a key ends with the root of the other role
+	var members []MemberPath
+	for role, filenames := range files {
+		for i := range filenames {
+			path := make([]string, *depth+1)
+			index := i
+			for d := 0; d < *depth; d++ {
+				path[d] = hex.EncodeToString(levels[role][d][index^1].Bytes())
+				index >>= 1
+			}
+			path[*depth] = hex.EncodeToString(levels[1-role][*depth][0].Bytes())
+			members = append(members, MemberPath{
+				Com:      hex.EncodeToString(coms[role][i].Bytes()),
+				Role:     roles[role],
+				Position: i,
+				Path:     path,
+			})
+		}
+	}
+
+	Output := RoleTree{
+		Hash:      commitHash,
+		Depth:     *depth,
+		Root:      hex.EncodeToString(root.Bytes()),
+		UserRoot:  hex.EncodeToString(userRoot.Bytes()),
+		AdminRoot: hex.EncodeToString(adminRoot.Bytes()),
+		Members:   members,
+	}
+
+	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling role tree JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeToFile(*output, bytes.NewReader(OutputJSON))
+	fmt.Printf("Root: %s\n", Output.Root)
+}
+
+// splitFiles splits a comma-separated list of files, an empty list having no file.
+func splitFiles(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// hashFieldElements hashes field elements written as 32-byte big-endian
+// integers, as the in-circuit Merkle proof does.
+func hashFieldElements(name string, values ...*big.Int) (*big.Int, error) {
+	h, err := circuits.NewNativeCommitmentHasher(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		buf := make([]byte, 32)
+		_, err = h.Write(v.FillBytes(buf))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case *InputWithCommit: // For the JSON input
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"crypto/rand"
+
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+	cryptosha3 "golang.org/x/crypto/sha3"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+
+	"circuits"
+	"zkbackend"
+)
+
+// InputWithCommit struct for JSON serialization of the commitment, as written by pub_commit.go.
+type InputWithCommit struct {
+	MsgHash string `json:"msgHash"` // Hex string of msgHash
+	R       string `json:"r"`       // Hex string of r
+	S       string `json:"s"`       // Hex string of s
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account, filled at proving time
+	Account string `json:"account"` // Hex string of the account using the proof, filled at proving time
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1 or p256
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+}
+
+// RotationInput struct for JSON serialization of the rotation witness inputs.
+type RotationInput struct {
+	MsgHash  string `json:"msgHash"`  // Hex string of the rotation message hash, to be signed
+	R        string `json:"r"`        // Hex string of signature R
+	S        string `json:"s"`        // Hex string of signature S
+	PubX     string `json:"pubX"`     // Hex string of public key X
+	PubY     string `json:"pubY"`     // Hex string of public key Y
+	Address  string `json:"address"`  // Hex string of address
+	Nonce    string `json:"nonce"`    // Hex string of the old nonce
+	NewNonce string `json:"newNonce"` // Hex string of the new nonce
+	ChainID  string `json:"chainId"`  // Hex string of the chain id of the account
+	Account  string `json:"account"`  // Hex string of the account
+	Com      string `json:"com"`      // Hex string of the old commitment
+	NewCom   string `json:"newCom"`   // Hex string of the new commitment
+	Hash     string `json:"hash"`     // Hash of the commitment, mimc or poseidon2
+	Curve    string `json:"curve"`    // Curve of the signer, secp256k1 or p256
+}
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"` // Hash of the public key commitment, mimc or poseidon2
+	Curve      string `json:"curve"`      // Curve of the signer, secp256k1 or p256
+	Backend    string `json:"backend"`    // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`     // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	chainID := flag.String("chainId", "", "hex chain id of the account, to prepare the rotation")
+	account := flag.String("account", "", "hex address of the account, to prepare the rotation")
+	r := flag.String("r", "", "hex signature r of the rotation message, to prove the rotation")
+	s := flag.String("s", "", "hex signature s of the rotation message, to prove the rotation")
+	flag.Parse()
+
+	switch {
+	case *chainID != "" && *account != "":
+		prepareRotation(*chainID, *account)
+	case *r != "" && *s != "":
+		proveRotation(*r, *s)
+	default:
+		fmt.Println("Usage: go run rotate.go -chainId <chainId> -account <account>")
+		fmt.Println("       go run rotate.go -r <r> -s <s>")
+		os.Exit(1)
+	}
+}
+
+// prepareRotation draws the new nonce of the commitment of witness_input.json
+// and writes the rotation message to sign in rotation_input.json.
+func prepareRotation(chainIDHex, accountHex string) {
+	var current InputWithCommit
+	err := readFromFile("witness_input.json", &current)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	if current.Version != 0 {
+		fmt.Printf("Error: only the single signer circuit supports version 1 commitments, commit with pub_commit.go -version 0\n")
+		os.Exit(1)
+	}
+	if current.Hash == "" {
+		current.Hash = "mimc"
+	}
+	if current.Curve == "" {
+		current.Curve = "secp256k1"
+	}
+	address, err := hex.DecodeString(current.Address)
+	if err != nil {
+		fmt.Printf("Error decoding Address hex: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 160 bits, as pub_commit.go
+	newNonce := make([]byte, 20)
+	_, err = rand.Read(newNonce)
+	if err != nil {
+		panic(err)
+	}
+	h, err := circuits.NewNativeCommitmentHasher(current.Hash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	h.Write(address)
+	h.Write(newNonce)
+	newCom := h.Sum(nil)
+
+	msgHash := rotationMessage(
+		mustDecodeHex("ChainID", chainIDHex),
+		mustDecodeHex("Account", accountHex),
+		mustDecodeHex("Com", current.Com),
+		new(big.Int).SetBytes(newCom),
+	)
+
+	Output := RotationInput{
+		MsgHash:  hex.EncodeToString(msgHash),
+		PubX:     current.PubX,
+		PubY:     current.PubY,
+		Address:  current.Address,
+		Nonce:    current.Nonce,
+		NewNonce: hex.EncodeToString(newNonce),
+		ChainID:  chainIDHex,
+		Account:  accountHex,
+		Com:      current.Com,
+		NewCom:   hex.EncodeToString(newCom),
+		Hash:     current.Hash,
+		Curve:    current.Curve,
+	}
+	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling rotation input JSON: %v\n", err)
+		os.Exit(1)
+	}
+	writeToFile("rotation_input.json", bytes.NewReader(OutputJSON))
+
+	fmt.Printf("New commitment: %s\n", Output.NewCom)
+	fmt.Printf("Sign the rotation hash %s and run: go run rotate.go -r <r> -s <s>\n", Output.MsgHash)
+}
+
+// rotationMessage returns keccak256(tag || chainId || account || oldCom || newCom),
+// the integers being encoded as 32-byte big-endian, as in the circuit.
+func rotationMessage(chainID, account, oldCom, newCom *big.Int) []byte {
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write([]byte(circuits.RotationTag))
+	for _, v := range []*big.Int{chainID, account, oldCom, newCom} {
+		keccak.Write(v.FillBytes(make([]byte, 32)))
+	}
+	return keccak.Sum(nil)
+}
+
+// proveRotation proves the rotation of rotation_input.json signed with (r, s),
+// and writes the new commitment in rotated_witness_input.json.
+func proveRotation(r, s string) {
+	var loadedInput RotationInput
+	err := readFromFile("rotation_input.json", &loadedInput)
+	if err != nil {
+		fmt.Printf("Error reading rotation_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	loadedInput.R = r
+	loadedInput.S = s
+	curve := loadedInput.Curve
+
+	// the setup records its options and its backend, PLONK for setups
+	// older than the backend field
+	config := SetupConfig{CommitHash: "mimc", Curve: curve}
+	configName := artifactName(curve, "rotation_setup_config.json")
+	if _, statErr := os.Stat(configName); statErr == nil {
+		err = readFromFile(configName, &config)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", configName, err)
+			os.Exit(1)
+		}
+	}
+	b, err := zkbackend.New(config.Backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Unsafe {
+		fmt.Printf("Warning: %s is a test setup, its proofs must not be trusted on-chain\n", configName)
+	}
+
+	// 1. Read back the compiled circuit and the keys
+	loadedR1CS := b.NewCS()
+	err = readFromFile(artifactName(curve, "rotation_r1cs.bin"), loadedR1CS)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "rotation_r1cs.bin"), err)
+		os.Exit(1)
+	}
+	fmt.Printf("Read %s (Constraints: %d)\n", artifactName(curve, "rotation_r1cs.bin"), loadedR1CS.GetNbConstraints())
+	loadedPK := b.NewProvingKey()
+	err = readFromFile(artifactName(curve, "rotation_proving_key.bin"), loadedPK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "rotation_proving_key.bin"), err)
+		os.Exit(1)
+	}
+	loadedVK := b.NewVerifyingKey()
+	err = readFromFile(artifactName(curve, "rotation_verifying_key.bin"), loadedVK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(curve, "rotation_verifying_key.bin"), err)
+		os.Exit(1)
+	}
+
+	// 2. the commitments must have been computed with the hash of the setup
+	if loadedInput.Hash != config.CommitHash || loadedInput.Curve != config.Curve {
+		fmt.Printf("Error: commitment on %s with %s, setup uses %s with %s\n", loadedInput.Curve, loadedInput.Hash, config.Curve, config.CommitHash)
+		os.Exit(1)
+	}
+
+	// 3. Create the witness
+	var witnessFull witness.Witness
+	switch curve {
+	case "secp256k1":
+		witnessFull, err = newRotationWitness[emulated.Secp256k1Fp, emulated.Secp256k1Fr](loadedInput)
+	case "p256":
+		witnessFull, err = newRotationWitness[emulated.P256Fp, emulated.P256Fr](loadedInput)
+	default:
+		err = fmt.Errorf("unknown curve %q", curve)
+	}
+	if err != nil {
+		fmt.Printf("Error creating witness: %v\n", err)
+		os.Exit(1)
+	}
+	publicWitness, err := witnessFull.Public()
+	if err != nil {
+		fmt.Printf("Error getting public witness: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 4. Prove and verify
+	fmt.Println("\n--- Proving rotation ---")
+	startProve := time.Now()
+	proof, err := b.Prove(loadedR1CS, loadedPK, witnessFull)
+	if err != nil {
+		fmt.Printf("Error generating proof, is the rotation hash signed by the committed key? %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Proof GENERATED (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
+	err = b.Verify(proof, loadedVK, publicWitness)
+	if err != nil {
+		fmt.Printf("Verification FAILED: %v\n", err)
+		os.Exit(1)
+	}
+	proofBytes, err := b.MarshalProof(proof)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Export the Solidity verifier test
+	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
+	testName := rotationVerifierName(curve)
+	verifierTestFile, err := os.Create("solidity/test/" + testName + ".t.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/test/%s.t.sol: %v\n", testName, err)
+		os.Exit(1)
+	}
+	defer verifierTestFile.Close()
+
+	// header
+	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.25;
+
+import {Test, console} from "forge-std/Test.sol";
+import {PlonkVerifier} from "../src/` + testName + `.sol";
+
+contract ` + testName + `Test is Test {
+    PlonkVerifier ZkRotation;
+
+    // public inputs: chainId, account, old commitment, new commitment
+    uint256 constant OLD_COM_INDEX = 2;
+    uint256 constant NEW_COM_INDEX = 3;
+    uint256 commitment = 0x` + loadedInput.Com + `;
+
+    function setUp() public {
+        ZkRotation = new PlonkVerifier();
+    }
+
+    // rotate replaces the registered commitment, as the account does
+    function rotate(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (inputs[OLD_COM_INDEX] != commitment) return false;
+        if (!ZkRotation.Verify(proof, inputs)) return false;
+        commitment = inputs[NEW_COM_INDEX];
+        return true;
+    }
+
+    function test_rotationPlonk() public {
+`))
+
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";`))
+	verifierTestFile.Write([]byte("\n"))
+
+	PI := fmt.Sprintf("%v", publicWitness.Vector())
+
+	verifierTestFile.Write([]byte("uint256[4] memory public_inputs = " + PI + ";\n"))
+
+	// footer
+	verifierTestFile.Write([]byte(`
+        uint256[] memory inputs = new uint256[](4);
+        for (uint i = 0; i < 4; i++) inputs[i] = uint256(public_inputs[i]);
+
+        bool res = rotate(proof, inputs);
+        assertTrue(res);
+        console.log(res);
+        assertEq(commitment, inputs[NEW_COM_INDEX]);
+
+        // the old commitment is no longer registered, the rotation cannot be replayed
+        assertFalse(rotate(proof, inputs));
+    }
+}
+`))
+	fmt.Printf("Successfully exported solidity/test/%s.t.sol\n", testName)
+
+	// 6. The new commitment replaces witness_input.json once registered
+	Output := InputWithCommit{
+		PubX:    loadedInput.PubX,
+		PubY:    loadedInput.PubY,
+		Address: loadedInput.Address,
+		Nonce:   loadedInput.NewNonce,
+		Com:     loadedInput.NewCom,
+		Hash:    loadedInput.Hash,
+		Curve:   loadedInput.Curve,
+	}
+	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling witness input JSON: %v\n", err)
+		os.Exit(1)
+	}
+	writeToFile("rotated_witness_input.json", bytes.NewReader(OutputJSON))
+	fmt.Println("Replace witness_input.json with rotated_witness_input.json once the rotation is accepted by the account.")
+
+	fmt.Print("\n\n\n=======================\nPROOF and PUBLIC INPUTS\n=======================\n0x", hexutil.Encode(proofBytes)[2:], " \"", publicWitness.Vector(), "\"\n")
+}
+
+// newRotationWitness returns the witness of the rotation circuit on the curve
+// of the signer.
+func newRotationWitness[T, S emulated.FieldParams](in RotationInput) (witness.Witness, error) {
+	assignment := circuits.RotationCircuit[T, S]{
+		Sig: ecdsa.Signature[S]{
+			R: emulated.ValueOf[S](mustDecodeHex("R", in.R)),
+			S: emulated.ValueOf[S](mustDecodeHex("S", in.S)),
+		},
+		Pub: ecdsa.PublicKey[T, S]{
+			X: emulated.ValueOf[T](mustDecodeHex("PubX", in.PubX)),
+			Y: emulated.ValueOf[T](mustDecodeHex("PubY", in.PubY)),
+		},
+		Address:  mustDecodeHex("Address", in.Address),
+		Nonce:    mustDecodeHex("Nonce", in.Nonce),
+		NewNonce: mustDecodeHex("NewNonce", in.NewNonce),
+		ChainID:  mustDecodeHex("ChainID", in.ChainID),
+		Account:  mustDecodeHex("Account", in.Account),
+		OldCom:   mustDecodeHex("Com", in.Com),
+		NewCom:   mustDecodeHex("NewCom", in.NewCom),
+
+		CommitHash: in.Hash,
+	}
+	return frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+}
+
+// mustDecodeHex decodes a hex string into a big.Int, and exits on error.
+func mustDecodeHex(name, s string) *big.Int {
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		fmt.Printf("Error decoding %s hex: %v\n", name, err)
+		os.Exit(1)
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// artifactName returns the file name of a setup artifact for the signer curve.
+// The secp256k1 artifacts keep their historical names.
+func artifactName(curve, name string) string {
+	if curve == "" || curve == "secp256k1" {
+		return name
+	}
+	return curve + "_" + name
+}
+
+// rotationVerifierName returns the name of the Solidity rotation verifier for
+// the signer curve.
+func rotationVerifierName(curve string) string {
+	if curve == "p256" {
+		return "P256RotationVerifier"
+	}
+	return "RotationVerifier"
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	case *RotationInput, *InputWithCommit: // For the JSON inputs
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	case *SetupConfig: // For the setup options
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
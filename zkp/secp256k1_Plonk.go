@@ -9,6 +9,7 @@ import (
 
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"math/big"
 	"os"
@@ -16,48 +17,19 @@ import (
 	// Added for performance timing
 	"github.com/consensys/gnark-crypto/ecc"
 
-	// cryptoposeidon2 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
-	cryptomimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	cryptosha3 "golang.org/x/crypto/sha3"
 
 	cryptoecdsa "github.com/consensys/gnark-crypto/ecc/secp256k1/ecdsa"
 
-	"github.com/consensys/gnark/backend/plonk"
-	plonk_bn254 "github.com/consensys/gnark/backend/plonk/bn254"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/scs"
-	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
-	"github.com/consensys/gnark/std/hash/mimc"
 	"github.com/consensys/gnark/std/math/emulated"
 	"github.com/consensys/gnark/std/signature/ecdsa"
 
-	"github.com/consensys/gnark/test/unsafekzg"
+	"circuits"
+	"zkbackend"
 )
 
-// EcdsaCircuit defines the circuit structure as provided by you.
-type EcdsaCircuit[T, S emulated.FieldParams] struct {
-	Sig     ecdsa.Signature[S]    `gnark:",secret"` // signature
-	Msg     emulated.Element[S]   `gnark:",public"` // message
-	Pub     ecdsa.PublicKey[T, S] `gnark:",secret"` // now secret
-	Address frontend.Variable     `gnark:",secret"` // secret address
-	Nonce   frontend.Variable     `gnark:",secret"` // secret nonce
-	Com     frontend.Variable     `gnark:",public"` // public commitment
-}
-
-func (c *EcdsaCircuit[T, S]) Define(api frontend.API) error {
-	curveParams := sw_emulated.GetCurveParams[T]()
-	c.Pub.Verify(api, curveParams, &c.Msg, &c.Sig)
-
-	mimc, _ := mimc.NewMiMC(api)
-
-	// specify constraints
-	// mimc(preImage) == hash
-	mimc.Write(c.Address)
-	mimc.Write(c.Nonce)
-	api.AssertIsEqual(c.Com, mimc.Sum())
-	return nil
-}
-
 // ProveInputEcdsa struct for JSON serialization of witness inputs.
 type ProveInputEcdsa struct {
 	MsgHash string `json:"msgHash"` // Hex string of the message hash
@@ -67,12 +39,57 @@ type ProveInputEcdsa struct {
 	PubY    string `json:"pubY"`    // Hex string of public key Y
 	Address string `json:"address"` // Hex string of address
 	Nonce   string `json:"nonce"`   // Hex string of nonce
+	ChainID string `json:"chainId"` // Hex string of the chain id of the account
+	Account string `json:"account"` // Hex string of the account using the proof
 	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, secp256k1 (default) or p256
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
 }
 
-
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash    string `json:"commitHash"`    // Hash of the public key commitment, mimc or poseidon2
+	Curve         string `json:"curve"`         // Curve of the signer, secp256k1 or p256
+	LowS          bool   `json:"lowS"`          // The circuit rejects high-s signatures
+	CommitVersion int    `json:"commitVersion"` // Layout of the commitment, 0 or 1
+	Backend       string `json:"backend"`       // Proof system, plonk or groth16
+	Unsafe        bool   `json:"unsafe"`        // The secret randomness of the setup was drawn locally, for tests only
+}
 
 func main() {
+	commitHash := flag.String("hash", "mimc", "hash of the public key commitment: mimc or poseidon2")
+	lowS := flag.Bool("lowS", false, "reject in-circuit the high-s form of the signature")
+	commitVersion := flag.Int("commitVersion", 0, "commitment layout: 0 for h(address, nonce), 1 for h(tag, chainId, account, address, nonce)")
+	backendName := flag.String("backend", "plonk", "proof system: plonk or groth16")
+	srsFile := flag.String("srs", "", "powers-of-tau transcript of the PLONK SRS: a .ptau file, an Ignition transcript00.dat or a gnark kzg.SRS")
+	unsafe := flag.Bool("unsafe", false, "draw the secret randomness of the setup locally, for tests only")
+	flag.Parse()
+	if *commitVersion != 0 && *commitVersion != 1 {
+		fmt.Printf("Error: unknown commitment version %d\n", *commitVersion)
+		os.Exit(1)
+	}
+	b, err := zkbackend.New(*backendName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	// the verifying key is deployed on-chain, its secret randomness must come
+	// from a ceremony unless the setup is explicitly a test setup
+	switch {
+	case *srsFile != "" && b.Name() != "plonk":
+		fmt.Printf("Error: -srs is the SRS of PLONK, the Groth16 setup is specific to the circuit\n")
+		os.Exit(1)
+	case *srsFile != "":
+		b = zkbackend.Plonk{SRSFile: *srsFile}
+	case !*unsafe && b.Name() == "groth16":
+		fmt.Printf("Error: the Groth16 setup draws its secret randomness locally, pass -unsafe for a test setup\n")
+		os.Exit(1)
+	case !*unsafe:
+		fmt.Printf("Error: without -srs the PLONK setup draws its SRS locally, pass -srs <transcript> or -unsafe for a test setup\n")
+		os.Exit(1)
+	}
+
 	fmt.Println("--- Generating ECDSA circuit inputs and performing compliance check ---")
 
 	// 1. Off-circuit ECDSA signature generation (to get inputs for the circuit)
@@ -89,14 +106,15 @@ func main() {
 	r, s := new(big.Int), new(big.Int)
 	r.SetBytes(sig.R[:32])
 	s.SetBytes(sig.S[:32])
+	normalizeS[emulated.Secp256k1Fr](s)
 
 	//msgHash := sha256.Sum256(msg)
 
 	hash := cryptoecdsa.HashToInt(msg)
 
 	// check that the signature is correct
-	flag, _ := publicKey.Verify(sigBin, msg, nil)
-	if !flag {
+	valid, _ := publicKey.Verify(sigBin, msg, nil)
+	if !valid {
 		fmt.Printf("Invalid signature\n")
 	}
 
@@ -108,15 +126,33 @@ func main() {
 	// INSECURE
 	nonce := make([]byte, 31)
 	// Fill with cryptographically secure random data
-	_, err := rand.Read(nonce)
+	_, err = rand.Read(nonce)
 	if err != nil {
 		panic(err)
 	}
 
-	address := big.NewInt(12345).Bytes()
+	// Ethereum address of the signer, keccak256(X||Y)[12:]
+	keccak := cryptosha3.NewLegacyKeccak256()
+	keccak.Write(xBytes[:])
+	keccak.Write(yBytes[:])
+	address := keccak.Sum(nil)[12:]
+
+	// the account using the proof, here ZKNOX_SimpleHybrid7702ZK on Zircuit Garfield testnet
+	chainID := big.NewInt(48898)
+	account, _ := new(big.Int).SetString("d70bb0f082FCf522B25592fC8dE8D396e8289544", 16)
 
 	// PK Commitment
-	h := cryptomimc.NewMiMC()
+	h, err := circuits.NewNativeCommitmentHasher(*commitHash)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *commitVersion == 1 {
+		// h(tag, chainId, account, address, nonce)
+		for _, v := range []*big.Int{new(big.Int).SetBytes([]byte(circuits.CommitTagV1)), chainID, account} {
+			h.Write(v.FillBytes(make([]byte, 32)))
+		}
+	}
 	_, err = h.Write(address)
 	if err != nil {
 		panic(err)
@@ -135,7 +171,12 @@ func main() {
 		PubY:    hex.EncodeToString(yBytes[:]),    // Slice the temporary variable
 		Address: hex.EncodeToString(address[:]),
 		Nonce:   hex.EncodeToString(nonce[:]),
+		ChainID: hex.EncodeToString(chainID.Bytes()),
+		Account: hex.EncodeToString(account.Bytes()),
 		Com:     hex.EncodeToString(ComPK),
+		Hash:    *commitHash,
+		Curve:   "secp256k1",
+		Version: *commitVersion,
 	}
 
 	proveInputJSON, err := json.MarshalIndent(proveInput, "", "  ")
@@ -145,9 +186,10 @@ func main() {
 	}
 
 	// 3. Compile the circuit
-	circuit := EcdsaCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{}
+	circuit := circuits.Circuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{CommitHash: *commitHash, LowS: *lowS, CommitVersion: *commitVersion}
+	// Groth16 compiles the circuit to R1CS, PLONK to SCS
 	fmt.Printf("Compiling circuit...\n")
-	ecdsaR1CS, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit)
+	ecdsaR1CS, err := b.Compile(&circuit)
 	if err != nil {
 		fmt.Printf("Error compiling ECDSA circuit: %v\n", err)
 		os.Exit(1)
@@ -155,12 +197,11 @@ func main() {
 	fmt.Printf("BN254 circuit compiled with %d constraints",
 		ecdsaR1CS.GetNbConstraints())
 
-	// 4. Perform Groth16 setup
-	fmt.Printf("Starting Plonk setup...\n")
-	A, B, _ := unsafekzg.NewSRS(ecdsaR1CS)
-	ecdsaPK, ecdsaVK, err := plonk.Setup(ecdsaR1CS, A, B)
+	// 4. Perform the setup of the backend
+	fmt.Printf("Starting %s setup...\n", b.Name())
+	ecdsaPK, ecdsaVK, err := b.Setup(ecdsaR1CS)
 	if err != nil {
-		fmt.Printf("Error during Plonk setup for ECDSA: %v\n", err)
+		fmt.Printf("Error during %s setup for ECDSA: %v\n", b.Name(), err)
 		os.Exit(1)
 	}
 	fmt.Printf("Setup done.\n")
@@ -172,9 +213,14 @@ func main() {
 	pky_bigint := new(big.Int)
 	publicKey.A.Y.BigInt(pky_bigint)
 	address_bigint := new(big.Int).SetBytes(address)
+	nullifier, err := circuits.ComputeNullifier(*commitHash, nonce_bigint, circuits.ScalarLimbs[emulated.Secp256k1Fr](hash), chainID, account)
+	if err != nil {
+		fmt.Printf("Error computing nullifier: %v\n", err)
+		os.Exit(1)
+	}
 
 	// 5. Create the full witness for the circuit (includes private and public parts)
-	witnessCircuit := EcdsaCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+	witnessCircuit := circuits.Circuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
 		Sig: ecdsa.Signature[emulated.Secp256k1Fr]{
 			R: emulated.ValueOf[emulated.Secp256k1Fr](r),
 			S: emulated.ValueOf[emulated.Secp256k1Fr](s),
@@ -184,9 +230,14 @@ func main() {
 			X: emulated.ValueOf[emulated.Secp256k1Fp](publicKey.A.X),
 			Y: emulated.ValueOf[emulated.Secp256k1Fp](publicKey.A.Y),
 		},
-		Address: address_bigint,
-		Nonce:   nonce_bigint,
-		Com:     compk_bigint,
+		Address:   address_bigint,
+		Nonce:     nonce_bigint,
+		ChainID:   chainID,
+		Account:   account,
+		Nullifier: nullifier,
+		Com:       compk_bigint,
+
+		CommitHash: *commitHash,
 	}
 	witnessFull, err := frontend.NewWitness(&witnessCircuit, ecc.BN254.ScalarField())
 	if err != nil {
@@ -201,11 +252,18 @@ func main() {
 	fmt.Printf("witness creation done.\n")
 
 	// 6. Write outputs to files (same as before)
-	writeToFile("r1cs.bin", ecdsaR1CS)
-	writeToFile("proving_key.bin", ecdsaPK)
-	writeToFile("verifying_key.bin", ecdsaVK)
+	writeToFile(artifactName(*backendName, "secp256k1", "r1cs.bin"), ecdsaR1CS)
+	writeToFile(artifactName(*backendName, "secp256k1", "proving_key.bin"), ecdsaPK)
+	writeToFile(artifactName(*backendName, "secp256k1", "verifying_key.bin"), ecdsaVK)
 	writeToFile("witness_input.json", bytes.NewReader(proveInputJSON))
 
+	configJSON, err := json.MarshalIndent(SetupConfig{CommitHash: *commitHash, Curve: "secp256k1", LowS: *lowS, CommitVersion: *commitVersion, Backend: *backendName, Unsafe: *srsFile == ""}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling setup config JSON: %v\n", err)
+		os.Exit(1)
+	}
+	writeToFile(artifactName(*backendName, "secp256k1", "setup_config.json"), bytes.NewReader(configJSON))
+
 	fmt.Println("\nAll input files generated successfully for CGO wrapper.")
 
 	fmt.Println(publicWitness)
@@ -213,32 +271,79 @@ func main() {
 	// 7. Perform a compliance check: Prove and Verify
 	fmt.Println("\n--- Performing compliance check (Prove & Verify within generate_input.go) ---")
 
-	// Prove
+	// Prove and verify
 	startProve := time.Now()
-	proof, err := plonk.Prove(ecdsaR1CS, ecdsaPK, witnessFull)
+	proof, err := b.Prove(ecdsaR1CS, ecdsaPK, witnessFull)
 	if err != nil {
 		fmt.Printf("Compliance check: Error generating proof: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Compliance check: Proof generated (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
-
-	// Verify
-	startVerify := time.Now()
-	err = plonk.Verify(proof, ecdsaVK, publicWitness)
+	err = b.Verify(proof, ecdsaVK, publicWitness)
 	if err != nil {
 		fmt.Printf("Compliance check: Verification FAILED: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Compliance check: Verification SUCCEEDED (%.1fms)!\n", float64(time.Since(startVerify).Milliseconds()))
+	fmt.Printf("Compliance check: Proof generated and verified (%.1fms).\n", float64(time.Since(startProve).Milliseconds()))
 	fmt.Println("Compliance check PASSED. Generated inputs are valid.")
 
 	// 8. Test the ReadFromFile functionality
-	testReadFromFile()
+	testReadFromFile(b)
 
 	fmt.Println("\nAll input files generated successfully for CGO wrapper.")
 
 }
 
+// groth16VerifyCall decodes a proof of zkbackend.Groth16.MarshalProof and
+// calls Verifier.verifyProof, whose arguments depend on the number of
+// commitments of the circuit: 8 words without commitment, otherwise 2 words
+// per commitment and 2 for their proof of knowledge after the 8 words.
+func groth16VerifyCall(proofBytes []byte, nbInputs int) string {
+	call := fmt.Sprintf("        uint256[%d] memory input;\n        for (uint i = 0; i < %d; i++) input[i] = inputs[i];\n", nbInputs, nbInputs)
+	if len(proofBytes) == 8*32 {
+		return "        uint256[8] memory p = abi.decode(proof, (uint256[8]));\n" + call +
+			"        try ZkK1.verifyProof(p, input) {} catch { return false; }\n"
+	}
+	nbWords := (len(proofBytes) - 10*32) / 32
+	return fmt.Sprintf(`        (uint256[8] memory p, uint256[%d] memory commitments, uint256[2] memory commitmentPok) =
+            abi.decode(proof, (uint256[8], uint256[%d], uint256[2]));
+`, nbWords, nbWords) + call + "        try ZkK1.verifyProof(p, commitments, commitmentPok, input) {} catch { return false; }\n"
+}
+
+// artifactName returns the file name of a setup artifact for the backend and
+// the signer curve. The secp256k1 PLONK artifacts keep their historical names,
+// the Groth16 artifacts are prefixed so that they are never mixed with them.
+func artifactName(backendName, curve, name string) string {
+	if curve != "" && curve != "secp256k1" {
+		name = curve + "_" + name
+	}
+	if backendName == "groth16" {
+		name = "groth16_" + name
+	}
+	return name
+}
+
+// verifierName returns the name of the Solidity verifier for the backend and
+// the signer curve.
+func verifierName(backendName, curve string) string {
+	name := "Verifier"
+	if curve == "p256" {
+		name = "P256Verifier"
+	}
+	if backendName == "groth16" {
+		name = "Groth16" + name
+	}
+	return name
+}
+
+// normalizeS replaces s by n - s when s > (n-1)/2. (r, n - s) is also a valid
+// signature, so the witness is the same for both forms of the signature.
+func normalizeS[S emulated.FieldParams](s *big.Int) {
+	var fr S
+	if s.Cmp(new(big.Int).Rsh(fr.Modulus(), 1)) > 0 {
+		s.Sub(fr.Modulus(), s)
+	}
+}
+
 // writeToFile is a helper to serialize and write gnark objects or byte readers to files.
 func writeToFile(filename string, data interface{}) {
 	file, err := os.Create(filename)
@@ -292,35 +397,38 @@ func readFromFile(filename string, data interface{}) error {
 }
 
 // testReadFromFile reads the generated files back and performs a verification.
-func testReadFromFile() {
+func testReadFromFile(b zkbackend.Backend) {
 	fmt.Println("\n--- Testing ReadFromFile and re-verification ---")
 
+	backendName := b.Name()
+	loadedR1CS, loadedPK, loadedVK := b.NewCS(), b.NewProvingKey(), b.NewVerifyingKey()
+	r1csName := artifactName(backendName, "secp256k1", "r1cs.bin")
+	pkName := artifactName(backendName, "secp256k1", "proving_key.bin")
+	vkName := artifactName(backendName, "secp256k1", "verifying_key.bin")
+
 	// 1. Read back the compiled circuit
-	loadedR1CS := plonk.NewCS(ecc.BN254)
-	err := readFromFile("r1cs.bin", loadedR1CS)
+	err := readFromFile(r1csName, loadedR1CS)
 	if err != nil {
-		fmt.Printf("Error reading r1cs.bin: %v\n", err)
+		fmt.Printf("Error reading %s: %v\n", r1csName, err)
 		os.Exit(1)
 	}
-	fmt.Printf("Read r1cs.bin (Constraints: %d)\n", loadedR1CS.GetNbConstraints())
+	fmt.Printf("Read %s (Constraints: %d)\n", r1csName, loadedR1CS.GetNbConstraints())
 
 	// 2. Read back the proving key
-	loadedPK := plonk.NewProvingKey(ecc.BN254)
-	err = readFromFile("proving_key.bin", loadedPK)
+	err = readFromFile(pkName, loadedPK)
 	if err != nil {
-		fmt.Printf("Error reading proving_key.bin: %v\n", err)
+		fmt.Printf("Error reading %s: %v\n", pkName, err)
 		os.Exit(1)
 	}
-	fmt.Println("Read proving_key.bin")
+	fmt.Println("Read", pkName)
 
 	// 3. Read back the verifying key
-	loadedVK := plonk.NewVerifyingKey(ecc.BN254)
-	err = readFromFile("verifying_key.bin", loadedVK)
+	err = readFromFile(vkName, loadedVK)
 	if err != nil {
-		fmt.Printf("Error reading verifying_key.bin: %v\n", err)
+		fmt.Printf("Error reading %s: %v\n", vkName, err)
 		os.Exit(1)
 	}
-	fmt.Println("Read verifying_key.bin")
+	fmt.Println("Read", vkName)
 
 	// 4. Read back the prove input JSON
 	var loadedProveInput ProveInputEcdsa
@@ -372,17 +480,35 @@ func testReadFromFile() {
 		fmt.Printf("Error decoding Com hex: %v\n", err)
 		os.Exit(1)
 	}
+	chainIDBytes, err := hex.DecodeString(loadedProveInput.ChainID)
+	if err != nil {
+		fmt.Printf("Error decoding ChainID hex: %v\n", err)
+		os.Exit(1)
+	}
+	accountBytes, err := hex.DecodeString(loadedProveInput.Account)
+	if err != nil {
+		fmt.Printf("Error decoding Account hex: %v\n", err)
+		os.Exit(1)
+	}
 
 	rLoaded := new(big.Int).SetBytes(rBytes)
 	sLoaded := new(big.Int).SetBytes(sBytes)
+	normalizeS[emulated.Secp256k1Fr](sLoaded)
 	pubXLoaded := new(big.Int).SetBytes(pubXBytes)
 	pubYLoaded := new(big.Int).SetBytes(pubYBytes)
 	addressLoaded := new(big.Int).SetBytes(addressBytes)
 	nonceLoaded := new(big.Int).SetBytes(nonceBytes)
 	comLoaded := new(big.Int).SetBytes(comBytes)
+	chainIDLoaded := new(big.Int).SetBytes(chainIDBytes)
+	accountLoaded := new(big.Int).SetBytes(accountBytes)
+	nullifierLoaded, err := circuits.ComputeNullifier(loadedProveInput.Hash, nonceLoaded, circuits.ScalarLimbs[emulated.Secp256k1Fr](new(big.Int).SetBytes(msgHashBytes)), chainIDLoaded, accountLoaded)
+	if err != nil {
+		fmt.Printf("Error computing nullifier: %v\n", err)
+		os.Exit(1)
+	}
 
 	// 5. Create a new witness using the loaded input data
-	witnessCircuitLoaded := EcdsaCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+	witnessCircuitLoaded := circuits.Circuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
 		Sig: ecdsa.Signature[emulated.Secp256k1Fr]{
 			R: emulated.ValueOf[emulated.Secp256k1Fr](rLoaded),
 			S: emulated.ValueOf[emulated.Secp256k1Fr](sLoaded),
@@ -392,9 +518,12 @@ func testReadFromFile() {
 			X: emulated.ValueOf[emulated.Secp256k1Fp](pubXLoaded),
 			Y: emulated.ValueOf[emulated.Secp256k1Fp](pubYLoaded),
 		},
-		Address: addressLoaded,
-		Nonce:   nonceLoaded,
-		Com:     comLoaded,
+		Address:   addressLoaded,
+		Nonce:     nonceLoaded,
+		ChainID:   chainIDLoaded,
+		Account:   accountLoaded,
+		Nullifier: nullifierLoaded,
+		Com:       comLoaded,
 	}
 	witnessFullLoaded, err := frontend.NewWitness(&witnessCircuitLoaded, ecc.BN254.ScalarField())
 	if err != nil {
@@ -410,24 +539,25 @@ func testReadFromFile() {
 	// 6. Perform a new proof and verification using the loaded artifacts
 	fmt.Println("\n--- Proving and Verifying with loaded artifacts ---")
 
-	// Prove
+	// Prove and verify
 	startProveLoaded := time.Now()
-	proofLoaded, err := plonk.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
+	proofLoaded, err := b.Prove(loadedR1CS, loadedPK, witnessFullLoaded)
 	if err != nil {
 		fmt.Printf("Verification from loaded files: Error generating proof: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Verification from loaded files: Proof generated (%.1fms).\n", float64(time.Since(startProveLoaded).Milliseconds()))
-
-	// Verify
-	startVerifyLoaded := time.Now()
-	err = plonk.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
+	err = b.Verify(proofLoaded, loadedVK, publicWitnessLoaded)
 	if err != nil {
 		fmt.Printf("Verification from loaded files: Verification FAILED: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Verification from loaded files: Verification SUCCEEDED (%.1fms)!\n", float64(time.Since(startVerifyLoaded).Milliseconds()))
+	fmt.Printf("Verification from loaded files: Proof generated and verified (%.1fms)!\n", float64(time.Since(startProveLoaded).Milliseconds()))
 	fmt.Println("ReadFromFile test PASSED. Loaded artifacts are valid and functional.")
+	proofBytes, err := b.MarshalProof(proofLoaded)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// =========================================================================
 	// Export Solidity Verifier and Calldata
@@ -435,64 +565,88 @@ func testReadFromFile() {
 
 	// 8. Export the Solidity verifier contract
 	fmt.Println("\n--- Exporting Solidity Verifier ---")
-	verifierFile, err := os.Create("solidity/src/Verifier.sol")
+	verifierPath := "solidity/src/" + verifierName(backendName, "secp256k1") + ".sol"
+	verifierFile, err := os.Create(verifierPath)
 	if err != nil {
-		fmt.Printf("Error creating solidity/src/Verifier.sol: %v\n", err)
+		fmt.Printf("Error creating %s: %v\n", verifierPath, err)
 		os.Exit(1)
 	}
 	defer verifierFile.Close()
-	err = loadedVK.ExportSolidity(verifierFile)
+	err = b.ExportSolidity(loadedVK, verifierFile)
 	if err != nil {
 		fmt.Printf("Error exporting solidity verifier: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Successfully exported solidty/src/Verifier.sol")
+	fmt.Printf("Successfully exported %s\n", verifierPath)
 
 	// 9. Export the Solidity verifier test
 	fmt.Println("\n--- Exporting Solidity Verifier Test ---")
-	verifierTestFile, err := os.Create("solidity/test/Verifier.t.sol")
+	verifierTestPath := "solidity/test/" + verifierName(backendName, "secp256k1") + ".t.sol"
+	verifierTestFile, err := os.Create(verifierTestPath)
 	if err != nil {
-		fmt.Printf("Error creating solidity/test/Verifier.t.sol: %v\n", err)
+		fmt.Printf("Error creating %s: %v\n", verifierTestPath, err)
 		os.Exit(1)
 	}
 	defer verifierTestFile.Close()
 
+	// the PLONK verifier returns the result, the Groth16 one reverts on an
+	// invalid proof and takes the proof, its commitment and the inputs apart
+	verifierContract, testName := "PlonkVerifier", "k1Plonk"
+	verifyCall := "        if (!ZkK1.Verify(proof, inputs)) return false;\n"
+	if backendName == "groth16" {
+		verifierContract, testName = "Verifier", "k1Groth16"
+		verifyCall = groth16VerifyCall(proofBytes, 8)
+	}
+
 	// header
 	verifierTestFile.Write([]byte(`// SPDX-License-Identifier: UNLICENSED
 pragma solidity ^0.8.13;
 
 import {Test, console} from "forge-std/Test.sol";
-import {PlonkVerifier} from "../src/Verifier.sol";
+import {` + verifierContract + `} from "../src/` + verifierName(backendName, "secp256k1") + `.sol";
 
-contract VerifierTest is Test {
-    PlonkVerifier ZkK1;
+contract ` + verifierName(backendName, "secp256k1") + `Test is Test {
+    ` + verifierContract + ` ZkK1;
+
+    // public inputs: msg (4 limbs), chainId, account, nullifier, commitment
+    uint256 constant NULLIFIER_INDEX = 6;
+    mapping(uint256 => bool) usedNullifiers;
 
     function setUp() public {
-        ZkK1 = new PlonkVerifier();
+        ZkK1 = new ` + verifierContract + `();
+    }
+
+    // useProof accepts a proof only once, as the account does
+    function useProof(bytes memory proof, uint256[] memory inputs) internal returns (bool) {
+        if (usedNullifiers[inputs[NULLIFIER_INDEX]]) return false;
+` + verifyCall + `        usedNullifiers[inputs[NULLIFIER_INDEX]] = true;
+        return true;
     }
 
-    function test_k1Plonk() public view {
+    function test_` + testName + `() public {
 `))
 
-	Proof := proofLoaded.(*plonk_bn254.Proof)
-	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(Proof.MarshalSolidity())[2:] + `";`))
+	verifierTestFile.Write([]byte(`bytes memory proof = hex"` + hexutil.Encode(proofBytes)[2:] + `";`))
 	verifierTestFile.Write([]byte("\n"))
 
 	PI := fmt.Sprintf("%v", publicWitnessLoaded.Vector())
 
-	verifierTestFile.Write([]byte("uint64[5] memory public_inputs = " + PI + ";\n"))
+	verifierTestFile.Write([]byte("uint256[8] memory public_inputs = " + PI + ";\n"))
 
 	// footer
 	verifierTestFile.Write([]byte(`
-        uint256[] memory inputs = new uint256[](5);
-        for (uint i = 0; i < 5; i++) inputs[i] = uint256(public_inputs[i]);
+        uint256[] memory inputs = new uint256[](8);
+        for (uint i = 0; i < 8; i++) inputs[i] = uint256(public_inputs[i]);
 
-        bool res = ZkK1.Verify(proof, inputs);
+        bool res = useProof(proof, inputs);
         assertTrue(res);
         console.log(res);
+
+        // the nullifier is spent, the same proof cannot be replayed
+        assertFalse(useProof(proof, inputs));
     }
 }
 `))
-	fmt.Println("Successfully exported solidty/src/Verifier.sol")
+	fmt.Printf("Successfully exported %s\n", verifierTestPath)
 
 }
@@ -4,56 +4,84 @@ import (
 	"bytes"
 	"io"
 
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 
-	// Added for performance timing
-	"github.com/consensys/gnark-crypto/ecc"
-
-	// cryptoposeidon2 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
-
-	"github.com/consensys/gnark/backend/plonk"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/scs"
-	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
-	"github.com/consensys/gnark/std/hash/mimc"
 	"github.com/consensys/gnark/std/math/emulated"
-	"github.com/consensys/gnark/std/signature/ecdsa"
 
-	"github.com/consensys/gnark/test/unsafekzg"
+	"circuits"
+	"zkbackend"
 )
 
-// Circuit defines the circuit structure as provided by you.
-type Circuit[T, S emulated.FieldParams] struct {
-	Sig     ecdsa.Signature[S]    `gnark:",secret"` // signature
-	Msg     emulated.Element[S]   `gnark:",public"` // message
-	Pub     ecdsa.PublicKey[T, S] `gnark:",secret"` // now secret
-	Address frontend.Variable     `gnark:",secret"` // secret address
-	Nonce   frontend.Variable     `gnark:",secret"` // secret nonce
-	Com     frontend.Variable     `gnark:",public"` // public commitment
-}
-
-func (c *Circuit[T, S]) Define(api frontend.API) error {
-	curveParams := sw_emulated.GetCurveParams[T]()
-	c.Pub.Verify(api, curveParams, &c.Msg, &c.Sig)
-
-	mimc, _ := mimc.NewMiMC(api)
-
-	// specify constraints
-	// mimc(preImage) == hash
-	mimc.Write(c.Address)
-	mimc.Write(c.Nonce)
-	api.AssertIsEqual(c.Com, mimc.Sum())
-	return nil
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash    string `json:"commitHash"`    // Hash of the public key commitment, mimc or poseidon2
+	Curve         string `json:"curve"`         // Curve of the signer, secp256k1 or p256
+	LowS          bool   `json:"lowS"`          // The circuit rejects high-s signatures
+	CommitVersion int    `json:"commitVersion"` // Layout of the commitment, 0 or 1
+	Expiry        bool   `json:"expiry"`        // The proof carries a validUntil public input, signed with the message
+	Backend       string `json:"backend"`       // Proof system, plonk or groth16
+	Unsafe        bool   `json:"unsafe"`        // The secret randomness of the setup was drawn locally, for tests only
 }
 
 func main() {
+	commitHash := flag.String("hash", "mimc", "hash of the public key commitment: mimc or poseidon2")
+	curve := flag.String("curve", "secp256k1", "curve of the signer: secp256k1 or p256")
+	lowS := flag.Bool("lowS", false, "reject in-circuit the high-s form of the signature")
+	commitVersion := flag.Int("commitVersion", 0, "commitment layout: 0 for h(address, nonce), 1 for h(tag, chainId, account, address, nonce)")
+	expiry := flag.Bool("expiry", false, "add a validUntil public input, the signature being over keccak256(msgHash || validUntil)")
+	backend := flag.String("backend", "plonk", "proof system: plonk or groth16")
+	srsFile := flag.String("srs", "", "powers-of-tau transcript of the PLONK SRS: a .ptau file, an Ignition transcript00.dat or a gnark kzg.SRS")
+	unsafe := flag.Bool("unsafe", false, "draw the secret randomness of the setup locally, for tests only")
+	flag.Parse()
+	if *commitVersion != 0 && *commitVersion != 1 {
+		fmt.Printf("Error: unknown commitment version %d\n", *commitVersion)
+		os.Exit(1)
+	}
+	b, err := zkbackend.New(*backend)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	// the verifying key is deployed on-chain, its secret randomness must come
+	// from a ceremony unless the setup is explicitly a test setup
+	switch {
+	case *srsFile != "" && b.Name() != "plonk":
+		fmt.Printf("Error: -srs is the SRS of PLONK, the Groth16 setup is specific to the circuit\n")
+		os.Exit(1)
+	case *srsFile != "":
+		b = zkbackend.Plonk{SRSFile: *srsFile}
+	case !*unsafe && b.Name() == "groth16":
+		fmt.Printf("Error: the Groth16 setup draws its secret randomness locally, pass -unsafe for a test setup\n")
+		os.Exit(1)
+	case !*unsafe:
+		fmt.Printf("Error: without -srs the PLONK setup draws its SRS locally, pass -srs <transcript> or -unsafe for a test setup\n")
+		os.Exit(1)
+	}
+
 	fmt.Println("--- Generating ECDSA circuit inputs and performing compliance check ---")
 
 	// 1. Compile the circuit
-	circuit := Circuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{}
-	fmt.Printf("Compiling circuit...\n")
-	R1CS, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit)
+	var validUntil []frontend.Variable
+	if *expiry {
+		validUntil = make([]frontend.Variable, 1)
+	}
+	var circuit frontend.Circuit
+	switch *curve {
+	case "secp256k1":
+		circuit = &circuits.Circuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{ValidUntil: validUntil, CommitHash: *commitHash, LowS: *lowS, CommitVersion: *commitVersion}
+	case "p256":
+		circuit = &circuits.Circuit[emulated.P256Fp, emulated.P256Fr]{ValidUntil: validUntil, CommitHash: *commitHash, LowS: *lowS, CommitVersion: *commitVersion}
+	default:
+		fmt.Printf("Error: unknown curve %q\n", *curve)
+		os.Exit(1)
+	}
+	// Groth16 compiles the circuit to R1CS, PLONK to SCS
+	fmt.Printf("Compiling %s circuit for %s...\n", *curve, b.Name())
+	R1CS, err := b.Compile(circuit)
 	if err != nil {
 		fmt.Printf("Error compiling ECDSA circuit: %v\n", err)
 		os.Exit(1)
@@ -61,40 +89,74 @@ func main() {
 	fmt.Printf("BN254 circuit compiled with %d constraints",
 		R1CS.GetNbConstraints())
 
-	// 2. Perform Groth16 setup
-	fmt.Printf("Starting Plonk setup...\n")
-	A, B, _ := unsafekzg.NewSRS(R1CS)
-	PK, VK, err := plonk.Setup(R1CS, A, B)
+	// 2. Perform the setup of the backend
+	fmt.Printf("Starting %s setup...\n", b.Name())
+	PK, VK, err := b.Setup(R1CS)
 	if err != nil {
-		fmt.Printf("Error during Plonk setup for ECDSA: %v\n", err)
+		fmt.Printf("Error during %s setup for ECDSA: %v\n", b.Name(), err)
 		os.Exit(1)
 	}
 	fmt.Printf("Setup done.\n")
 
 	// 3. Save to bin files
-	writeToFile("r1cs.bin", R1CS)
-	writeToFile("proving_key.bin", PK)
-	writeToFile("verifying_key.bin", VK)
+	writeToFile(artifactName(*backend, *curve, "r1cs.bin"), R1CS)
+	writeToFile(artifactName(*backend, *curve, "proving_key.bin"), PK)
+	writeToFile(artifactName(*backend, *curve, "verifying_key.bin"), VK)
+
+	// the prover and pub_commit.go must use the same options as the setup
+	configJSON, err := json.MarshalIndent(SetupConfig{CommitHash: *commitHash, Curve: *curve, LowS: *lowS, CommitVersion: *commitVersion, Expiry: *expiry, Backend: *backend, Unsafe: *srsFile == ""}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling setup config JSON: %v\n", err)
+		os.Exit(1)
+	}
+	writeToFile(artifactName(*backend, *curve, "setup_config.json"), bytes.NewReader(configJSON))
 
 	fmt.Println("\nAll input files generated successfully for CGO wrapper.")
 
 	// 4. Export the Solidity verifier contract
 	fmt.Println("\n--- Exporting Solidity Verifier ---")
-	verifierFile, err := os.Create("solidity/src/Verifier.sol")
+	verifierPath := "solidity/src/" + verifierName(*backend, *curve) + ".sol"
+	verifierFile, err := os.Create(verifierPath)
 	if err != nil {
-		fmt.Printf("Error creating solidity/src/Verifier.sol: %v\n", err)
+		fmt.Printf("Error creating %s: %v\n", verifierPath, err)
 		os.Exit(1)
 	}
 	defer verifierFile.Close()
-	err = VK.ExportSolidity(verifierFile)
+	err = b.ExportSolidity(VK, verifierFile)
 	if err != nil {
 		fmt.Printf("Error exporting solidity verifier: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Successfully exported solidty/src/Verifier.sol")
+	fmt.Printf("Successfully exported %s\n", verifierPath)
 
 }
 
+// artifactName returns the file name of a setup artifact for the backend and
+// the signer curve. The secp256k1 PLONK artifacts keep their historical names,
+// the Groth16 artifacts are prefixed so that they are never mixed with them.
+func artifactName(backendName, curve, name string) string {
+	if curve != "" && curve != "secp256k1" {
+		name = curve + "_" + name
+	}
+	if backendName == "groth16" {
+		name = "groth16_" + name
+	}
+	return name
+}
+
+// verifierName returns the name of the Solidity verifier for the backend and
+// the signer curve.
+func verifierName(backendName, curve string) string {
+	name := "Verifier"
+	if curve == "p256" {
+		name = "P256Verifier"
+	}
+	if backendName == "groth16" {
+		name = "Groth16" + name
+	}
+	return name
+}
+
 // writeToFile is a helper to serialize and write gnark objects or byte readers to files.
 func writeToFile(filename string, data interface{}) {
 	file, err := os.Create(filename)
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	// Added for performance timing
+	"github.com/consensys/gnark-crypto/ecc"
+
+	"github.com/consensys/gnark/backend/plonk"
+
+	"circuits"
+	"zkbackend"
+)
+
+// AggregateConfig struct for JSON serialization of the aggregation options chosen at setup time.
+type AggregateConfig struct {
+	NbProofs int    `json:"nbProofs"` // Number of aggregated proofs
+	Curve    string `json:"curve"`    // Curve of the signers of the inner proofs
+	Backend  string `json:"backend"`  // Proof system, plonk
+	Unsafe   bool   `json:"unsafe"`   // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	nbProofs := flag.Int("n", 2, "number of aggregated proofs")
+	curve := flag.String("curve", "secp256k1", "curve of the signers of the inner proofs: secp256k1 or p256")
+	backend := flag.String("backend", "plonk", "proof system: plonk only, a Groth16 setup is made by trusted_setup.go")
+	srsFile := flag.String("srs", "", "powers-of-tau transcript of the PLONK SRS: a .ptau file, an Ignition transcript00.dat or a gnark kzg.SRS")
+	unsafe := flag.Bool("unsafe", false, "draw the SRS of the setup locally, for tests only")
+	flag.Parse()
+	// only the single signer circuit of trusted_setup.go has a Groth16 setup
+	if *backend != "plonk" {
+		fmt.Printf("Error: the aggregation circuit is only set up for plonk, pass -backend groth16 to trusted_setup.go for a Groth16 setup\n")
+		os.Exit(1)
+	}
+	// the verifying key is deployed on-chain, its SRS must come from a
+	// ceremony unless the setup is explicitly a test setup
+	if *srsFile == "" && !*unsafe {
+		fmt.Printf("Error: without -srs the PLONK setup draws its SRS locally, pass -srs <transcript> or -unsafe for a test setup\n")
+		os.Exit(1)
+	}
+	b := zkbackend.Plonk{SRSFile: *srsFile}
+
+	fmt.Println("--- Generating aggregation circuit ---")
+
+	// 1. Read the inner circuit and its verifying key
+	innerR1CS := plonk.NewCS(ecc.BN254)
+	err := readFromFile(artifactName(*curve, "r1cs.bin"), innerR1CS)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(*curve, "r1cs.bin"), err)
+		os.Exit(1)
+	}
+	innerVK := plonk.NewVerifyingKey(ecc.BN254)
+	err = readFromFile(artifactName(*curve, "verifying_key.bin"), innerVK)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", artifactName(*curve, "verifying_key.bin"), err)
+		os.Exit(1)
+	}
+
+	// 2. Compile the circuit
+	circuit, err := circuits.NewAggregateCircuit(*nbProofs, innerR1CS, innerVK)
+	if err != nil {
+		fmt.Printf("Error creating aggregation circuit: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Compiling circuit for %d proofs...\n", *nbProofs)
+	R1CS, err := b.Compile(circuit)
+	if err != nil {
+		fmt.Printf("Error compiling aggregation circuit: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("BN254 circuit compiled with %d constraints",
+		R1CS.GetNbConstraints())
+
+	// 3. Perform Plonk setup
+	fmt.Printf("Starting Plonk setup...\n")
+	PK, VK, err := b.Setup(R1CS)
+	if err != nil {
+		fmt.Printf("Error during Plonk setup for aggregation: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Setup done.\n")
+
+	// 4. Save to bin files
+	writeToFile("aggregate_r1cs.bin", R1CS)
+	writeToFile("aggregate_proving_key.bin", PK)
+	writeToFile("aggregate_verifying_key.bin", VK)
+
+	// the aggregator must use the same options as the setup
+	configJSON, err := json.MarshalIndent(AggregateConfig{NbProofs: *nbProofs, Curve: *curve, Backend: *backend, Unsafe: *srsFile == ""}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling setup config JSON: %v\n", err)
+		os.Exit(1)
+	}
+	writeToFile("aggregate_setup_config.json", bytes.NewReader(configJSON))
+
+	// 5. Export the Solidity verifier contract
+	fmt.Println("\n--- Exporting Solidity Verifier ---")
+	verifierFile, err := os.Create("solidity/src/AggregateVerifier.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/src/AggregateVerifier.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierFile.Close()
+	err = b.ExportSolidity(VK, verifierFile)
+	if err != nil {
+		fmt.Printf("Error exporting solidity verifier: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Successfully exported solidity/src/AggregateVerifier.sol")
+}
+
+// artifactName returns the file name of a setup artifact for the signer curve.
+// The secp256k1 artifacts keep their historical names.
+func artifactName(curve, name string) string {
+	if curve == "" || curve == "secp256k1" {
+		return name
+	}
+	return curve + "_" + name
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.ReaderFrom:
+		_, err = v.ReadFrom(file)
+		if err != nil && err != io.EOF { // io.EOF is expected if the file is empty or partially read
+			return fmt.Errorf("error reading from file %s into io.ReaderFrom: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/consensys/gnark/std/math/emulated"
+
+	"circuits"
+	"zkbackend"
+)
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"` // Hash of the public key commitment, mimc or poseidon2
+	Curve      string `json:"curve"`      // Curve of the signer, secp256k1 for transactions
+	Backend    string `json:"backend"`    // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`     // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	commitHash := flag.String("hash", "mimc", "hash of the public key commitment: mimc or poseidon2")
+	backend := flag.String("backend", "plonk", "proof system: plonk only, a Groth16 setup is made by trusted_setup.go")
+	srsFile := flag.String("srs", "", "powers-of-tau transcript of the PLONK SRS: a .ptau file, an Ignition transcript00.dat or a gnark kzg.SRS")
+	unsafe := flag.Bool("unsafe", false, "draw the SRS of the setup locally, for tests only")
+	flag.Parse()
+	// only the single signer circuit of trusted_setup.go has a Groth16 setup
+	if *backend != "plonk" {
+		fmt.Printf("Error: the EIP-1559 circuit is only set up for plonk, pass -backend groth16 to trusted_setup.go for a Groth16 setup\n")
+		os.Exit(1)
+	}
+	// the verifying key is deployed on-chain, its SRS must come from a
+	// ceremony unless the setup is explicitly a test setup
+	if *srsFile == "" && !*unsafe {
+		fmt.Printf("Error: without -srs the PLONK setup draws its SRS locally, pass -srs <transcript> or -unsafe for a test setup\n")
+		os.Exit(1)
+	}
+	b := zkbackend.Plonk{SRSFile: *srsFile}
+
+	fmt.Println("--- Generating EIP-1559 transaction circuit ---")
+
+	// 1. Compile the circuit
+	circuit := circuits.TransactionCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{CommitHash: *commitHash}
+	fmt.Printf("Compiling circuit...\n")
+	R1CS, err := b.Compile(&circuit)
+	if err != nil {
+		fmt.Printf("Error compiling EIP-1559 circuit: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("BN254 circuit compiled with %d constraints",
+		R1CS.GetNbConstraints())
+
+	// 2. Perform Plonk setup
+	fmt.Printf("Starting Plonk setup...\n")
+	PK, VK, err := b.Setup(R1CS)
+	if err != nil {
+		fmt.Printf("Error during Plonk setup for EIP-1559: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Setup done.\n")
+
+	// 3. Save to bin files
+	writeToFile("eip1559_r1cs.bin", R1CS)
+	writeToFile("eip1559_proving_key.bin", PK)
+	writeToFile("eip1559_verifying_key.bin", VK)
+
+	// the prover must use the same options as the setup
+	configJSON, err := json.MarshalIndent(SetupConfig{CommitHash: *commitHash, Curve: "secp256k1", Backend: *backend, Unsafe: *srsFile == ""}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling setup config JSON: %v\n", err)
+		os.Exit(1)
+	}
+	writeToFile("eip1559_setup_config.json", bytes.NewReader(configJSON))
+
+	// 4. Export the Solidity verifier contract
+	fmt.Println("\n--- Exporting Solidity Verifier ---")
+	verifierFile, err := os.Create("solidity/src/EIP1559Verifier.sol")
+	if err != nil {
+		fmt.Printf("Error creating solidity/src/EIP1559Verifier.sol: %v\n", err)
+		os.Exit(1)
+	}
+	defer verifierFile.Close()
+	err = b.ExportSolidity(VK, verifierFile)
+	if err != nil {
+		fmt.Printf("Error exporting solidity verifier: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Successfully exported solidity/src/EIP1559Verifier.sol")
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
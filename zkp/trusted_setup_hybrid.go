@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+
+	"circuits"
+	"zkbackend"
+)
+
+// SetupConfig struct for JSON serialization of the circuit options chosen at setup time.
+type SetupConfig struct {
+	CommitHash string `json:"commitHash"` // Hash of the public key commitment, mimc or poseidon2
+	Curve      string `json:"curve"`      // Curve of the signer, secp256k1 or p256
+	LowS       bool   `json:"lowS"`       // The circuit rejects high-s signatures
+	Backend    string `json:"backend"`    // Proof system, plonk
+	Unsafe     bool   `json:"unsafe"`     // The SRS of the setup was drawn locally, for tests only
+}
+
+func main() {
+	commitHash := flag.String("hash", "mimc", "hash of the public key commitment: mimc or poseidon2")
+	curve := flag.String("curve", "secp256k1", "curve of the signer: secp256k1 or p256")
+	lowS := flag.Bool("lowS", false, "reject in-circuit the high-s form of the signature")
+	backend := flag.String("backend", "plonk", "proof system: plonk only, a Groth16 setup is made by trusted_setup.go")
+	srsFile := flag.String("srs", "", "powers-of-tau transcript of the PLONK SRS: a .ptau file, an Ignition transcript00.dat or a gnark kzg.SRS")
+	unsafe := flag.Bool("unsafe", false, "draw the SRS of the setup locally, for tests only")
+	flag.Parse()
+	// only the single signer circuit of trusted_setup.go has a Groth16 setup
+	if *backend != "plonk" {
+		fmt.Printf("Error: the hybrid circuit is only set up for plonk, pass -backend groth16 to trusted_setup.go for a Groth16 setup\n")
+		os.Exit(1)
+	}
+	// the verifying key is deployed on-chain, its SRS must come from a
+	// ceremony unless the setup is explicitly a test setup
+	if *srsFile == "" && !*unsafe {
+		fmt.Printf("Error: without -srs the PLONK setup draws its SRS locally, pass -srs <transcript> or -unsafe for a test setup\n")
+		os.Exit(1)
+	}
+	b := zkbackend.Plonk{SRSFile: *srsFile}
+
+	fmt.Println("--- Generating hybrid ECDSA + Falcon-512 circuit ---")
+
+	// 1. Compile the circuit
+	var circuit frontend.Circuit
+	switch *curve {
+	case "secp256k1":
+		circuit = &circuits.HybridCircuit[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{CommitHash: *commitHash, LowS: *lowS}
+	case "p256":
+		circuit = &circuits.HybridCircuit[emulated.P256Fp, emulated.P256Fr]{CommitHash: *commitHash, LowS: *lowS}
+	default:
+		fmt.Printf("Error: unknown curve %q\n", *curve)
+		os.Exit(1)
+	}
+	fmt.Printf("Compiling %s circuit...\n", *curve)
+	R1CS, err := b.Compile(circuit)
+	if err != nil {
+		fmt.Printf("Error compiling hybrid circuit: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("BN254 circuit compiled with %d constraints",
+		R1CS.GetNbConstraints())
+
+	// 2. Perform Plonk setup
+	fmt.Printf("Starting Plonk setup...\n")
+	PK, VK, err := b.Setup(R1CS)
+	if err != nil {
+		fmt.Printf("Error during Plonk setup for hybrid: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Setup done.\n")
+
+	// 3. Save to bin files
+	writeToFile(artifactName(*curve, "hybrid_r1cs.bin"), R1CS)
+	writeToFile(artifactName(*curve, "hybrid_proving_key.bin"), PK)
+	writeToFile(artifactName(*curve, "hybrid_verifying_key.bin"), VK)
+
+	// prove_hybrid_k1.go must use the same options as the setup
+	configJSON, err := json.MarshalIndent(SetupConfig{CommitHash: *commitHash, Curve: *curve, LowS: *lowS, Backend: *backend, Unsafe: *srsFile == ""}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling setup config JSON: %v\n", err)
+		os.Exit(1)
+	}
+	writeToFile(artifactName(*curve, "hybrid_setup_config.json"), bytes.NewReader(configJSON))
+
+	// 4. Export the Solidity verifier contract
+	fmt.Println("\n--- Exporting Solidity Verifier ---")
+	verifierPath := "solidity/src/" + hybridVerifierName(*curve) + ".sol"
+	verifierFile, err := os.Create(verifierPath)
+	if err != nil {
+		fmt.Printf("Error creating %s: %v\n", verifierPath, err)
+		os.Exit(1)
+	}
+	defer verifierFile.Close()
+	err = b.ExportSolidity(VK, verifierFile)
+	if err != nil {
+		fmt.Printf("Error exporting solidity verifier: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Successfully exported %s\n", verifierPath)
+}
+
+// artifactName returns the file name of a setup artifact for the signer curve.
+// The secp256k1 artifacts keep their historical names.
+func artifactName(curve, name string) string {
+	if curve == "" || curve == "secp256k1" {
+		return name
+	}
+	return curve + "_" + name
+}
+
+// hybridVerifierName returns the name of the Solidity hybrid verifier for the
+// signer curve.
+func hybridVerifierName(curve string) string {
+	if curve == "p256" {
+		return "P256HybridVerifier"
+	}
+	return "HybridVerifier"
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// authDataLen is the length of an authenticatorData without extensions,
+// rpIdHash (32) || flags (1) || signCount (4).
+const authDataLen = 37
+
+// maxClientDataLen is the maximum length of the clientDataJSON.
+const maxClientDataLen = 384
+
+// clientDataPrefix starts the clientDataJSON of every assertion, the challenge
+// follows as the base64url encoding of the 32-byte digest.
+const clientDataPrefix = `{"type":"webauthn.get","challenge":"`
+
+// Assertion struct for JSON deserialization of a WebAuthn assertion, as
+// returned by PublicKeyCredential.toJSON().
+type Assertion struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		AuthenticatorData string `json:"authenticatorData"` // base64url
+		ClientDataJSON    string `json:"clientDataJSON"`    // base64url
+		Signature         string `json:"signature"`         // base64url, DER encoded
+	} `json:"response"`
+}
+
+// InputWithCommit struct for JSON deserialization of the commitment of the passkey.
+type InputWithCommit struct {
+	PubX    string `json:"pubX"`    // Hex string of public key X
+	PubY    string `json:"pubY"`    // Hex string of public key Y
+	Address string `json:"address"` // Hex string of address
+	Nonce   string `json:"nonce"`   // Hex string of nonce
+	Com     string `json:"com"`     // Hex string of Com
+	Hash    string `json:"hash"`    // Hash of the commitment, mimc or poseidon2
+	Curve   string `json:"curve"`   // Curve of the signer, must be p256
+	Version int    `json:"version"` // Layout of the commitment, 0 or 1
+}
+
+// ProveInputWebAuthn struct for JSON serialization of WebAuthn witness inputs.
+type ProveInputWebAuthn struct {
+	AuthenticatorData string `json:"authenticatorData"` // Hex string of authenticatorData
+	ClientDataJSON    string `json:"clientDataJSON"`    // Hex string of clientDataJSON
+	MsgHash           string `json:"msgHash"`           // Hex string of the digest, the challenge of the assertion
+	R                 string `json:"r"`                 // Hex string of signature R
+	S                 string `json:"s"`                 // Hex string of signature S
+	PubX              string `json:"pubX"`              // Hex string of public key X
+	PubY              string `json:"pubY"`              // Hex string of public key Y
+	Address           string `json:"address"`           // Hex string of address
+	Nonce             string `json:"nonce"`             // Hex string of nonce
+	ChainID           string `json:"chainId"`           // Hex string of the chain id of the account
+	Account           string `json:"account"`           // Hex string of the account using the proof
+	Com               string `json:"com"`               // Hex string of Com
+	Hash              string `json:"hash"`              // Hash of the commitment, mimc or poseidon2
+}
+
+func main() {
+	assertionFile := flag.String("assertion", "assertion.json", "WebAuthn assertion, as returned by PublicKeyCredential.toJSON()")
+	chainID := flag.String("chainId", "", "hex chain id of the account using the proof")
+	account := flag.String("account", "", "hex address of the account using the proof")
+	flag.Parse()
+
+	var assertion Assertion
+	err := readFromFile(*assertionFile, &assertion)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", *assertionFile, err)
+		os.Exit(1)
+	}
+	var commitment InputWithCommit
+	err = readFromFile("witness_input.json", &commitment)
+	if err != nil {
+		fmt.Printf("Error reading witness_input.json: %v\n", err)
+		os.Exit(1)
+	}
+	if commitment.Version != 0 {
+		fmt.Printf("Error: only the single signer circuit supports version 1 commitments, commit with pub_commit.go -version 0\n")
+		os.Exit(1)
+	}
+	if commitment.Curve != "p256" {
+		fmt.Printf("Error: witness_input.json commits to a %q key, a p256 passkey is expected\n", commitment.Curve)
+		os.Exit(1)
+	}
+	if _, err = hex.DecodeString(*chainID); err != nil || *chainID == "" {
+		fmt.Printf("Error: -chainId must be a hex string\n")
+		os.Exit(1)
+	}
+	if _, err = hex.DecodeString(*account); err != nil || *account == "" {
+		fmt.Printf("Error: -account must be a hex string\n")
+		os.Exit(1)
+	}
+
+	authData, err := decodeBase64URL(assertion.Response.AuthenticatorData)
+	if err != nil {
+		fmt.Printf("Error decoding authenticatorData: %v\n", err)
+		os.Exit(1)
+	}
+	clientData, err := decodeBase64URL(assertion.Response.ClientDataJSON)
+	if err != nil {
+		fmt.Printf("Error decoding clientDataJSON: %v\n", err)
+		os.Exit(1)
+	}
+	derSig, err := decodeBase64URL(assertion.Response.Signature)
+	if err != nil {
+		fmt.Printf("Error decoding signature: %v\n", err)
+		os.Exit(1)
+	}
+
+	// the circuit supports assertions without extensions
+	if len(authData) != authDataLen {
+		fmt.Printf("Error: authenticatorData is %d bytes long, only %d (no extensions) is supported\n", len(authData), authDataLen)
+		os.Exit(1)
+	}
+	if authData[32]&1 == 0 {
+		fmt.Printf("Error: the user presence flag is not set\n")
+		os.Exit(1)
+	}
+	if len(clientData) > maxClientDataLen {
+		fmt.Printf("Error: clientDataJSON is %d bytes long, at most %d is supported\n", len(clientData), maxClientDataLen)
+		os.Exit(1)
+	}
+
+	// the challenge is the base64url encoded digest, right after the prefix
+	if !bytes.HasPrefix(clientData, []byte(clientDataPrefix)) {
+		fmt.Printf("Error: clientDataJSON does not start with %s\n", clientDataPrefix)
+		os.Exit(1)
+	}
+	challenge, _, found := strings.Cut(string(clientData[len(clientDataPrefix):]), `"`)
+	if !found {
+		fmt.Printf("Error: unterminated challenge in clientDataJSON\n")
+		os.Exit(1)
+	}
+	digest, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(digest) != 32 {
+		fmt.Printf("Error: the challenge must be a base64url encoded 32-byte digest\n")
+		os.Exit(1)
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err = asn1.Unmarshal(derSig, &sig); err != nil {
+		fmt.Printf("Error decoding DER signature: %v\n", err)
+		os.Exit(1)
+	}
+
+	// check the assertion against the committed key before proving
+	pubXBytes, err := hex.DecodeString(commitment.PubX)
+	if err != nil {
+		fmt.Printf("Error decoding PubX hex: %v\n", err)
+		os.Exit(1)
+	}
+	pubYBytes, err := hex.DecodeString(commitment.PubY)
+	if err != nil {
+		fmt.Printf("Error decoding PubY hex: %v\n", err)
+		os.Exit(1)
+	}
+	pub := ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(pubXBytes),
+		Y:     new(big.Int).SetBytes(pubYBytes),
+	}
+	clientDataHash := sha256.Sum256(clientData)
+	msg := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	if !ecdsa.Verify(&pub, msg[:], sig.R, sig.S) {
+		fmt.Printf("Error: the assertion is not signed by the committed key\n")
+		os.Exit(1)
+	}
+
+	Output := ProveInputWebAuthn{
+		AuthenticatorData: hex.EncodeToString(authData),
+		ClientDataJSON:    hex.EncodeToString(clientData),
+		MsgHash:           hex.EncodeToString(digest),
+		R:                 hex.EncodeToString(sig.R.Bytes()),
+		S:                 hex.EncodeToString(sig.S.Bytes()),
+		PubX:              commitment.PubX,
+		PubY:              commitment.PubY,
+		Address:           commitment.Address,
+		Nonce:             commitment.Nonce,
+		ChainID:           *chainID,
+		Account:           *account,
+		Com:               commitment.Com,
+		Hash:              commitment.Hash,
+	}
+
+	OutputJSON, err := json.MarshalIndent(Output, "", "  ")
+	if err != nil {
+		fmt.Printf("Error marshaling prove input JSON: %v\n", err)
+		os.Exit(1)
+	}
+
+	writeToFile("webauthn_witness_input.json", bytes.NewReader(OutputJSON))
+}
+
+// decodeBase64URL decodes a base64url string, with or without padding.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// writeToFile is a helper to serialize and write gnark objects or byte readers to files.
+func writeToFile(filename string, data interface{}) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("Error creating file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case io.WriterTo:
+		_, err = v.WriteTo(file)
+	case *bytes.Reader: // For the JSON input
+		_, err = v.WriteTo(file)
+	default:
+		err = fmt.Errorf("unsupported type for writing to file")
+	}
+
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s\n", filename)
+}
+
+// readFromFile is a helper to deserialize and read gnark objects or JSON from files.
+func readFromFile(filename string, data interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	switch v := data.(type) {
+	case *Assertion, *InputWithCommit: // For the JSON inputs
+		decoder := json.NewDecoder(file)
+		err = decoder.Decode(v)
+		if err != nil {
+			return fmt.Errorf("error decoding JSON from file %s: %w", filename, err)
+		}
+	default:
+		return fmt.Errorf("unsupported type for reading from file: %T", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// Package zkbackend puts the gnark proof systems of ZKeeper behind a single
+// interface, so that the setup, the provers and the mobile library share one
+// code path for PLONK and Groth16 on BN254.
+package zkbackend
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/solidity"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// ProvingKey, VerifyingKey and Proof are the keys and proofs of a backend,
+// written to and read from the setup artifacts. They are only used with the
+// backend that created them.
+type (
+	ProvingKey interface {
+		io.WriterTo
+		io.ReaderFrom
+	}
+	VerifyingKey interface {
+		io.WriterTo
+		io.ReaderFrom
+	}
+	Proof interface {
+		io.WriterTo
+		io.ReaderFrom
+	}
+)
+
+// Backend is a proof system on BN254.
+type Backend interface {
+	// Name is the name of the backend in the -backend flags and the setup config.
+	Name() string
+
+	// Compile compiles the circuit to the constraint system of the backend.
+	Compile(circuit frontend.Circuit, opts ...frontend.CompileOption) (constraint.ConstraintSystem, error)
+
+	// Setup creates the proving and verifying keys of the constraint system.
+	Setup(ccs constraint.ConstraintSystem) (ProvingKey, VerifyingKey, error)
+
+	// Prove proves the full witness. The proof is verifiable by the Solidity
+	// verifier, unless opts select another transcript.
+	Prove(ccs constraint.ConstraintSystem, pk ProvingKey, fullWitness witness.Witness, opts ...backend.ProverOption) (Proof, error)
+
+	// Verify verifies the proof against the public witness, with the transcript
+	// of the Solidity verifier unless opts select another one.
+	Verify(proof Proof, vk VerifyingKey, publicWitness witness.Witness, opts ...backend.VerifierOption) error
+
+	// ExportSolidity writes the Solidity verifier of the verifying key.
+	ExportSolidity(vk VerifyingKey, w io.Writer, opts ...solidity.ExportOption) error
+
+	// MarshalProof encodes the proof for the Solidity verifier.
+	MarshalProof(proof Proof) ([]byte, error)
+
+	// NewCS, NewProvingKey, NewVerifyingKey and NewProof return empty objects,
+	// to be read from the setup artifacts.
+	NewCS() constraint.ConstraintSystem
+	NewProvingKey() ProvingKey
+	NewVerifyingKey() VerifyingKey
+	NewProof() Proof
+}
+
+// New returns the backend of the given name, plonk or groth16. The empty name
+// of the setup configs written before Groth16 is PLONK.
+func New(name string) (Backend, error) {
+	switch name {
+	case "", "plonk":
+		return Plonk{}, nil
+	case "groth16":
+		return Groth16{}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+}
+
+var (
+	_ Backend = Plonk{}
+	_ Backend = Groth16{}
+)
@@ -0,0 +1,84 @@
+package zkbackend
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/solidity"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// Groth16 is the Groth16 backend on BN254. The commitments of the proof are
+// bound to the public inputs with keccak256, as in the Solidity verifier.
+type Groth16 struct{}
+
+func (Groth16) Name() string { return "groth16" }
+
+func (Groth16) Compile(circuit frontend.Circuit, opts ...frontend.CompileOption) (constraint.ConstraintSystem, error) {
+	return frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit, opts...)
+}
+
+// Setup draws the toxic waste of the circuit locally, the setup is only as
+// safe as the machine running it.
+func (Groth16) Setup(ccs constraint.ConstraintSystem) (ProvingKey, VerifyingKey, error) {
+	return groth16.Setup(ccs)
+}
+
+func (Groth16) Prove(ccs constraint.ConstraintSystem, pk ProvingKey, fullWitness witness.Witness, opts ...backend.ProverOption) (Proof, error) {
+	groth16PK, ok := pk.(groth16.ProvingKey)
+	if !ok {
+		return nil, fmt.Errorf("not a Groth16 proving key")
+	}
+	opts = append([]backend.ProverOption{solidity.WithProverTargetSolidityVerifier(backend.GROTH16)}, opts...)
+	return groth16.Prove(ccs, groth16PK, fullWitness, opts...)
+}
+
+func (Groth16) Verify(proof Proof, vk VerifyingKey, publicWitness witness.Witness, opts ...backend.VerifierOption) error {
+	groth16Proof, ok := proof.(groth16.Proof)
+	if !ok {
+		return fmt.Errorf("not a Groth16 proof")
+	}
+	groth16VK, ok := vk.(groth16.VerifyingKey)
+	if !ok {
+		return fmt.Errorf("not a Groth16 verifying key")
+	}
+	opts = append([]backend.VerifierOption{solidity.WithVerifierTargetSolidityVerifier(backend.GROTH16)}, opts...)
+	return groth16.Verify(groth16Proof, groth16VK, publicWitness, opts...)
+}
+
+func (Groth16) ExportSolidity(vk VerifyingKey, w io.Writer, opts ...solidity.ExportOption) error {
+	groth16VK, ok := vk.(groth16.VerifyingKey)
+	if !ok {
+		return fmt.Errorf("not a Groth16 verifying key")
+	}
+	return groth16VK.ExportSolidity(w, opts...)
+}
+
+// MarshalProof returns abi.encode(uint256[8] proof, uint256[2n] commitments,
+// uint256[2] commitmentPok), the arguments of Verifier.verifyProof before the
+// public inputs. Without commitments, it is abi.encode(uint256[8] proof).
+func (Groth16) MarshalProof(proof Proof) ([]byte, error) {
+	bn254Proof, ok := proof.(*groth16_bn254.Proof)
+	if !ok {
+		return nil, fmt.Errorf("not a Groth16 proof on BN254")
+	}
+	// Ar | Bs | Krs, then with commitments, their number on 4 bytes, the
+	// commitments and their proof of knowledge
+	raw := bn254Proof.MarshalSolidity()
+	if len(bn254Proof.Commitments) == 0 {
+		return raw, nil
+	}
+	return append(raw[:8*32:8*32], raw[8*32+4:]...), nil
+}
+
+func (Groth16) NewCS() constraint.ConstraintSystem { return groth16.NewCS(ecc.BN254) }
+func (Groth16) NewProvingKey() ProvingKey          { return groth16.NewProvingKey(ecc.BN254) }
+func (Groth16) NewVerifyingKey() VerifyingKey      { return groth16.NewVerifyingKey(ecc.BN254) }
+func (Groth16) NewProof() Proof                    { return groth16.NewProof(ecc.BN254) }
@@ -0,0 +1,83 @@
+package zkbackend
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// squareCircuit has no commitment, its Groth16 proof is Ar | Bs | Krs only
+type squareCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *squareCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	return nil
+}
+
+// committedCircuit commits to its secret input
+type committedCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *committedCircuit) Define(api frontend.API) error {
+	cmt, err := api.(frontend.Committer).Commit(c.X)
+	if err != nil {
+		return err
+	}
+	api.AssertIsDifferent(cmt, 0)
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	return nil
+}
+
+func TestGroth16MarshalProof(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		circuit frontend.Circuit
+		witness frontend.Circuit
+		size    int
+	}{
+		// abi.encode(uint256[8])
+		{"no commitment", &squareCircuit{}, &squareCircuit{X: 3, Y: 9}, 8 * 32},
+		// abi.encode(uint256[8], uint256[2], uint256[2])
+		{"one commitment", &committedCircuit{}, &committedCircuit{X: 3, Y: 9}, 12 * 32},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var b Groth16
+			ccs, err := b.Compile(tc.circuit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pk, vk, err := b.Setup(ccs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fullWitness, err := frontend.NewWitness(tc.witness, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatal(err)
+			}
+			publicWitness, err := fullWitness.Public()
+			if err != nil {
+				t.Fatal(err)
+			}
+			proof, err := b.Prove(ccs, pk, fullWitness)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Verify(proof, vk, publicWitness); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := b.MarshalProof(proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(raw) != tc.size {
+				t.Fatalf("marshalled proof of %d bytes, expected %d", len(raw), tc.size)
+			}
+		})
+	}
+}
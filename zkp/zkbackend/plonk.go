@@ -0,0 +1,95 @@
+package zkbackend
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/plonk"
+	plonk_bn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/solidity"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test/unsafekzg"
+)
+
+// Plonk is the PLONK backend, with a KZG commitment on BN254.
+type Plonk struct {
+	// SRSFile is the powers-of-tau transcript of the KZG SRS, read by ReadSRS.
+	// Without it, Setup draws the SRS locally.
+	SRSFile string
+}
+
+func (Plonk) Name() string { return "plonk" }
+
+func (Plonk) Compile(circuit frontend.Circuit, opts ...frontend.CompileOption) (constraint.ConstraintSystem, error) {
+	return frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, circuit, opts...)
+}
+
+// Setup truncates the SRS of SRSFile to the size of the circuit. Without
+// SRSFile, it draws the SRS locally with unsafekzg, and the setup is only as
+// safe as the machine running it.
+func (p Plonk) Setup(ccs constraint.ConstraintSystem) (ProvingKey, VerifyingKey, error) {
+	if p.SRSFile == "" {
+		srs, srsLagrange, err := unsafekzg.NewSRS(ccs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating the SRS: %w", err)
+		}
+		return plonk.Setup(ccs, srs, srsLagrange)
+	}
+	size, sizeLagrange := SRSSize(ccs)
+	srs, err := ReadSRS(p.SRSFile, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	srs, srsLagrange, err := NewLagrangeSRS(srs, size, sizeLagrange)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plonk.Setup(ccs, srs, srsLagrange)
+}
+
+func (Plonk) Prove(ccs constraint.ConstraintSystem, pk ProvingKey, fullWitness witness.Witness, opts ...backend.ProverOption) (Proof, error) {
+	plonkPK, ok := pk.(plonk.ProvingKey)
+	if !ok {
+		return nil, fmt.Errorf("not a PLONK proving key")
+	}
+	return plonk.Prove(ccs, plonkPK, fullWitness, opts...)
+}
+
+func (Plonk) Verify(proof Proof, vk VerifyingKey, publicWitness witness.Witness, opts ...backend.VerifierOption) error {
+	plonkProof, ok := proof.(plonk.Proof)
+	if !ok {
+		return fmt.Errorf("not a PLONK proof")
+	}
+	plonkVK, ok := vk.(plonk.VerifyingKey)
+	if !ok {
+		return fmt.Errorf("not a PLONK verifying key")
+	}
+	return plonk.Verify(plonkProof, plonkVK, publicWitness, opts...)
+}
+
+func (Plonk) ExportSolidity(vk VerifyingKey, w io.Writer, opts ...solidity.ExportOption) error {
+	plonkVK, ok := vk.(plonk.VerifyingKey)
+	if !ok {
+		return fmt.Errorf("not a PLONK verifying key")
+	}
+	return plonkVK.ExportSolidity(w, opts...)
+}
+
+// MarshalProof returns the proof as taken by PlonkVerifier.Verify.
+func (Plonk) MarshalProof(proof Proof) ([]byte, error) {
+	bn254Proof, ok := proof.(*plonk_bn254.Proof)
+	if !ok {
+		return nil, fmt.Errorf("not a PLONK proof on BN254")
+	}
+	return bn254Proof.MarshalSolidity(), nil
+}
+
+func (Plonk) NewCS() constraint.ConstraintSystem { return plonk.NewCS(ecc.BN254) }
+func (Plonk) NewProvingKey() ProvingKey          { return plonk.NewProvingKey(ecc.BN254) }
+func (Plonk) NewVerifyingKey() VerifyingKey      { return plonk.NewVerifyingKey(ecc.BN254) }
+func (Plonk) NewProof() Proof                    { return plonk.NewProof(ecc.BN254) }
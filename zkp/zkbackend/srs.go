@@ -0,0 +1,329 @@
+package zkbackend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	"github.com/consensys/gnark/constraint"
+)
+
+// SRSSize returns the number of G1 powers of the KZG SRS of the PLONK setup of
+// ccs, and the size of its Lagrange form.
+func SRSSize(ccs constraint.ConstraintSystem) (canonical, lagrange uint64) {
+	lagrange = ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints() + ccs.GetNbPublicVariables()))
+	return lagrange + 3, lagrange
+}
+
+// ReadSRS reads the first size G1 powers of a BN254 powers-of-tau transcript,
+// with [1]G₂ and [τ]G₂, and checks that they are the powers of one τ. The
+// format follows the file:
+//   - a snarkjs .ptau file, e.g. of the Perpetual Powers of Tau ceremony;
+//   - an Aztec Ignition transcript, transcript00.dat, whose powers start at [τ]G₁;
+//   - otherwise a kzg.SRS written by gnark-crypto.
+func ReadSRS(path string, size uint64) (*kzg_bn254.SRS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<20)
+	magic, err := r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var srs *kzg_bn254.SRS
+	switch {
+	case string(magic) == "ptau":
+		srs, err = readPtau(f, size)
+	case strings.HasSuffix(path, ".dat"):
+		srs, err = readIgnition(r, size)
+	default:
+		srs = new(kzg_bn254.SRS)
+		_, err = srs.ReadFrom(r)
+		if err == nil && uint64(len(srs.Pk.G1)) < size {
+			err = fmt.Errorf("%d powers, %d needed", len(srs.Pk.G1), size)
+		}
+		if err == nil {
+			srs.Pk.G1 = srs.Pk.G1[:size]
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	// the pairing lines are computed here rather than trusted from the file
+	srs.Vk.Lines[0] = bn254.PrecomputeLines(srs.Vk.G2[0])
+	srs.Vk.Lines[1] = bn254.PrecomputeLines(srs.Vk.G2[1])
+
+	err = CheckSRS(srs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return srs, nil
+}
+
+// CheckSRS checks that the SRS is [τⁱ]G₁ for the τ of [τ]G₂, starting from
+// the generators. All the successive powers are checked at once, on a random
+// linear combination: e(∑ rⁱ[τⁱ]G₁, [τ]G₂) = e(∑ rⁱ[τⁱ⁺¹]G₁, G₂).
+func CheckSRS(srs *kzg_bn254.SRS) error {
+	_, _, g1, g2 := bn254.Generators()
+	g1s := srs.Pk.G1
+	if len(g1s) < 2 {
+		return errors.New("less than 2 powers")
+	}
+	if !g1s[0].Equal(&g1) || !srs.Vk.G1.Equal(&g1) || !srs.Vk.G2[0].Equal(&g2) {
+		return errors.New("the powers do not start at the generators")
+	}
+	if !srs.Vk.G2[1].IsInSubGroup() || srs.Vk.G2[1].IsInfinity() || srs.Vk.G2[1].Equal(&g2) {
+		return errors.New("invalid [τ]G₂")
+	}
+	for i := range g1s {
+		if !g1s[i].IsOnCurve() || g1s[i].IsInfinity() {
+			return fmt.Errorf("invalid power %d", i)
+		}
+	}
+
+	var r fr.Element
+	if _, err := r.SetRandom(); err != nil {
+		return err
+	}
+	coeffs := make([]fr.Element, len(g1s)-1)
+	coeffs[0].SetOne()
+	for i := 1; i < len(coeffs); i++ {
+		coeffs[i].Mul(&coeffs[i-1], &r)
+	}
+	var a, b bn254.G1Affine
+	if _, err := a.MultiExp(g1s[:len(g1s)-1], coeffs, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if _, err := b.MultiExp(g1s[1:], coeffs, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	b.Neg(&b)
+	ok, err := bn254.PairingCheck([]bn254.G1Affine{a, b}, []bn254.G2Affine{srs.Vk.G2[1], srs.Vk.G2[0]})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("the powers are not the powers of [τ]G₂")
+	}
+	return nil
+}
+
+// NewLagrangeSRS returns the SRS truncated to size powers, and its Lagrange
+// form on the domain of size lagrange.
+func NewLagrangeSRS(srs *kzg_bn254.SRS, size, lagrange uint64) (*kzg_bn254.SRS, *kzg_bn254.SRS, error) {
+	if uint64(len(srs.Pk.G1)) < size || size < lagrange {
+		return nil, nil, fmt.Errorf("SRS of %d powers, %d needed", len(srs.Pk.G1), size)
+	}
+	canonical := &kzg_bn254.SRS{Vk: srs.Vk, Pk: kzg_bn254.ProvingKey{G1: srs.Pk.G1[:size]}}
+	g1s, err := kzg_bn254.ToLagrangeG1(srs.Pk.G1[:lagrange])
+	if err != nil {
+		return nil, nil, err
+	}
+	return canonical, &kzg_bn254.SRS{Vk: srs.Vk, Pk: kzg_bn254.ProvingKey{G1: g1s}}, nil
+}
+
+// readPtau reads a snarkjs .ptau file: sections of a type and a size, the
+// header in section 1, the powers [τⁱ]G₁ in section 2 and [τⁱ]G₂ in section 3.
+// The coordinates are in Montgomery form, little-endian.
+func readPtau(f *os.File, size uint64) (*kzg_bn254.SRS, error) {
+	var header struct {
+		Magic      [4]byte
+		Version    uint32
+		NbSections uint32
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(f, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	sections := make(map[uint32]int64)
+	pos := int64(12)
+	for i := uint32(0); i < header.NbSections; i++ {
+		var section struct {
+			Type uint32
+			Size uint64
+		}
+		if _, err := f.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(f, binary.LittleEndian, &section); err != nil {
+			return nil, err
+		}
+		sections[section.Type] = pos + 12
+		pos += 12 + int64(section.Size)
+	}
+	for _, s := range []uint32{1, 2, 3} {
+		if _, ok := sections[s]; !ok {
+			return nil, fmt.Errorf("no section %d", s)
+		}
+	}
+
+	// the curve and the number of powers
+	if _, err := f.Seek(sections[1], io.SeekStart); err != nil {
+		return nil, err
+	}
+	var n8 uint32
+	if err := binary.Read(f, binary.LittleEndian, &n8); err != nil {
+		return nil, err
+	}
+	if n8 != fp.Bytes {
+		return nil, fmt.Errorf("field of %d bytes, not BN254", n8)
+	}
+	var q [fp.Bytes]byte
+	if _, err := io.ReadFull(f, q[:]); err != nil {
+		return nil, err
+	}
+	var power uint32
+	if err := binary.Read(f, binary.LittleEndian, &power); err != nil {
+		return nil, err
+	}
+	if new(big.Int).SetBytes(reverse(q[:])).Cmp(fp.Modulus()) != 0 {
+		return nil, errors.New("not a BN254 transcript")
+	}
+	if power >= 32 || size > 1<<(power+1)-1 {
+		return nil, fmt.Errorf("2^%d powers, %d needed", power, size)
+	}
+
+	srs := new(kzg_bn254.SRS)
+	srs.Pk.G1 = make([]bn254.G1Affine, size)
+	if _, err := f.Seek(sections[2], io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReaderSize(f, 1<<20)
+	for i := range srs.Pk.G1 {
+		if err := readG1(r, &srs.Pk.G1[i], fpMont); err != nil {
+			return nil, fmt.Errorf("power %d: %w", i, err)
+		}
+	}
+	if _, err := f.Seek(sections[3], io.SeekStart); err != nil {
+		return nil, err
+	}
+	r.Reset(f)
+	for i := range srs.Vk.G2 {
+		if err := readG2(r, &srs.Vk.G2[i], fpMont); err != nil {
+			return nil, fmt.Errorf("G₂ power %d: %w", i, err)
+		}
+	}
+	srs.Vk.G1 = srs.Pk.G1[0]
+	return srs, nil
+}
+
+// readIgnition reads the first transcript of the Aztec Ignition ceremony: a
+// manifest of big-endian uint32, the powers [τⁱ]G₁ from i = 1, then [τ]G₂.
+// The coordinates are 64-bit limbs from the least significant, each limb
+// big-endian.
+func readIgnition(r io.Reader, size uint64) (*kzg_bn254.SRS, error) {
+	var manifest struct {
+		TranscriptNumber, TotalTranscripts uint32
+		TotalG1Points, TotalG2Points       uint32
+		NbG1Points, NbG2Points             uint32
+		StartFrom                          uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.TranscriptNumber != 0 || manifest.StartFrom != 0 {
+		return nil, fmt.Errorf("transcript %d, the powers start in transcript 0", manifest.TranscriptNumber)
+	}
+	if manifest.NbG2Points == 0 {
+		return nil, errors.New("no [τ]G₂ in the transcript")
+	}
+	if size > uint64(manifest.NbG1Points)+1 {
+		return nil, fmt.Errorf("%d powers, %d needed", manifest.NbG1Points+1, size)
+	}
+
+	_, _, g1, g2 := bn254.Generators()
+	srs := new(kzg_bn254.SRS)
+	srs.Pk.G1 = make([]bn254.G1Affine, size)
+	srs.Pk.G1[0] = g1
+	for i := 1; i < len(srs.Pk.G1); i++ {
+		if err := readG1(r, &srs.Pk.G1[i], fpIgnition); err != nil {
+			return nil, fmt.Errorf("power %d: %w", i, err)
+		}
+	}
+	// skip the powers beyond size
+	skip := int64(manifest.NbG1Points+1-uint32(size)) * 2 * fp.Bytes
+	if _, err := io.CopyN(io.Discard, r, skip); err != nil {
+		return nil, err
+	}
+	srs.Vk.G2[0] = g2
+	if err := readG2(r, &srs.Vk.G2[1], fpIgnition); err != nil {
+		return nil, fmt.Errorf("[τ]G₂: %w", err)
+	}
+	srs.Vk.G1 = srs.Pk.G1[0]
+	return srs, nil
+}
+
+func readG1(r io.Reader, p *bn254.G1Affine, decode func(*[fp.Bytes]byte) (fp.Element, error)) error {
+	var err error
+	var buf [fp.Bytes]byte
+	for _, c := range []*fp.Element{&p.X, &p.Y} {
+		if _, err = io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		if *c, err = decode(&buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readG2(r io.Reader, p *bn254.G2Affine, decode func(*[fp.Bytes]byte) (fp.Element, error)) error {
+	var err error
+	var buf [fp.Bytes]byte
+	for _, c := range []*fp.Element{&p.X.A0, &p.X.A1, &p.Y.A0, &p.Y.A1} {
+		if _, err = io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		if *c, err = decode(&buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fpMont decodes a coordinate of a .ptau file, in Montgomery form like
+// fp.Element, little-endian.
+func fpMont(b *[fp.Bytes]byte) (fp.Element, error) {
+	var z fp.Element
+	// the raw value is below the modulus
+	if _, err := fp.LittleEndian.Element(b); err != nil {
+		return z, err
+	}
+	for i := range z {
+		z[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+	return z, nil
+}
+
+// fpIgnition decodes a coordinate of an Ignition transcript.
+func fpIgnition(b *[fp.Bytes]byte) (fp.Element, error) {
+	var le [fp.Bytes]byte
+	for i := 0; i < fp.Bytes; i += 8 {
+		binary.LittleEndian.PutUint64(le[i:], binary.BigEndian.Uint64(b[i:]))
+	}
+	return fp.LittleEndian.Element(&le)
+}
+
+func reverse(b []byte) []byte {
+	r := bytes.Clone(b)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return r
+}
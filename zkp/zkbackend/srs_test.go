@@ -0,0 +1,153 @@
+package zkbackend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+)
+
+// ptauPower is the power of the test transcripts, 2^(ptauPower+1)-1 G1 powers
+const ptauPower = 3
+
+func testSRS(t *testing.T) *kzg_bn254.SRS {
+	t.Helper()
+	srs, err := kzg_bn254.NewSRS(1<<(ptauPower+1)-1, big.NewInt(12345))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srs
+}
+
+func TestCheckSRS(t *testing.T) {
+	if err := CheckSRS(testSRS(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a G1 power replaced by another point of the curve
+	srs := testSRS(t)
+	srs.Pk.G1[3].Add(&srs.Pk.G1[3], &srs.Pk.G1[0])
+	if CheckSRS(srs) == nil {
+		t.Fatal("tampered G1 power accepted")
+	}
+
+	// [τ]G2 of another τ
+	srs = testSRS(t)
+	srs.Vk.G2[1].ScalarMultiplication(&srs.Vk.G2[1], big.NewInt(2))
+	if CheckSRS(srs) == nil {
+		t.Fatal("tampered [τ]G2 accepted")
+	}
+}
+
+func TestReadSRS(t *testing.T) {
+	src := testSRS(t)
+	dir := t.TempDir()
+	var gnarkSRS bytes.Buffer
+	if _, err := src.WriteTo(&gnarkSRS); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"srs.kzg":          gnarkSRS.Bytes(),
+		"test.ptau":        ptauTranscript(src),
+		"transcript00.dat": ignitionTranscript(src),
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, content, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			// the transcript is truncated to the powers needed
+			const size = 10
+			srs, err := ReadSRS(path, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(srs.Pk.G1) != size {
+				t.Fatalf("%d powers read, %d expected", len(srs.Pk.G1), size)
+			}
+			for i := range srs.Pk.G1 {
+				if !srs.Pk.G1[i].Equal(&src.Pk.G1[i]) {
+					t.Fatalf("power %d differs from the source SRS", i)
+				}
+			}
+			for i := range srs.Vk.G2 {
+				if !srs.Vk.G2[i].Equal(&src.Vk.G2[i]) {
+					t.Fatalf("G2 power %d differs from the source SRS", i)
+				}
+			}
+			if _, err := ReadSRS(path, uint64(len(src.Pk.G1))+1); err == nil {
+				t.Fatal("more powers than in the transcript read")
+			}
+		})
+	}
+}
+
+// ptauTranscript encodes the SRS as a snarkjs .ptau file of 2^ptauPower,
+// with its two G2 powers only.
+func ptauTranscript(srs *kzg_bn254.SRS) []byte {
+	var header, g1s, g2s bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, uint32(fp.Bytes))
+	q := fp.Modulus().FillBytes(make([]byte, fp.Bytes))
+	header.Write(reverse(q))
+	binary.Write(&header, binary.LittleEndian, uint32(ptauPower))
+	binary.Write(&header, binary.LittleEndian, uint32(ptauPower))
+	for i := range srs.Pk.G1 {
+		writeMont(&g1s, &srs.Pk.G1[i].X, &srs.Pk.G1[i].Y)
+	}
+	for i := range srs.Vk.G2 {
+		p := &srs.Vk.G2[i]
+		writeMont(&g2s, &p.X.A0, &p.X.A1, &p.Y.A0, &p.Y.A1)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("ptau")
+	binary.Write(&b, binary.LittleEndian, uint32(1))
+	binary.Write(&b, binary.LittleEndian, uint32(3))
+	for i, section := range []*bytes.Buffer{&header, &g1s, &g2s} {
+		binary.Write(&b, binary.LittleEndian, uint32(i+1))
+		binary.Write(&b, binary.LittleEndian, uint64(section.Len()))
+		b.Write(section.Bytes())
+	}
+	return b.Bytes()
+}
+
+// ignitionTranscript encodes the SRS as an Ignition transcript00.dat, with the
+// powers from [τ]G1 and [τ]G2.
+func ignitionTranscript(srs *kzg_bn254.SRS) []byte {
+	var b bytes.Buffer
+	nbG1 := uint32(len(srs.Pk.G1) - 1)
+	for _, v := range []uint32{0, 1, nbG1, 1, nbG1, 1, 0} {
+		binary.Write(&b, binary.BigEndian, v)
+	}
+	for i := 1; i < len(srs.Pk.G1); i++ {
+		writeIgnition(&b, &srs.Pk.G1[i].X, &srs.Pk.G1[i].Y)
+	}
+	p := &srs.Vk.G2[1]
+	writeIgnition(&b, &p.X.A0, &p.X.A1, &p.Y.A0, &p.Y.A1)
+	return b.Bytes()
+}
+
+// writeMont writes the coordinates in Montgomery form, little-endian.
+func writeMont(b *bytes.Buffer, coords ...*fp.Element) {
+	for _, c := range coords {
+		for _, limb := range c {
+			binary.Write(b, binary.LittleEndian, limb)
+		}
+	}
+}
+
+// writeIgnition writes the coordinates as 64-bit limbs from the least
+// significant, each limb big-endian.
+func writeIgnition(b *bytes.Buffer, coords ...*fp.Element) {
+	for _, c := range coords {
+		for _, limb := range c.Bits() {
+			binary.Write(b, binary.BigEndian, limb)
+		}
+	}
+}